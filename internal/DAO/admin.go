@@ -0,0 +1,130 @@
+package dao
+
+import (
+	"time"
+
+	"github.com/Jack-samu/the-blog-backend-gin.git/internal/models"
+	"gorm.io/gorm"
+)
+
+// 后台用户列表的筛选条件，零值表示不筛选
+type UserFilter struct {
+	Keyword        string
+	Role           string
+	RegisteredFrom *time.Time
+	RegisteredTo   *time.Time
+	ActiveFrom     *time.Time
+	ActiveTo       *time.Time
+	MinFailed      int
+	Suspended      *bool
+}
+
+func applyUserFilter(tx *gorm.DB, f *UserFilter) *gorm.DB {
+	if f == nil {
+		return tx
+	}
+
+	if f.Keyword != "" {
+		like := "%" + escapeLike(f.Keyword) + "%"
+		tx = tx.Where("username LIKE ? ESCAPE '!' OR email LIKE ? ESCAPE '!'", like, like)
+	}
+	if f.Role != "" {
+		tx = tx.Where("role = ?", f.Role)
+	}
+	if f.RegisteredFrom != nil {
+		tx = tx.Where("created_at >= ?", *f.RegisteredFrom)
+	}
+	if f.RegisteredTo != nil {
+		tx = tx.Where("created_at < ?", *f.RegisteredTo)
+	}
+	if f.ActiveFrom != nil {
+		tx = tx.Where("last_activity >= ?", *f.ActiveFrom)
+	}
+	if f.ActiveTo != nil {
+		tx = tx.Where("last_activity < ?", *f.ActiveTo)
+	}
+	if f.MinFailed > 0 {
+		tx = tx.Where("failed_login >= ?", f.MinFailed)
+	}
+	if f.Suspended != nil {
+		if *f.Suspended {
+			tx = tx.Where("suspended_at IS NOT NULL")
+		} else {
+			tx = tx.Where("suspended_at IS NULL")
+		}
+	}
+
+	return tx
+}
+
+// 用户主键是uuid，不适用按id的游标分页，这里只做偏移分页
+func (r *DAO) GetUsers(p *Paging, filter *UserFilter) ([]models.User, int64, error) {
+	var users []models.User
+	var total int64
+
+	if err := applyUserFilter(r.db.Model(&models.User{}), filter).Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	err := applyUserFilter(r.db.Model(&models.User{}), filter).
+		Order("created_at DESC").
+		Order("id").
+		Offset(int((p.Page - 1) * p.PerPage)).
+		Limit(int(p.PerPage)).
+		Find(&users).Error
+
+	return users, total, err
+}
+
+// suspendedAt为nil时解除封禁
+func (r *DAO) SetUserSuspended(id string, suspendedAt *time.Time) error {
+	result := r.db.Model(&models.User{}).Where("id = ?", id).Update("suspended_at", suspendedAt)
+	if result.Error == nil && result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return result.Error
+}
+
+func (r *DAO) ResetFailedLogin(id string) error {
+	result := r.db.Model(&models.User{}).Where("id = ?", id).Updates(map[string]interface{}{
+		"failed_login": 0,
+		"locked_until": nil,
+	})
+	if result.Error == nil && result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return result.Error
+}
+
+// 删除用户，文章、草稿、图片、分类按外键级联删除，评论按外键置空；
+// 多对多的标签关联表和点赞记录没有外键约束，需要手动清理；关注、收藏和阅读列表也一并手动清理
+func (r *DAO) DeleteUser(tx *gorm.DB, user *models.User) error {
+	drafts := tx.Session(&gorm.Session{NewDB: true}).Model(&models.Draft{}).Select("id").Where("user_id = ?", user.ID)
+
+	if err := r.deletePosts(tx, "user_id = ?", user.ID); err != nil {
+		return err
+	}
+	if err := tx.Exec("DELETE FROM draft_tags WHERE draft_id IN (?)", drafts).Error; err != nil {
+		return err
+	}
+	if err := tx.Where("user_id = ?", user.ID).Delete(&models.Like{}).Error; err != nil {
+		return err
+	}
+	if err := tx.Where("follower_id = ? OR followee_id = ?", user.ID, user.ID).Delete(&models.Follow{}).Error; err != nil {
+		return err
+	}
+
+	// 自己的收藏和阅读列表，别人对该用户文章的收藏已随文章清理
+	lists := tx.Session(&gorm.Session{NewDB: true}).Model(&models.ReadingList{}).Select("id").Where("user_id = ?", user.ID)
+	if err := tx.Where("user_id = ?", user.ID).Delete(&models.Bookmark{}).Error; err != nil {
+		return err
+	}
+	if err := tx.Where("list_id IN (?)", lists).Delete(&models.ReadingListItem{}).Error; err != nil {
+		return err
+	}
+	if err := tx.Where("user_id = ?", user.ID).Delete(&models.ReadingList{}).Error; err != nil {
+		return err
+	}
+
+	return tx.Delete(user).Error
+}
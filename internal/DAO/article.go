@@ -3,35 +3,137 @@ package dao
 import (
 	"errors"
 	"log"
+	"sort"
 	"time"
 
 	"github.com/Jack-samu/the-blog-backend-gin.git/internal/models"
 	"gorm.io/gorm"
 )
 
-func (r *DAO) GetAllPosts(page, perPage int64) ([]models.Post, int64, error) {
+// 文章列表排序方式
+const (
+	SortNewest   = "newest"
+	SortOldest   = "oldest"
+	SortViews    = "views"
+	SortLikes    = "likes"
+	SortComments = "comments"
+)
+
+// 公共文章列表的筛选条件，零值表示不做限制
+type PostFilter struct {
+	Tag      string
+	Category string
+	Author   string
+	From     *time.Time
+	To       *time.Time
+	Sort     string
+}
+
+const commentCntExpr = "(SELECT COUNT(*) FROM comments WHERE comments.post_id = posts.id)"
+
+// 查询时附带评论数，避免列表为了计数去加载全部评论
+func withCommentCnt(tx *gorm.DB) *gorm.DB {
+	return tx.Select("posts.*, " + commentCntExpr + " AS comment_cnt")
+}
+
+func applyPostFilter(tx *gorm.DB, f *PostFilter) *gorm.DB {
+	if f == nil {
+		return tx
+	}
+
+	if f.Tag != "" {
+		tx = tx.Where("posts.id IN (?)", tx.Session(&gorm.Session{NewDB: true}).
+			Table("post_tags").
+			Select("post_tags.post_id").
+			Joins("JOIN tags ON tags.id = post_tags.tag_id").
+			Where("tags.name = ?", f.Tag))
+	}
+	if f.Category != "" {
+		tx = tx.Where("posts.category_id IN (?)", tx.Session(&gorm.Session{NewDB: true}).
+			Model(&models.Category{}).Select("id").Where("name = ?", f.Category))
+	}
+	if f.Author != "" {
+		tx = tx.Where("posts.user_id IN (?)", tx.Session(&gorm.Session{NewDB: true}).
+			Model(&models.User{}).Select("id").Where("username = ?", f.Author))
+	}
+	if f.From != nil {
+		tx = tx.Where("posts.created_at >= ?", *f.From)
+	}
+	if f.To != nil {
+		tx = tx.Where("posts.created_at < ?", *f.To)
+	}
+
+	return tx
+}
+
+func applyPostSort(tx *gorm.DB, sort string) *gorm.DB {
+	switch sort {
+	case SortOldest:
+		return tx.Order("posts.created_at ASC").Order("posts.id ASC")
+	case SortViews:
+		tx = tx.Order("posts.views_cnt DESC")
+	case SortLikes:
+		tx = tx.Order("posts.like_cnt DESC")
+	case SortComments:
+		tx = tx.Order(commentCntExpr + " DESC")
+	}
+
+	return tx.Order("posts.created_at DESC").Order("posts.id DESC")
+}
+
+func postCursor(p models.Post) Cursor {
+	return Cursor{CreatedAt: p.CreatedAt, ID: p.ID}
+}
+
+func draftCursor(d models.Draft) Cursor {
+	return Cursor{CreatedAt: d.CreatedAt, ID: d.ID}
+}
+
+func loadPostList(tx *gorm.DB) *gorm.DB {
+	return withCommentCnt(tx).
+		Preload("Author").
+		Preload("Category", "id IS NOT NULL").
+		Preload("Tags", "id IS NOT NULL")
+}
+
+func (r *DAO) GetAllPosts(p *Paging, filter *PostFilter) ([]models.Post, *PageInfo, error) {
 	var posts []models.Post
-	var total int64
 
-	// 暂时不添加条件查询，后续功能完善可以添加
-	err := r.db.Model(&models.Post{}).Count(&total).Error
+	sort := SortNewest
+	if filter != nil && filter.Sort != "" {
+		sort = filter.Sort
+	}
+
+	build := func() *gorm.DB {
+		return applyPostFilter(r.db.Model(&models.Post{}), filter)
+	}
+
+	// 按时间排序的走(created_at, id)分页，支持游标
+	if sort == SortNewest || sort == SortOldest {
+		info, err := paginate(build, loadPostList, "posts", p, sort == SortNewest, &posts, postCursor)
+		return posts, info, err
+	}
+
+	// 按计数排序的只支持偏移分页
+	if p.Cursor != nil {
+		return nil, nil, ErrCursorUnsupported
+	}
+
+	info := &PageInfo{}
+	err := build().Count(&info.Total).Error
 	if err != nil {
-		return nil, 0, err
+		return nil, nil, err
 	}
 
-	offset := (page - 1) * perPage
+	offset := (p.Page - 1) * p.PerPage
 
 	// 通过偏移量确定开始，每页数量确定获取的数据数量
-	err = r.db.Model(&models.Post{}).
-		Preload("Author").
-		Preload("Category", "id IS NOT NULL").
-		Preload("Tags", "id IS NOT NULL").
-		Order("created_at DESC").
+	err = applyPostSort(loadPostList(build()), sort).
 		Offset(int(offset)).
-		Limit(int(perPage)).
+		Limit(int(p.PerPage)).
 		Find(&posts).Error
 
-	return posts, total, err
+	return posts, info, err
 }
 
 func (r *DAO) GetPost(id uint, tx *gorm.DB) (*models.Post, error) {
@@ -76,58 +178,42 @@ func (r *DAO) GetDraft(id uint, tx *gorm.DB) (*models.Draft, error) {
 	return &draft, err
 }
 
-func (r *DAO) GetAllUsersPosts(page, perPage int64, id string) ([]models.Post, int64, error) {
+func (r *DAO) GetAllUsersPosts(p *Paging, id string) ([]models.Post, *PageInfo, error) {
 	var posts []models.Post
-	var total int64
 
-	// 暂时不添加条件查询，后续功能完善可以添加
-	err := r.db.Model(&models.Post{}).
-		Where("user_id = ?", id).Count(&total).Error
-	if err != nil {
-		return nil, 0, err
+	build := func() *gorm.DB {
+		return r.db.Model(&models.Post{}).Where("posts.user_id = ?", id)
 	}
 
-	offset := (page - 1) * perPage
+	info, err := paginate(build, loadPostList, "posts", p, true, &posts, postCursor)
+	return posts, info, err
+}
 
-	// 通过偏移量确定开始，每页数量确定获取的数据数量
-	err = r.db.Model(&models.Post{}).
-		Preload("Author").
-		Preload("Category", "id IS NOT NULL").
-		Preload("Tags", "id IS NOT NULL").
-		Where("user_id = ?", id).
-		Order("created_at DESC").
-		Offset(int(offset)).
-		Limit(int(perPage)).
+// 作者最近发布的文章，用于作者主页
+func (r *DAO) GetRecentUserPosts(userID string, limit int) ([]models.Post, error) {
+	var posts []models.Post
+	err := loadPostList(r.db.Model(&models.Post{})).
+		Where("posts.user_id = ?", userID).
+		Order("posts.created_at DESC").
+		Limit(limit).
 		Find(&posts).Error
-
-	return posts, total, err
+	return posts, err
 }
 
-func (r *DAO) GetAllUsersDrafts(page, perPage int64, id string) ([]models.Draft, int64, error) {
+func (r *DAO) GetAllUsersDrafts(p *Paging, id string) ([]models.Draft, *PageInfo, error) {
 	var drafts []models.Draft
-	var total int64
 
-	// 暂时不添加条件查询，后续功能完善可以添加
-	err := r.db.Model(&models.Draft{}).
-		Where("user_id = ?", id).Count(&total).Error
-	if err != nil {
-		return nil, 0, err
+	build := func() *gorm.DB {
+		return r.db.Model(&models.Draft{}).Where("drafts.user_id = ?", id)
+	}
+	load := func(tx *gorm.DB) *gorm.DB {
+		return tx.Preload("Author").
+			Preload("Category", "id IS NOT NULL").
+			Preload("Tags", "id IS NOT NULL")
 	}
 
-	offset := (page - 1) * perPage
-
-	// 通过偏移量确定开始，每页数量确定获取的数据数量
-	err = r.db.Model(&models.Draft{}).
-		Preload("Author").
-		Preload("Category", "id IS NOT NULL").
-		Preload("Tags", "id IS NOT NULL").
-		Where("user_id = ?", id).
-		Order("created_at DESC").
-		Offset(int(offset)).
-		Limit(int(perPage)).
-		Find(&drafts).Error
-
-	return drafts, total, err
+	info, err := paginate(build, load, "drafts", p, true, &drafts, draftCursor)
+	return drafts, info, err
 }
 
 func (r *DAO) GetSeries(id string) ([]models.Category, error) {
@@ -140,13 +226,19 @@ func (r *DAO) GetSeries(id string) ([]models.Category, error) {
 	}
 
 	err = r.db.Model(&models.Category{}).
-		Preload("Posts").
+		Preload("Posts", orderSeriesPosts).
 		Where("user_id = ?", id).
+		Order("created_at ASC").
 		Find(&categories).Error
 
 	return categories, err
 }
 
+// 系列内文章按指定顺序排列，顺序相同时按发布时间
+func orderSeriesPosts(tx *gorm.DB) *gorm.DB {
+	return tx.Order("series_order ASC").Order("created_at ASC")
+}
+
 // 创建post数据库记录并返回操作对象
 func (r *DAO) CreatePost(title, excerpt, content, cover, user_id string, tx *gorm.DB) (*models.Post, error) {
 	if tx == nil {
@@ -206,6 +298,15 @@ func (r *DAO) UpdateCategory(tx *gorm.DB, article models.Article, c string) erro
 
 	switch a := article.(type) {
 	case *models.Post:
+		// 新加入系列的文章排在最后
+		if a.CategoryID == nil || *a.CategoryID != category.ID {
+			if a.SeriesOrder, err = nextSeriesOrder(tx, category.ID); err != nil {
+				return err
+			}
+			if err = tx.Model(a).UpdateColumn("series_order", a.SeriesOrder).Error; err != nil {
+				return err
+			}
+		}
 		if err = tx.Model(&category).Association("Posts").Append(a); err != nil {
 			return err
 		}
@@ -218,6 +319,47 @@ func (r *DAO) UpdateCategory(tx *gorm.DB, article models.Article, c string) erro
 	return nil
 }
 
+func nextSeriesOrder(tx *gorm.DB, categoryID uint) (int, error) {
+	var maxOrder int
+	err := tx.Model(&models.Post{}).
+		Where("category_id = ?", categoryID).
+		Select("COALESCE(MAX(series_order), 0)").
+		Scan(&maxOrder).Error
+
+	return maxOrder + 1, err
+}
+
+// 草稿的分类和标签带到文章上
+func copyDraftMeta(tx *gorm.DB, draft *models.Draft, post *models.Post) error {
+	switch {
+	case draft.CategoryID == nil && post.CategoryID != nil:
+		err := tx.Model(post).UpdateColumns(map[string]interface{}{"category_id": nil, "series_order": 0}).Error
+		if err != nil {
+			return err
+		}
+		post.CategoryID = nil
+		post.SeriesOrder = 0
+	case draft.CategoryID != nil && (post.CategoryID == nil || *post.CategoryID != *draft.CategoryID):
+		order, err := nextSeriesOrder(tx, *draft.CategoryID)
+		if err != nil {
+			return err
+		}
+		err = tx.Model(post).UpdateColumns(map[string]interface{}{"category_id": *draft.CategoryID, "series_order": order}).Error
+		if err != nil {
+			return err
+		}
+		post.CategoryID = draft.CategoryID
+		post.SeriesOrder = order
+	}
+
+	if err := tx.Model(post).Association("Tags").Replace(draft.Tags); err != nil {
+		return err
+	}
+	post.Tags = draft.Tags
+
+	return nil
+}
+
 // 针对draft和post的tags更新
 func (r *DAO) UpdateTags(tx *gorm.DB, article models.Article, toAdd, toRemove []string) error {
 	// 删旧
@@ -242,12 +384,10 @@ func (r *DAO) UpdateTags(tx *gorm.DB, article models.Article, toAdd, toRemove []
 		}
 	}
 
-	// 添新
+	// 添新，标签已存在时直接复用
 	for _, t := range toAdd {
-		tag := models.Tag{
-			Name: t,
-		}
-		if err := tx.Create(&tag).Error; err != nil {
+		var tag models.Tag
+		if err := tx.Where(models.Tag{Name: t}).FirstOrCreate(&tag).Error; err != nil {
 			log.Printf("创建新标签%s出错：%s\n", t, err.Error())
 			continue
 		}
@@ -268,15 +408,75 @@ func (r *DAO) UpdateTags(tx *gorm.DB, article models.Article, toAdd, toRemove []
 	return nil
 }
 
+// 更新草稿并递增版本号，version不为0时只在版本一致时更新，返回是否更新成功
+func (r *DAO) UpdateDraftVersioned(tx *gorm.DB, id uint, version int, fields map[string]interface{}) (bool, error) {
+	if tx == nil {
+		tx = r.db
+	}
+
+	fields["version"] = gorm.Expr("version + 1")
+	query := tx.Model(&models.Draft{}).Where("id = ?", id)
+	if version != 0 {
+		query = query.Where("version = ?", version)
+	}
+
+	result := query.Updates(fields)
+	return result.RowsAffected > 0, result.Error
+}
+
+// 草稿移出分类
+func (r *DAO) ClearDraftCategory(tx *gorm.DB, id uint) error {
+	if tx == nil {
+		tx = r.db
+	}
+
+	return tx.Model(&models.Draft{}).Where("id = ?", id).Update("category_id", nil).Error
+}
+
 func (r *DAO) Draft2Post(draft *models.Draft, tx *gorm.DB) (*models.Post, error) {
 
+	// 已发布文章的编辑稿，原地更新以保留文章id、评论和点赞
+	if draft.PostID != nil {
+		post, err := r.GetPost(*draft.PostID, tx)
+		if err != nil {
+			return nil, err
+		}
+
+		post.Title = draft.Title
+		post.Excerpt = draft.Excerpt
+		post.Content = draft.Content
+		post.Cover = draft.Cover
+		err = tx.Model(post).
+			Select("title", "excerpt", "content", "cover").
+			Updates(post).Error
+		if err != nil {
+			return nil, err
+		}
+		if err = copyDraftMeta(tx, draft, post); err != nil {
+			return nil, err
+		}
+
+		return post, removeConvertedDraft(tx, draft)
+	}
+
 	post, err := r.CreatePost(draft.Title, draft.Excerpt, draft.Content, draft.Cover, draft.UserID, tx)
 	if err != nil {
 		return nil, err
-	} else {
-		err = tx.Delete(draft).Error
-		return post, err
 	}
+	if err = copyDraftMeta(tx, draft, post); err != nil {
+		return nil, err
+	}
+
+	return post, removeConvertedDraft(tx, draft)
+}
+
+// 发布完成后删除草稿，先解除标签关联
+func removeConvertedDraft(tx *gorm.DB, draft *models.Draft) error {
+	if err := tx.Model(draft).Association("Tags").Clear(); err != nil {
+		return err
+	}
+
+	return tx.Delete(draft).Error
 }
 
 func (r *DAO) DeleteArticle(article models.Article, tx *gorm.DB) error {
@@ -286,7 +486,7 @@ func (r *DAO) DeleteArticle(article models.Article, tx *gorm.DB) error {
 
 	switch a := article.(type) {
 	case (*models.Post):
-		if err := tx.Model(&models.Post{}).Delete(a).Error; err != nil {
+		if err := r.deletePosts(tx, "id = ?", a.ID); err != nil {
 			return err
 		}
 	case (*models.Draft):
@@ -298,6 +498,36 @@ func (r *DAO) DeleteArticle(article models.Article, tx *gorm.DB) error {
 	return nil
 }
 
+// 按条件删除文章，删除单篇、系列和用户时共用；评论和回复随文章级联删除，
+// 标签关联、收藏和阅读列表中的引用，以及文章、评论和回复上的点赞需要手动清理
+func (r *DAO) deletePosts(tx *gorm.DB, query string, args ...interface{}) error {
+	posts := tx.Session(&gorm.Session{NewDB: true}).Model(&models.Post{}).Select("id").Where(query, args...)
+	comments := tx.Session(&gorm.Session{NewDB: true}).Model(&models.Comment{}).Select("id").Where("post_id IN (?)", posts)
+	replies := tx.Session(&gorm.Session{NewDB: true}).Model(&models.Reply{}).Select("id").Where("comment_id IN (?)", comments)
+
+	if err := r.deleteTargetLikes(tx, models.LikeTargetReply, replies); err != nil {
+		return err
+	}
+	if err := r.deleteTargetLikes(tx, models.LikeTargetComment, comments); err != nil {
+		return err
+	}
+	if err := r.deleteTargetLikes(tx, models.LikeTargetPost, posts); err != nil {
+		return err
+	}
+
+	if err := tx.Exec("DELETE FROM post_tags WHERE post_id IN (?)", posts).Error; err != nil {
+		return err
+	}
+	if err := tx.Where("post_id IN (?)", posts).Delete(&models.Bookmark{}).Error; err != nil {
+		return err
+	}
+	if err := tx.Where("post_id IN (?)", posts).Delete(&models.ReadingListItem{}).Error; err != nil {
+		return err
+	}
+
+	return tx.Where(query, args...).Delete(&models.Post{}).Error
+}
+
 func (r *DAO) Transaction(f func(*gorm.DB) error) error {
 	tx := r.db.Begin()
 	if tx.Error != nil {
@@ -326,3 +556,24 @@ func (r *DAO) Transaction(f func(*gorm.DB) error) error {
 
 	return nil
 }
+
+// 批量累加浏览量，按id顺序更新避免并发写入时互相死锁
+func (r *DAO) IncreaseViews(counts map[uint]int) error {
+	ids := make([]uint, 0, len(counts))
+	for id := range counts {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+
+	return r.Transaction(func(tx *gorm.DB) error {
+		for _, id := range ids {
+			err := tx.Model(&models.Post{}).
+				Where("id = ?", id).
+				UpdateColumn("views_cnt", gorm.Expr("views_cnt + ?", counts[id])).Error
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
@@ -0,0 +1,182 @@
+package dao
+
+import (
+	"github.com/Jack-samu/the-blog-backend-gin.git/internal/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+func bookmarkCursor(b models.Bookmark) Cursor {
+	return Cursor{CreatedAt: b.CreatedAt, ID: b.ID}
+}
+
+// 列表中的文章和公共列表一样附带作者、分类、标签和评论数
+func preloadPostList(tx *gorm.DB, field string) *gorm.DB {
+	return tx.Preload(field, withCommentCnt).
+		Preload(field+".Author").
+		Preload(field+".Category", "id IS NOT NULL").
+		Preload(field+".Tags", "id IS NOT NULL")
+}
+
+// 收藏文章，已收藏时不做任何操作，返回是否真正新增了记录
+func (r *DAO) CreateBookmark(userID string, postID uint) (bool, error) {
+	bookmark := &models.Bookmark{UserID: userID, PostID: postID}
+	result := r.db.Clauses(clause.OnConflict{DoNothing: true}).Create(bookmark)
+	return result.RowsAffected > 0, result.Error
+}
+
+// 取消收藏，返回是否真正删除了记录
+func (r *DAO) DeleteBookmark(userID string, postID uint) (bool, error) {
+	result := r.db.Where("user_id = ? AND post_id = ?", userID, postID).Delete(&models.Bookmark{})
+	return result.RowsAffected > 0, result.Error
+}
+
+// 给定文章中已被该用户收藏的
+func (r *DAO) BookmarkedPostIDs(userID string, postIDs []uint) (map[uint]bool, error) {
+	marked := make(map[uint]bool)
+	if userID == "" || len(postIDs) == 0 {
+		return marked, nil
+	}
+
+	var ids []uint
+	err := r.db.Model(&models.Bookmark{}).
+		Where("user_id = ? AND post_id IN ?", userID, postIDs).
+		Pluck("post_id", &ids).Error
+	if err != nil {
+		return nil, err
+	}
+
+	for _, id := range ids {
+		marked[id] = true
+	}
+	return marked, nil
+}
+
+// 收藏的文章，最近收藏的在前
+func (r *DAO) GetBookmarks(p *Paging, userID string) ([]models.Bookmark, *PageInfo, error) {
+	var bookmarks []models.Bookmark
+
+	build := func() *gorm.DB {
+		return r.db.Model(&models.Bookmark{}).Where("bookmarks.user_id = ?", userID)
+	}
+	load := func(tx *gorm.DB) *gorm.DB {
+		return preloadPostList(tx, "Post")
+	}
+
+	info, err := paginate(build, load, "bookmarks", p, true, &bookmarks, bookmarkCursor)
+	return bookmarks, info, err
+}
+
+func orderListItems(tx *gorm.DB) *gorm.DB {
+	return tx.Order("position ASC, id ASC")
+}
+
+func (r *DAO) CreateReadingList(list *models.ReadingList) error {
+	return r.db.Create(list).Error
+}
+
+// 阅读列表及其中的文章
+func (r *DAO) GetReadingList(id uint, tx *gorm.DB) (*models.ReadingList, error) {
+	if tx == nil {
+		tx = r.db
+	}
+
+	var list models.ReadingList
+	err := preloadPostList(tx.Model(&models.ReadingList{}).
+		Preload("User").
+		Preload("Items", orderListItems), "Items.Post").
+		First(&list, id).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return &list, nil
+}
+
+func (r *DAO) GetReadingListByToken(token string) (*models.ReadingList, error) {
+	var list models.ReadingList
+	if err := r.db.Select("id").Where("share_token = ?", token).First(&list).Error; err != nil {
+		return nil, err
+	}
+
+	return r.GetReadingList(list.ID, nil)
+}
+
+// 用户的全部阅读列表，只带文章id用于计数
+func (r *DAO) GetUserReadingLists(userID string) ([]models.ReadingList, error) {
+	var lists []models.ReadingList
+	err := r.db.Model(&models.ReadingList{}).
+		Preload("Items", orderListItems).
+		Where("user_id = ?", userID).
+		Order("created_at DESC, id DESC").
+		Find(&lists).Error
+	return lists, err
+}
+
+func (r *DAO) UpdateReadingList(list *models.ReadingList, fields map[string]interface{}) error {
+	return r.db.Model(list).Updates(fields).Error
+}
+
+func (r *DAO) DeleteReadingList(tx *gorm.DB, list *models.ReadingList) error {
+	if err := tx.Where("list_id = ?", list.ID).Delete(&models.ReadingListItem{}).Error; err != nil {
+		return err
+	}
+	return tx.Delete(list).Error
+}
+
+// 把文章加到列表末尾，已在列表中时不做任何操作，返回是否真正新增了记录
+func (r *DAO) AddReadingListItem(tx *gorm.DB, listID, postID uint) (bool, error) {
+	if tx == nil {
+		tx = r.db
+	}
+
+	var last int
+	err := tx.Model(&models.ReadingListItem{}).
+		Where("list_id = ?", listID).
+		Select("COALESCE(MAX(position), 0)").
+		Scan(&last).Error
+	if err != nil {
+		return false, err
+	}
+
+	item := &models.ReadingListItem{ListID: listID, PostID: postID, Position: last + 1}
+	result := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(item)
+	return result.RowsAffected > 0, result.Error
+}
+
+func (r *DAO) RemoveReadingListItem(listID, postID uint) (bool, error) {
+	result := r.db.Where("list_id = ? AND post_id = ?", listID, postID).Delete(&models.ReadingListItem{})
+	return result.RowsAffected > 0, result.Error
+}
+
+// 按postIDs的顺序重排，没列出的文章保持原有相对顺序排在后面
+func (r *DAO) ReorderReadingList(tx *gorm.DB, list *models.ReadingList, postIDs []uint) error {
+	listed := make(map[uint]bool, len(postIDs))
+	position := 0
+
+	for _, id := range postIDs {
+		position++
+		listed[id] = true
+		err := tx.Model(&models.ReadingListItem{}).
+			Where("list_id = ? AND post_id = ?", list.ID, id).
+			UpdateColumn("position", position).Error
+		if err != nil {
+			return err
+		}
+	}
+
+	for _, item := range list.Items {
+		if listed[item.PostID] {
+			continue
+		}
+		position++
+		err := tx.Model(&models.ReadingListItem{}).
+			Where("id = ?", item.ID).
+			UpdateColumn("position", position).Error
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
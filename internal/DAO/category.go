@@ -0,0 +1,97 @@
+package dao
+
+import (
+	"github.com/Jack-samu/the-blog-backend-gin.git/internal/models"
+	"gorm.io/gorm"
+)
+
+func (r *DAO) GetCategory(id uint, tx *gorm.DB) (*models.Category, error) {
+	if tx == nil {
+		tx = r.db
+	}
+
+	var category models.Category
+	err := tx.Model(&models.Category{}).
+		Preload("User").
+		Preload("Posts", orderSeriesPosts).
+		First(&category, id).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return &category, nil
+}
+
+func (r *DAO) ExistCategoryName(userID, name string, excludeID uint) (bool, error) {
+	var cnt int64
+	err := r.db.Model(&models.Category{}).
+		Where("user_id = ? AND name = ? AND id <> ?", userID, name, excludeID).
+		Count(&cnt).Error
+	return cnt > 0, err
+}
+
+func (r *DAO) CreateCategory(category *models.Category) error {
+	return r.db.Create(category).Error
+}
+
+func (r *DAO) UpdateCategoryInfo(category *models.Category, fields map[string]interface{}) error {
+	return r.db.Model(category).Updates(fields).Error
+}
+
+// 删除系列，deletePosts为true时连同系列下的文章一起删除，否则文章只是移出系列
+func (r *DAO) DeleteCategory(tx *gorm.DB, category *models.Category, deletePosts bool) error {
+	if deletePosts {
+		if err := r.deletePosts(tx, "category_id = ?", category.ID); err != nil {
+			return err
+		}
+	} else {
+		err := tx.Model(&models.Post{}).
+			Where("category_id = ?", category.ID).
+			Updates(map[string]interface{}{"category_id": nil, "series_order": 0}).Error
+		if err != nil {
+			return err
+		}
+	}
+
+	// 草稿只解除关联
+	err := tx.Model(&models.Draft{}).
+		Where("category_id = ?", category.ID).
+		Update("category_id", nil).Error
+	if err != nil {
+		return err
+	}
+
+	return tx.Delete(category).Error
+}
+
+// 按给定的文章id顺序重排系列，未列出的文章排在后面并保持原有相对顺序
+func (r *DAO) ReorderSeries(tx *gorm.DB, category *models.Category, postIDs []uint) error {
+	listed := make(map[uint]bool, len(postIDs))
+	order := 0
+
+	for _, id := range postIDs {
+		order++
+		listed[id] = true
+		err := tx.Model(&models.Post{}).
+			Where("id = ? AND category_id = ?", id, category.ID).
+			UpdateColumn("series_order", order).Error
+		if err != nil {
+			return err
+		}
+	}
+
+	for _, post := range category.Posts {
+		if listed[post.ID] {
+			continue
+		}
+		order++
+		err := tx.Model(&models.Post{}).
+			Where("id = ?", post.ID).
+			UpdateColumn("series_order", order).Error
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
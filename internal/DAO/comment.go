@@ -1,49 +1,60 @@
 package dao
 
 import (
-	"errors"
 	"log"
 
 	"github.com/Jack-samu/the-blog-backend-gin.git/internal/models"
 	"gorm.io/gorm"
 )
 
-func (r DAO) GetComments(id int64) ([]*models.Comment, error) {
+// 一级评论按时间倒序
+func (r *DAO) GetComments(id int64, p *Paging) ([]*models.Comment, *PageInfo, error) {
 	var comments []*models.Comment
 
 	var post models.Post
 	if err := r.db.Take(&post, "id = ?", id).Error; err != nil {
 		log.Println("文章主体不存在")
 		// 评论的主体post都不存在
-		return nil, gorm.ErrRecordNotFound
+		return nil, nil, gorm.ErrRecordNotFound
 	}
 
-	err := r.db.Model(&models.Comment{}).Where("post_id = ?", id).
-		Preload("User").
-		Preload("Replies").
-		Find(&comments).
-		Error
+	build := func() *gorm.DB {
+		return r.db.Model(&models.Comment{}).Where("comments.post_id = ?", id)
+	}
+	load := func(tx *gorm.DB) *gorm.DB {
+		return tx.Preload("User").Preload("Replies")
+	}
+	key := func(c *models.Comment) Cursor {
+		return Cursor{CreatedAt: c.CreatedAt, ID: c.ID}
+	}
 
-	return comments, err
+	info, err := paginate(build, load, "comments", p, true, &comments, key)
+	return comments, info, err
 }
 
-func (r *DAO) GetReplies(id int64) ([]*models.Reply, error) {
+// 二级评论按时间正序，方便阅读对话
+func (r *DAO) GetReplies(id int64, p *Paging) ([]*models.Reply, *PageInfo, error) {
 	var replies []*models.Reply
 
 	var comment models.Comment
 	err := r.db.Take(&comment, "id = ?", id).Error
 	if err != nil {
 		// 二级评论的主体comment都不存在
-		return nil, gorm.ErrRecordNotFound
+		return nil, nil, gorm.ErrRecordNotFound
 	}
 
-	err = r.db.Model(&models.Reply{}).
-		Where("comment_id = ?", id).
-		Preload("User").
-		Preload("Replies", "parent_id IS NOT NULL").
-		Find(&replies).Error
+	build := func() *gorm.DB {
+		return r.db.Model(&models.Reply{}).Where("replies.comment_id = ?", id)
+	}
+	load := func(tx *gorm.DB) *gorm.DB {
+		return tx.Preload("User").Preload("Replies", "parent_id IS NOT NULL")
+	}
+	key := func(r *models.Reply) Cursor {
+		return Cursor{CreatedAt: r.CreatedAt, ID: r.ID}
+	}
 
-	return replies, err
+	info, err := paginate(build, load, "replies", p, false, &replies, key)
+	return replies, info, err
 }
 
 func (r *DAO) IsLiked(userID, targetType string, targetID uint) (bool, error) {
@@ -54,9 +65,68 @@ func (r *DAO) IsLiked(userID, targetType string, targetID uint) (bool, error) {
 		Where("target_id = ?", targetID).
 		Where("user_id = ?", userID).
 		Count(&record).Error
-	if err != nil && errors.Is(err, gorm.ErrRecordNotFound) {
+	if err != nil {
 		return false, err
 	}
 
-	return true, nil
+	return record > 0, nil
+}
+
+func (r *DAO) GetComment(id int64, tx *gorm.DB) (*models.Comment, error) {
+	if tx == nil {
+		tx = r.db
+	}
+
+	var comment models.Comment
+	if err := tx.Model(&models.Comment{}).First(&comment, id).Error; err != nil {
+		return nil, err
+	}
+
+	return &comment, nil
+}
+
+// 删除评论，回复随外键级联删除，评论和回复上的点赞需要手动清理
+func (r *DAO) DeleteComment(tx *gorm.DB, comment *models.Comment) error {
+	replies := tx.Session(&gorm.Session{NewDB: true}).Model(&models.Reply{}).Select("id").Where("comment_id = ?", comment.ID)
+
+	if err := r.deleteTargetLikes(tx, models.LikeTargetReply, replies); err != nil {
+		return err
+	}
+	if err := r.deleteTargetLikes(tx, models.LikeTargetComment, []uint{comment.ID}); err != nil {
+		return err
+	}
+
+	return tx.Delete(comment).Error
+}
+
+// 删除回复，下级回复随外键级联删除，逐层找出它们后一并清理点赞
+func (r *DAO) DeleteReply(tx *gorm.DB, reply *models.Reply) error {
+	ids := []uint{reply.ID}
+	for parents := ids; len(parents) > 0; {
+		var children []uint
+		if err := tx.Model(&models.Reply{}).Where("parent_id IN ?", parents).Pluck("id", &children).Error; err != nil {
+			return err
+		}
+		ids = append(ids, children...)
+		parents = children
+	}
+
+	if err := r.deleteTargetLikes(tx, models.LikeTargetReply, ids); err != nil {
+		return err
+	}
+
+	return tx.Delete(reply).Error
+}
+
+func (r *DAO) GetReply(id int64, tx *gorm.DB) (*models.Reply, error) {
+	if tx == nil {
+		tx = r.db
+	}
+
+	var reply models.Reply
+	if err := tx.Model(&models.Reply{}).First(&reply, id).Error; err != nil {
+		return nil, err
+	}
+
+	return &reply, nil
 }
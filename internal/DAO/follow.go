@@ -0,0 +1,87 @@
+package dao
+
+import (
+	"github.com/Jack-samu/the-blog-backend-gin.git/internal/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+func followCursor(f models.Follow) Cursor {
+	return Cursor{CreatedAt: f.CreatedAt, ID: f.ID}
+}
+
+// 创建关注关系，已关注时不做任何操作，返回是否真正新增了记录
+func (r *DAO) CreateFollow(followerID, followeeID string) (bool, error) {
+	follow := &models.Follow{FollowerID: followerID, FolloweeID: followeeID}
+	result := r.db.Clauses(clause.OnConflict{DoNothing: true}).Create(follow)
+	return result.RowsAffected > 0, result.Error
+}
+
+// 取消关注，返回是否真正删除了记录
+func (r *DAO) DeleteFollow(followerID, followeeID string) (bool, error) {
+	result := r.db.Where("follower_id = ? AND followee_id = ?", followerID, followeeID).Delete(&models.Follow{})
+	return result.RowsAffected > 0, result.Error
+}
+
+func (r *DAO) IsFollowing(followerID, followeeID string) (bool, error) {
+	var cnt int64
+	err := r.db.Model(&models.Follow{}).
+		Where("follower_id = ? AND followee_id = ?", followerID, followeeID).
+		Count(&cnt).Error
+	return cnt > 0, err
+}
+
+// 粉丝数和关注数
+func (r *DAO) CountFollows(userID string) (int64, int64, error) {
+	var followers, following int64
+	if err := r.db.Model(&models.Follow{}).Where("followee_id = ?", userID).Count(&followers).Error; err != nil {
+		return 0, 0, err
+	}
+	if err := r.db.Model(&models.Follow{}).Where("follower_id = ?", userID).Count(&following).Error; err != nil {
+		return 0, 0, err
+	}
+	return followers, following, nil
+}
+
+// 关注了该用户的人，最近关注的在前
+func (r *DAO) GetFollowers(p *Paging, userID string) ([]models.Follow, *PageInfo, error) {
+	var follows []models.Follow
+
+	build := func() *gorm.DB {
+		return r.db.Model(&models.Follow{}).Where("follows.followee_id = ?", userID)
+	}
+	load := func(tx *gorm.DB) *gorm.DB {
+		return tx.Preload("Follower")
+	}
+
+	info, err := paginate(build, load, "follows", p, true, &follows, followCursor)
+	return follows, info, err
+}
+
+// 该用户关注的人，最近关注的在前
+func (r *DAO) GetFollowing(p *Paging, userID string) ([]models.Follow, *PageInfo, error) {
+	var follows []models.Follow
+
+	build := func() *gorm.DB {
+		return r.db.Model(&models.Follow{}).Where("follows.follower_id = ?", userID)
+	}
+	load := func(tx *gorm.DB) *gorm.DB {
+		return tx.Preload("Followee")
+	}
+
+	info, err := paginate(build, load, "follows", p, true, &follows, followCursor)
+	return follows, info, err
+}
+
+// 关注的作者发布的文章，按发布时间倒序
+func (r *DAO) GetFeed(p *Paging, userID string) ([]models.Post, *PageInfo, error) {
+	var posts []models.Post
+
+	build := func() *gorm.DB {
+		followees := r.db.Model(&models.Follow{}).Select("followee_id").Where("follower_id = ?", userID)
+		return r.db.Model(&models.Post{}).Where("posts.user_id IN (?)", followees)
+	}
+
+	info, err := paginate(build, loadPostList, "posts", p, true, &posts, postCursor)
+	return posts, info, err
+}
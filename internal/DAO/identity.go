@@ -0,0 +1,28 @@
+package dao
+
+import (
+	"time"
+
+	"github.com/Jack-samu/the-blog-backend-gin.git/internal/models"
+	"gorm.io/gorm"
+)
+
+func (r *DAO) GetIdentity(provider, subject string) (*models.UserIdentity, error) {
+	var identity models.UserIdentity
+	err := r.db.Where("provider = ? AND subject = ?", provider, subject).First(&identity).Error
+	if err != nil {
+		return nil, err
+	}
+	return &identity, nil
+}
+
+func (r *DAO) CreateIdentity(tx *gorm.DB, identity *models.UserIdentity) error {
+	if tx == nil {
+		tx = r.db
+	}
+	return tx.Create(identity).Error
+}
+
+func (r *DAO) TouchIdentity(id uint) error {
+	return r.db.Model(&models.UserIdentity{}).Where("id = ?", id).Update("last_login_at", time.Now()).Error
+}
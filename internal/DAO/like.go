@@ -0,0 +1,96 @@
+package dao
+
+import (
+	"github.com/Jack-samu/the-blog-backend-gin.git/internal/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// 点赞目标类型到对应模型的映射，计数字段均为like_cnt
+func likeTargetModel(targetType string) interface{} {
+	switch targetType {
+	case models.LikeTargetPost:
+		return &models.Post{}
+	case models.LikeTargetComment:
+		return &models.Comment{}
+	case models.LikeTargetReply:
+		return &models.Reply{}
+	}
+	return nil
+}
+
+// 检查点赞目标是否存在
+func (r *DAO) LikeTargetExists(tx *gorm.DB, targetType string, targetID uint) (bool, error) {
+	if tx == nil {
+		tx = r.db
+	}
+
+	model := likeTargetModel(targetType)
+	if model == nil {
+		return false, nil
+	}
+
+	var cnt int64
+	err := tx.Model(model).Where("id = ?", targetID).Count(&cnt).Error
+	return cnt > 0, err
+}
+
+// 创建点赞记录，已点赞时不做任何操作，返回是否真正新增了记录
+func (r *DAO) CreateLike(tx *gorm.DB, userID, targetType string, targetID uint) (bool, error) {
+	if tx == nil {
+		tx = r.db
+	}
+
+	like := &models.Like{
+		UserID:     userID,
+		TargetType: targetType,
+		TargetID:   targetID,
+	}
+
+	result := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(like)
+	return result.RowsAffected > 0, result.Error
+}
+
+// 删除点赞记录，返回是否真正删除了记录
+func (r *DAO) DeleteLike(tx *gorm.DB, userID, targetType string, targetID uint) (bool, error) {
+	if tx == nil {
+		tx = r.db
+	}
+
+	result := tx.Where("user_id = ? AND target_type = ? AND target_id = ?", userID, targetType, targetID).
+		Delete(&models.Like{})
+	return result.RowsAffected > 0, result.Error
+}
+
+// 删除目标上的全部点赞，ids为目标id的列表或子查询；
+// 点赞按类型和id关联目标，没有外键，删除文章、评论或回复时需在同一事务中调用
+func (r *DAO) deleteTargetLikes(tx *gorm.DB, targetType string, ids interface{}) error {
+	return tx.Where("target_type = ? AND target_id IN (?)", targetType, ids).Delete(&models.Like{}).Error
+}
+
+// 调整冗余的点赞计数，减少时不会减到负数
+func (r *DAO) UpdateLikeCnt(tx *gorm.DB, targetType string, targetID uint, delta int) error {
+	if tx == nil {
+		tx = r.db
+	}
+
+	query := tx.Model(likeTargetModel(targetType)).Where("id = ?", targetID)
+	if delta < 0 {
+		query = query.Where("like_cnt >= ?", -delta)
+	}
+
+	return query.UpdateColumn("like_cnt", gorm.Expr("like_cnt + ?", delta)).Error
+}
+
+func (r *DAO) GetLikeCnt(tx *gorm.DB, targetType string, targetID uint) (int, error) {
+	if tx == nil {
+		tx = r.db
+	}
+
+	var cnt int
+	err := tx.Model(likeTargetModel(targetType)).
+		Where("id = ?", targetID).
+		Select("like_cnt").
+		Scan(&cnt).Error
+	return cnt, err
+}
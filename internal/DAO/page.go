@@ -0,0 +1,136 @@
+package dao
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+var (
+	ErrInvalidCursor     = errors.New("无效的分页游标")
+	ErrCursorUnsupported = errors.New("当前排序方式不支持游标分页")
+)
+
+// 游标分页的定位点，按(created_at, id)确定列表中的位置；
+// Backward为true时表示从该位置往列表前面翻页
+type Cursor struct {
+	CreatedAt time.Time `json:"t"`
+	ID        uint      `json:"i"`
+	Backward  bool      `json:"b,omitempty"`
+}
+
+// 分页参数，Cursor不为空时使用游标分页，否则使用偏移分页
+type Paging struct {
+	Page    int64
+	PerPage int64
+	Cursor  *Cursor
+}
+
+type PageInfo struct {
+	Total      int64
+	NextCursor string
+	PrevCursor string
+}
+
+// 游标对客户端不透明，序列化后做base64编码
+func EncodeCursor(c Cursor) string {
+	data, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func DecodeCursor(s string) (*Cursor, error) {
+	data, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+
+	var c Cursor
+	if err = json.Unmarshal(data, &c); err != nil || c.ID == 0 {
+		return nil, ErrInvalidCursor
+	}
+
+	return &c, nil
+}
+
+// 按(created_at, id)排序的通用分页，desc为列表整体的顺序。
+// build每次返回只带筛选条件的新查询（用于计数），load在其上追加select和preload，
+// key从记录中取出游标定位点；多取一条用来判断是否还有下一页
+func paginate[T any](build func() *gorm.DB, load func(*gorm.DB) *gorm.DB, table string,
+	p *Paging, desc bool, dest *[]T, key func(T) Cursor) (*PageInfo, error) {
+
+	info := &PageInfo{}
+	if err := build().Count(&info.Total).Error; err != nil {
+		return nil, err
+	}
+
+	c := p.Cursor
+	backward := c != nil && c.Backward
+
+	// 向前翻页时逆序查询，取完再反转回列表顺序
+	less := desc != backward
+	op, dir := ">", "ASC"
+	if less {
+		op, dir = "<", "DESC"
+	}
+
+	query := load(build())
+	if c == nil {
+		query = query.Offset(int((p.Page - 1) * p.PerPage))
+	} else {
+		query = query.Where(fmt.Sprintf("(%[1]s.created_at %[2]s ? OR (%[1]s.created_at = ? AND %[1]s.id %[2]s ?))", table, op),
+			c.CreatedAt, c.CreatedAt, c.ID)
+	}
+
+	err := query.
+		Order(table + ".created_at " + dir).
+		Order(table + ".id " + dir).
+		Limit(int(p.PerPage) + 1).
+		Find(dest).Error
+	if err != nil {
+		return nil, err
+	}
+
+	items := *dest
+	hasMore := int64(len(items)) > p.PerPage
+	if hasMore {
+		items = items[:p.PerPage]
+	}
+	if backward {
+		slices.Reverse(items)
+	}
+	*dest = items
+
+	if len(items) == 0 {
+		return info, nil
+	}
+
+	first, last := key(items[0]), key(items[len(items)-1])
+	first.Backward = true
+
+	switch {
+	case c == nil:
+		if hasMore {
+			info.NextCursor = EncodeCursor(last)
+		}
+		if p.Page > 1 {
+			info.PrevCursor = EncodeCursor(first)
+		}
+	case backward:
+		info.NextCursor = EncodeCursor(last)
+		if hasMore {
+			info.PrevCursor = EncodeCursor(first)
+		}
+	default:
+		if hasMore {
+			info.NextCursor = EncodeCursor(last)
+		}
+		info.PrevCursor = EncodeCursor(first)
+	}
+
+	return info, nil
+}
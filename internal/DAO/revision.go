@@ -0,0 +1,106 @@
+package dao
+
+import (
+	"github.com/Jack-samu/the-blog-backend-gin.git/internal/models"
+	"gorm.io/gorm"
+)
+
+// 以文章当前内容记录一个新版本
+func (r *DAO) CreateRevision(tx *gorm.DB, post *models.Post, userID string) (*models.PostRevision, error) {
+	if tx == nil {
+		tx = r.db
+	}
+
+	var maxVersion int
+	err := tx.Model(&models.PostRevision{}).
+		Where("post_id = ?", post.ID).
+		Select("COALESCE(MAX(version), 0)").
+		Scan(&maxVersion).Error
+	if err != nil {
+		return nil, err
+	}
+
+	revision := &models.PostRevision{
+		PostID:  post.ID,
+		Version: maxVersion + 1,
+		Title:   post.Title,
+		Excerpt: post.Excerpt,
+		Content: post.Content,
+		Cover:   post.Cover,
+		UserID:  userID,
+	}
+	err = tx.Create(revision).Error
+
+	return revision, err
+}
+
+// 没有修订记录的旧文章，编辑前先把当前内容存为第一版
+func (r *DAO) EnsureBaseRevision(tx *gorm.DB, postID uint) error {
+	if tx == nil {
+		tx = r.db
+	}
+
+	var cnt int64
+	err := tx.Model(&models.PostRevision{}).Where("post_id = ?", postID).Count(&cnt).Error
+	if err != nil || cnt > 0 {
+		return err
+	}
+
+	var post models.Post
+	if err = tx.First(&post, postID).Error; err != nil {
+		return err
+	}
+
+	return tx.Create(&models.PostRevision{
+		PostID:    post.ID,
+		Version:   1,
+		Title:     post.Title,
+		Excerpt:   post.Excerpt,
+		Content:   post.Content,
+		Cover:     post.Cover,
+		UserID:    post.UserID,
+		CreatedAt: post.UpdatedAt,
+	}).Error
+}
+
+func (r *DAO) GetRevisions(postID uint) ([]models.PostRevision, error) {
+	var revisions []models.PostRevision
+
+	err := r.db.Model(&models.PostRevision{}).
+		Preload("Author").
+		Omit("content").
+		Where("post_id = ?", postID).
+		Order("version DESC").
+		Find(&revisions).Error
+
+	return revisions, err
+}
+
+func (r *DAO) GetRevision(postID uint, version int) (*models.PostRevision, error) {
+	var revision models.PostRevision
+
+	err := r.db.Model(&models.PostRevision{}).
+		Preload("Author").
+		Where("post_id = ? AND version = ?", postID, version).
+		First(&revision).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return &revision, nil
+}
+
+// 查找已发布文章对应的编辑稿
+func (r *DAO) GetDraftOfPost(postID uint, tx *gorm.DB) (*models.Draft, error) {
+	if tx == nil {
+		tx = r.db
+	}
+
+	var draft models.Draft
+	err := tx.Model(&models.Draft{}).Where("post_id = ?", postID).First(&draft).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return &draft, nil
+}
@@ -0,0 +1,53 @@
+package dao
+
+import (
+	"time"
+
+	"github.com/Jack-samu/the-blog-backend-gin.git/internal/models"
+	"gorm.io/gorm"
+)
+
+func (r *DAO) GetScheduledDrafts(userID string) ([]models.Draft, error) {
+	var drafts []models.Draft
+
+	err := r.db.Model(&models.Draft{}).
+		Preload("Author").
+		Preload("Category", "id IS NOT NULL").
+		Preload("Tags", "id IS NOT NULL").
+		Where("user_id = ? AND publish_at IS NOT NULL", userID).
+		Order("publish_at ASC").
+		Find(&drafts).Error
+
+	return drafts, err
+}
+
+// 设置或取消草稿的定时发布时间
+func (r *DAO) SetDraftSchedule(tx *gorm.DB, id uint, publishAt *time.Time) error {
+	if tx == nil {
+		tx = r.db
+	}
+
+	return tx.Model(&models.Draft{}).Where("id = ?", id).Update("publish_at", publishAt).Error
+}
+
+// 到点待发布的草稿id
+func (r *DAO) GetDueDraftIDs(now time.Time, limit int) ([]uint, error) {
+	var ids []uint
+
+	err := r.db.Model(&models.Draft{}).
+		Where("publish_at IS NOT NULL AND publish_at <= ?", now).
+		Order("publish_at ASC").
+		Limit(limit).
+		Pluck("id", &ids).Error
+
+	return ids, err
+}
+
+// 抢占到点的草稿，清掉发布时间，多个实例同时跑时只有一个能成功
+func (r *DAO) ClaimDueDraft(tx *gorm.DB, id uint, now time.Time) (bool, error) {
+	result := tx.Model(&models.Draft{}).
+		Where("id = ? AND publish_at IS NOT NULL AND publish_at <= ?", id, now).
+		Update("publish_at", nil)
+
+	return result.RowsAffected > 0, result.Error
+}
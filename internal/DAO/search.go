@@ -0,0 +1,106 @@
+package dao
+
+import (
+	"strings"
+
+	"github.com/Jack-samu/the-blog-backend-gin.git/internal/models"
+)
+
+// 搜索命中，按相关度从高到低排列
+type SearchHit struct {
+	ID    uint
+	Score float64
+}
+
+// 全文搜索文章，mysql使用FULLTEXT索引（ngram分词以支持中文），
+// 其他数据库（测试用的sqlite）退化为LIKE匹配并按命中字段加权打分
+func (r *DAO) SearchPosts(query string, terms []string, page, perPage int64) ([]models.Post, []SearchHit, int64, error) {
+	var hits []SearchHit
+	var total int64
+
+	var scoreExpr string
+	var scoreArgs []interface{}
+	var cond string
+	var condArgs []interface{}
+
+	if r.db.Dialector.Name() == "mysql" {
+		scoreExpr = "MATCH(title, excerpt, content) AGAINST(? IN NATURAL LANGUAGE MODE)"
+		scoreArgs = []interface{}{query}
+		cond = scoreExpr
+		condArgs = scoreArgs
+	} else {
+		scoreParts := make([]string, 0, len(terms))
+		condParts := make([]string, 0, len(terms))
+		for _, term := range terms {
+			pattern := "%" + escapeLike(term) + "%"
+			scoreParts = append(scoreParts, "(CASE WHEN title LIKE ? ESCAPE '!' THEN 3 ELSE 0 END"+
+				" + CASE WHEN excerpt LIKE ? ESCAPE '!' THEN 2 ELSE 0 END"+
+				" + CASE WHEN content LIKE ? ESCAPE '!' THEN 1 ELSE 0 END)")
+			scoreArgs = append(scoreArgs, pattern, pattern, pattern)
+			condParts = append(condParts, "(title LIKE ? ESCAPE '!' OR excerpt LIKE ? ESCAPE '!' OR content LIKE ? ESCAPE '!')")
+			condArgs = append(condArgs, pattern, pattern, pattern)
+		}
+		scoreExpr = strings.Join(scoreParts, " + ")
+		cond = strings.Join(condParts, " OR ")
+	}
+
+	err := r.db.Model(&models.Post{}).Where(cond, condArgs...).Count(&total).Error
+	if err != nil {
+		return nil, nil, 0, err
+	}
+
+	offset := (page - 1) * perPage
+
+	// 先分页取出命中的id和得分，再统一加载关联
+	err = r.db.Model(&models.Post{}).
+		Select("id, "+scoreExpr+" AS score", scoreArgs...).
+		Where(cond, condArgs...).
+		Order("score DESC").
+		Order("created_at DESC").
+		Offset(int(offset)).
+		Limit(int(perPage)).
+		Scan(&hits).Error
+	if err != nil || len(hits) == 0 {
+		return nil, hits, total, err
+	}
+
+	ids := make([]uint, len(hits))
+	for i, hit := range hits {
+		ids[i] = hit.ID
+	}
+
+	var found []models.Post
+	err = withCommentCnt(r.db.Model(&models.Post{})).
+		Preload("Author").
+		Preload("Category", "id IS NOT NULL").
+		Preload("Tags", "id IS NOT NULL").
+		Where("id IN ?", ids).
+		Find(&found).Error
+	if err != nil {
+		return nil, nil, 0, err
+	}
+
+	// 按命中顺序重新排列
+	byID := make(map[uint]models.Post, len(found))
+	for _, post := range found {
+		byID[post.ID] = post
+	}
+	posts := make([]models.Post, 0, len(hits))
+	matched := make([]SearchHit, 0, len(hits))
+	for _, hit := range hits {
+		if post, ok := byID[hit.ID]; ok {
+			posts = append(posts, post)
+			matched = append(matched, hit)
+		}
+	}
+
+	return posts, matched, total, nil
+}
+
+// LIKE通配符转义，统一使用!作为转义符以兼容mysql和sqlite
+func escapeLike(s string) string {
+	s = strings.ReplaceAll(s, "!", "!!")
+	s = strings.ReplaceAll(s, "%", "!%")
+	s = strings.ReplaceAll(s, "_", "!_")
+	return s
+}
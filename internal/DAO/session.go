@@ -0,0 +1,81 @@
+package dao
+
+import (
+	"time"
+
+	"github.com/Jack-samu/the-blog-backend-gin.git/internal/models"
+	"gorm.io/gorm"
+)
+
+func (r *DAO) CreateSession(tx *gorm.DB, session *models.Session) error {
+	if tx == nil {
+		tx = r.db
+	}
+	return tx.Create(session).Error
+}
+
+func (r *DAO) GetSession(id string) (*models.Session, error) {
+	var session models.Session
+	if err := r.db.Where("id = ?", id).First(&session).Error; err != nil {
+		return nil, err
+	}
+	return &session, nil
+}
+
+func (r *DAO) GetActiveSessions(userID string) ([]models.Session, error) {
+	var sessions []models.Session
+	err := r.db.Where("user_id = ? AND revoked_at IS NULL AND expires_at > ?", userID, time.Now()).
+		Order("last_seen_at DESC").
+		Find(&sessions).Error
+	return sessions, err
+}
+
+// 刷新活跃时间，expiresAt不为空时同时延长会话有效期
+func (r *DAO) TouchSession(tx *gorm.DB, id string, expiresAt *time.Time) error {
+	if tx == nil {
+		tx = r.db
+	}
+
+	fields := map[string]interface{}{"last_seen_at": time.Now()}
+	if expiresAt != nil {
+		fields["expires_at"] = *expiresAt
+	}
+	return tx.Model(&models.Session{}).Where("id = ?", id).Updates(fields).Error
+}
+
+// 吊销会话和其下的refresh token，返回实际吊销的会话数
+func (r *DAO) revokeSessions(tx *gorm.DB, query string, args ...interface{}) (int64, error) {
+	var ids []string
+	err := tx.Model(&models.Session{}).
+		Where("revoked_at IS NULL").
+		Where(query, args...).
+		Pluck("id", &ids).Error
+	if err != nil || len(ids) == 0 {
+		return 0, err
+	}
+
+	now := time.Now()
+	if err = tx.Model(&models.RefreshToken{}).
+		Where("family_id IN ? AND revoked_at IS NULL", ids).
+		Update("revoked_at", now).Error; err != nil {
+		return 0, err
+	}
+
+	result := tx.Model(&models.Session{}).Where("id IN ?", ids).Update("revoked_at", now)
+	return result.RowsAffected, result.Error
+}
+
+func (r *DAO) RevokeSession(tx *gorm.DB, userID, id string) (int64, error) {
+	if tx == nil {
+		tx = r.db
+	}
+	return r.revokeSessions(tx, "user_id = ? AND id = ?", userID, id)
+}
+
+// keepID为空时吊销该用户的全部会话
+func (r *DAO) RevokeOtherSessions(tx *gorm.DB, userID, keepID string) (int64, error) {
+	if tx == nil {
+		tx = r.db
+	}
+	return r.revokeSessions(tx, "user_id = ? AND id <> ?", userID, keepID)
+}
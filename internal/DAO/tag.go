@@ -0,0 +1,103 @@
+package dao
+
+import (
+	"github.com/Jack-samu/the-blog-backend-gin.git/internal/models"
+	"gorm.io/gorm"
+)
+
+// 标签排序方式
+const (
+	TagSortCount  = "count"
+	TagSortName   = "name"
+	TagSortNewest = "newest"
+)
+
+// 标签及其关联的已发布文章数
+type TagCount struct {
+	ID      uint
+	Name    string
+	PostCnt int64
+}
+
+func (r *DAO) tagCountQuery() *gorm.DB {
+	return r.db.Model(&models.Tag{}).
+		Select("tags.id, tags.name, COUNT(post_tags.post_id) AS post_cnt").
+		Joins("JOIN post_tags ON post_tags.tag_id = tags.id").
+		Group("tags.id, tags.name")
+}
+
+// 标签云，只返回至少关联一篇文章的标签
+func (r *DAO) GetTagsWithCount(sort string, limit int) ([]TagCount, error) {
+	var tags []TagCount
+
+	query := r.tagCountQuery()
+	switch sort {
+	case TagSortName:
+		query = query.Order("tags.name ASC")
+	case TagSortNewest:
+		query = query.Order("tags.created_at DESC")
+	default:
+		query = query.Order("post_cnt DESC").Order("tags.name ASC")
+	}
+
+	err := query.Limit(limit).Scan(&tags).Error
+	return tags, err
+}
+
+func (r *DAO) GetTagByName(name string) (*TagCount, error) {
+	var tag models.Tag
+	if err := r.db.Where("name = ?", name).First(&tag).Error; err != nil {
+		return nil, err
+	}
+
+	var cnt int64
+	err := r.db.Table("post_tags").Where("tag_id = ?", tag.ID).Count(&cnt).Error
+
+	return &TagCount{ID: tag.ID, Name: tag.Name, PostCnt: cnt}, err
+}
+
+// 编辑器用的标签联想，包括还没有文章的标签，常用的排在前面
+func (r *DAO) SuggestTags(prefix string, limit int) ([]TagCount, error) {
+	var tags []TagCount
+
+	err := r.db.Model(&models.Tag{}).
+		Select("tags.id, tags.name, COUNT(post_tags.post_id) AS post_cnt").
+		Joins("LEFT JOIN post_tags ON post_tags.tag_id = tags.id").
+		Where("tags.name LIKE ? ESCAPE '!'", escapeLike(prefix)+"%").
+		Group("tags.id, tags.name").
+		Order("post_cnt DESC").
+		Order("tags.name ASC").
+		Limit(limit).
+		Scan(&tags).Error
+
+	return tags, err
+}
+
+func (r *DAO) GetTag(id uint) (*models.Tag, error) {
+	var tag models.Tag
+	if err := r.db.First(&tag, id).Error; err != nil {
+		return nil, err
+	}
+	return &tag, nil
+}
+
+func (r *DAO) ExistTagName(name string, excludeID uint) (bool, error) {
+	var cnt int64
+	err := r.db.Model(&models.Tag{}).Where("name = ? AND id <> ?", name, excludeID).Count(&cnt).Error
+	return cnt > 0, err
+}
+
+func (r *DAO) RenameTag(tag *models.Tag, name string) error {
+	return r.db.Model(tag).Update("name", name).Error
+}
+
+// 删除标签，先清掉文章和草稿上的关联
+func (r *DAO) DeleteTag(tx *gorm.DB, tag *models.Tag) error {
+	if err := tx.Exec("DELETE FROM post_tags WHERE tag_id = ?", tag.ID).Error; err != nil {
+		return err
+	}
+	if err := tx.Exec("DELETE FROM draft_tags WHERE tag_id = ?", tag.ID).Error; err != nil {
+		return err
+	}
+	return tx.Delete(tag).Error
+}
@@ -0,0 +1,36 @@
+package dao
+
+import (
+	"time"
+
+	"github.com/Jack-samu/the-blog-backend-gin.git/internal/models"
+	"gorm.io/gorm"
+)
+
+func (r *DAO) CreateRefreshToken(tx *gorm.DB, token *models.RefreshToken) error {
+	if tx == nil {
+		tx = r.db
+	}
+	return tx.Create(token).Error
+}
+
+func (r *DAO) GetRefreshToken(tx *gorm.DB, hash string) (*models.RefreshToken, error) {
+	if tx == nil {
+		tx = r.db
+	}
+
+	var token models.RefreshToken
+	if err := tx.Where("token_hash = ?", hash).First(&token).Error; err != nil {
+		return nil, err
+	}
+	return &token, nil
+}
+
+// 标记为已使用，只有未使用且未吊销的token能标记成功，
+// 并发刷新时只有一个请求能拿到新token
+func (r *DAO) UseRefreshToken(tx *gorm.DB, id uint) (bool, error) {
+	result := tx.Model(&models.RefreshToken{}).
+		Where("id = ? AND used_at IS NULL AND revoked_at IS NULL", id).
+		Update("used_at", time.Now())
+	return result.RowsAffected > 0, result.Error
+}
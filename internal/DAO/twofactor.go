@@ -0,0 +1,86 @@
+package dao
+
+import (
+	"time"
+
+	"github.com/Jack-samu/the-blog-backend-gin.git/internal/models"
+	"gorm.io/gorm"
+)
+
+// 保存待确认的密钥，确认前两步验证不生效
+func (r *DAO) SetTOTPSecret(userID, secret string) error {
+	return r.db.Model(&models.User{}).Where("id = ?", userID).Updates(map[string]interface{}{
+		"totp_secret":     secret,
+		"totp_enabled_at": nil,
+		"totp_last_step":  0,
+	}).Error
+}
+
+func (r *DAO) EnableTOTP(tx *gorm.DB, userID string, step int64) error {
+	if tx == nil {
+		tx = r.db
+	}
+	return tx.Model(&models.User{}).Where("id = ?", userID).Updates(map[string]interface{}{
+		"totp_enabled_at": time.Now(),
+		"totp_last_step":  step,
+	}).Error
+}
+
+// 关闭两步验证，同时删除恢复码
+func (r *DAO) DisableTOTP(tx *gorm.DB, userID string) error {
+	if tx == nil {
+		tx = r.db
+	}
+
+	err := tx.Model(&models.User{}).Where("id = ?", userID).Updates(map[string]interface{}{
+		"totp_secret":     "",
+		"totp_enabled_at": nil,
+		"totp_last_step":  0,
+	}).Error
+	if err != nil {
+		return err
+	}
+
+	return tx.Where("user_id = ?", userID).Delete(&models.RecoveryCode{}).Error
+}
+
+// 记录已使用的验证码周期，同一周期或更早的验证码不能再次使用
+func (r *DAO) UseTOTPStep(tx *gorm.DB, userID string, step int64) (bool, error) {
+	if tx == nil {
+		tx = r.db
+	}
+
+	result := tx.Model(&models.User{}).
+		Where("id = ? AND totp_last_step < ?", userID, step).
+		Update("totp_last_step", step)
+	return result.RowsAffected > 0, result.Error
+}
+
+// 用新的恢复码替换旧的
+func (r *DAO) ReplaceRecoveryCodes(tx *gorm.DB, userID string, hashes []string) error {
+	if tx == nil {
+		tx = r.db
+	}
+
+	if err := tx.Where("user_id = ?", userID).Delete(&models.RecoveryCode{}).Error; err != nil {
+		return err
+	}
+
+	codes := make([]models.RecoveryCode, 0, len(hashes))
+	for _, hash := range hashes {
+		codes = append(codes, models.RecoveryCode{UserID: userID, CodeHash: hash})
+	}
+	return tx.Create(&codes).Error
+}
+
+// 核销恢复码，并发核销时只有一个能成功
+func (r *DAO) UseRecoveryCode(tx *gorm.DB, userID, hash string) (bool, error) {
+	if tx == nil {
+		tx = r.db
+	}
+
+	result := tx.Model(&models.RecoveryCode{}).
+		Where("user_id = ? AND code_hash = ? AND used_at IS NULL", userID, hash).
+		Update("used_at", time.Now())
+	return result.RowsAffected > 0, result.Error
+}
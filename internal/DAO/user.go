@@ -4,11 +4,16 @@ import (
 	"time"
 
 	"github.com/Jack-samu/the-blog-backend-gin.git/internal/models"
+	"gorm.io/gorm"
 )
 
-func (r *DAO) ExistByEmail(email string) (bool, error) {
+func (r *DAO) ExistByEmail(tx *gorm.DB, email string) (bool, error) {
+	if tx == nil {
+		tx = r.db
+	}
+
 	var cnt int64
-	err := r.db.Model(&models.User{}).Where("email = ?", email).Count(&cnt).Error
+	err := tx.Model(&models.User{}).Where("email = ?", email).Count(&cnt).Error
 	return cnt > 0, err
 }
 
@@ -18,8 +23,11 @@ func (r *DAO) ExistByUsername(username string) (bool, error) {
 	return cnt > 0, err
 }
 
-func (r *DAO) CreateUser(u *models.User) error {
-	return r.db.Create(u).Error
+func (r *DAO) CreateUser(tx *gorm.DB, u *models.User) error {
+	if tx == nil {
+		tx = r.db
+	}
+	return tx.Create(u).Error
 }
 
 func (r *DAO) GetUserByName(username string) (*models.User, error) {
@@ -32,9 +40,13 @@ func (r *DAO) GetUserByName(username string) (*models.User, error) {
 	return user, err
 }
 
-func (r *DAO) GetUserById(id string) (*models.User, error) {
+func (r *DAO) GetUserById(tx *gorm.DB, id string) (*models.User, error) {
+	if tx == nil {
+		tx = r.db
+	}
+
 	user := &models.User{}
-	err := r.db.Model(&models.User{}).Where("id = ?", id).First(&user).Error
+	err := tx.Model(&models.User{}).Where("id = ?", id).First(&user).Error
 	if err != nil {
 		return nil, err
 	}
@@ -42,10 +54,29 @@ func (r *DAO) GetUserById(id string) (*models.User, error) {
 	return user, err
 }
 
-func (r *DAO) IncreaseFailedLogin(u *models.User) error {
-	// u.FailedLogin = u.FailedLogin + 1
-	// return r.db.Save(u).Error
-	return r.db.Model(&u).Update("failed_login", u.FailedLogin+1).Error
+// 登录失败计数加一，返回库里加一之后的计数，并发失败时也不会漏掉
+func (r *DAO) IncreaseFailedLogin(u *models.User) (int, error) {
+	err := r.db.Model(&models.User{}).Where("id = ?", u.ID).
+		UpdateColumn("failed_login", gorm.Expr("failed_login + 1")).Error
+	if err != nil {
+		return 0, err
+	}
+
+	var failed int
+	err = r.db.Model(&models.User{}).Where("id = ?", u.ID).Pluck("failed_login", &failed).Error
+	return failed, err
+}
+
+func (r *DAO) LockLogin(u *models.User, lockedUntil time.Time) error {
+	return r.db.Model(&models.User{}).Where("id = ?", u.ID).Update("locked_until", lockedUntil).Error
+}
+
+// 登录成功后清除失败计数和锁定
+func (r *DAO) ClearLoginLock(u *models.User) error {
+	return r.db.Model(&u).Updates(map[string]interface{}{
+		"failed_login": 0,
+		"locked_until": nil,
+	}).Error
 }
 
 func (r *DAO) IncreaseCaptchaCnt(u *models.User) error {
@@ -98,3 +129,38 @@ func (r *DAO) SaveImg(filename, user_id string, is_avatar bool) error {
 
 	return r.db.Create(img).Error
 }
+
+func (r *DAO) SetUserRole(id, role string) error {
+	result := r.db.Model(&models.User{}).Where("id = ?", id).Update("role", role)
+	if result.Error == nil && result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return result.Error
+}
+
+// 修改密码时同时清除登录锁定
+func (r *DAO) UpdatePassword(tx *gorm.DB, userID, pwdHash string) error {
+	if tx == nil {
+		tx = r.db
+	}
+	return tx.Model(&models.User{}).Where("id = ?", userID).Updates(map[string]interface{}{
+		"pwd":          pwdHash,
+		"failed_login": 0,
+		"locked_until": nil,
+	}).Error
+}
+
+// 新邮箱经过确认才会写入，因此同时视为已验证
+func (r *DAO) UpdateEmail(tx *gorm.DB, userID, email string) error {
+	if tx == nil {
+		tx = r.db
+	}
+	return tx.Model(&models.User{}).Where("id = ?", userID).Updates(map[string]interface{}{
+		"email":             email,
+		"email_verified_at": time.Now(),
+	}).Error
+}
+
+func (r *DAO) UpdateProfile(userID string, fields map[string]interface{}) error {
+	return r.db.Model(&models.User{}).Where("id = ?", userID).Updates(fields).Error
+}
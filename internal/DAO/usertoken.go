@@ -0,0 +1,73 @@
+package dao
+
+import (
+	"errors"
+	"time"
+
+	"github.com/Jack-samu/the-blog-backend-gin.git/internal/models"
+	"gorm.io/gorm"
+)
+
+var ErrTokenUsed = errors.New("链接已使用或已失效")
+
+func (r *DAO) CreateUserToken(tx *gorm.DB, token *models.UserToken) error {
+	if tx == nil {
+		tx = r.db
+	}
+	return tx.Create(token).Error
+}
+
+// 最近一次签发的token，用于判断重发冷却
+func (r *DAO) GetLatestUserToken(userID, purpose string) (*models.UserToken, error) {
+	var token models.UserToken
+	err := r.db.Where("user_id = ? AND purpose = ?", userID, purpose).
+		Order("created_at DESC").
+		First(&token).Error
+	if err != nil {
+		return nil, err
+	}
+	return &token, nil
+}
+
+// 让该用户同一用途下尚未使用的token全部失效
+func (r *DAO) InvalidateUserTokens(tx *gorm.DB, userID, purpose string) error {
+	if tx == nil {
+		tx = r.db
+	}
+	return tx.Model(&models.UserToken{}).
+		Where("user_id = ? AND purpose = ? AND used_at IS NULL", userID, purpose).
+		Update("used_at", time.Now()).Error
+}
+
+// 核销token，已使用或过期时返回ErrTokenUsed，并发核销时只有一个能成功
+func (r *DAO) ConsumeUserToken(tx *gorm.DB, hash, purpose string) (*models.UserToken, error) {
+	var token models.UserToken
+	if err := tx.Where("token_hash = ? AND purpose = ?", hash, purpose).First(&token).Error; err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	if !now.Before(token.ExpiresAt) {
+		return nil, ErrTokenUsed
+	}
+
+	result := tx.Model(&models.UserToken{}).
+		Where("id = ? AND used_at IS NULL", token.ID).
+		Update("used_at", now)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return nil, ErrTokenUsed
+	}
+
+	token.UsedAt = &now
+	return &token, nil
+}
+
+func (r *DAO) SetEmailVerified(tx *gorm.DB, userID string) error {
+	if tx == nil {
+		tx = r.db
+	}
+	return tx.Model(&models.User{}).Where("id = ?", userID).Update("email_verified_at", time.Now()).Error
+}
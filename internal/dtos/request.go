@@ -1,5 +1,7 @@
 package dtos
 
+import "time"
+
 type RegisterReq struct {
 	Username string `json:"username" binding:"required,min=3,max=20"`
 	Email    string `json:"email" binding:"required,email"`
@@ -13,6 +15,11 @@ type LoginReq struct {
 	Password string `json:"password" binding:"required,min=8"`
 }
 
+// refreshToken也可以放在Authorization头里
+type RefreshReq struct {
+	RefreshToken string `json:"refreshToken"`
+}
+
 type GetCodeReq struct {
 	Username string `json:"username" binding:"required,min=3,max=20"`
 }
@@ -22,6 +29,105 @@ type VerifyReq struct {
 	Code     string `json:"verificationCode" binding:"required"`
 }
 
+type ChangePasswordReq struct {
+	OldPassword string `json:"oldPassword" binding:"required"`
+	NewPassword string `json:"newPassword" binding:"required,min=8"`
+}
+
+type ChangeEmailReq struct {
+	Email    string `json:"email" binding:"required,email"`
+	Password string `json:"password" binding:"required"`
+}
+
+type TwoFactorCodeReq struct {
+	Code string `json:"code" binding:"required"`
+}
+
+type TwoFactorLoginReq struct {
+	ChallengeToken string `json:"challengeToken" binding:"required"`
+	Code           string `json:"code" binding:"required"`
+}
+
+// 只更新传了的字段，Links传了时整体替换
+type ProfileUpdateReq struct {
+	DisplayName *string           `json:"displayName"`
+	Bio         *string           `json:"bio"`
+	Website     *string           `json:"website" binding:"omitempty,max=255"`
+	Links       map[string]string `json:"links"`
+}
+
+type ResetReq struct {
+	Token     string `json:"token" binding:"required"`
+	Password  string `json:"password" binding:"required"`
+	PwdRepeat string `json:"pwdRepeat" binding:"required"`
+}
+
+// 列表分页参数，带cursor时使用游标分页，page被忽略
+type PageReq struct {
+	Page    int64  `form:"page"`
+	PerPage int64  `form:"per_page"`
+	Cursor  string `form:"cursor"`
+}
+
+// 公共文章列表的查询参数，日期支持2006-01-02和RFC3339两种格式
+type ArticleListReq struct {
+	PageReq
+	Tag      string `form:"tag"`
+	Category string `form:"category"`
+	Author   string `form:"author"`
+	From     string `form:"from"`
+	To       string `form:"to"`
+	Sort     string `form:"sort" binding:"omitempty,oneof=newest oldest views likes comments"`
+}
+
+type TagListReq struct {
+	Sort  string `form:"sort" binding:"omitempty,oneof=count name newest"`
+	Limit int    `form:"limit"`
+}
+
+type TagSuggestReq struct {
+	Prefix string `form:"prefix" binding:"required,max=36"`
+	Limit  int    `form:"limit"`
+}
+
+type SeriesReq struct {
+	Name        string `json:"name" binding:"required,max=20"`
+	Description string `json:"description" binding:"max=500"`
+	Cover       string `json:"cover"`
+}
+
+// 修改系列，只更新传了的字段
+type SeriesUpdateReq struct {
+	Name        *string `json:"name" binding:"omitempty,min=1,max=20"`
+	Description *string `json:"description" binding:"omitempty,max=500"`
+	Cover       *string `json:"cover"`
+}
+
+type SeriesOrderReq struct {
+	ArticleIDs []uint `json:"article_ids" binding:"required"`
+}
+
+type ReadingListReq struct {
+	Name        string `json:"name" binding:"required,max=50"`
+	Description string `json:"description" binding:"max=500"`
+	Public      bool   `json:"public"`
+}
+
+// 修改阅读列表，只更新传了的字段
+type ReadingListUpdateReq struct {
+	Name        *string `json:"name" binding:"omitempty,min=1,max=50"`
+	Description *string `json:"description" binding:"omitempty,max=500"`
+	Public      *bool   `json:"public"`
+}
+
+type ReadingListItemReq struct {
+	ArticleID uint `json:"article_id" binding:"required"`
+}
+
+type ReadingListOrderReq struct {
+	ArticleIDs []uint `json:"article_ids" binding:"required"`
+}
+
 type ArticleReq struct {
 	// 判别项
 	Id uint `json:"id,omitempty"`
@@ -34,6 +140,46 @@ type ArticleReq struct {
 	// 附加型表单
 	Category string   `json:"category,omitempty"`
 	Tags     []string `json:"tags,omitempty"`
+
+	// 定时发布，晚于当前时间时先存为草稿，到点后自动发布
+	PublishAt *time.Time `json:"publish_at,omitempty"`
+}
+
+type ScheduleReq struct {
+	PublishAt time.Time `json:"publish_at" binding:"required"`
+}
+
+// 自动保存，只更新传了的字段，version为客户端持有的草稿版本
+type AutosaveReq struct {
+	Version  int       `json:"version" binding:"required,min=1"`
+	Title    *string   `json:"title" binding:"omitempty,max=100"`
+	Content  *string   `json:"content"`
+	Excerpt  *string   `json:"excerpt" binding:"omitempty,max=200"`
+	Cover    *string   `json:"cover"`
+	Category *string   `json:"category" binding:"omitempty,max=20"`
+	Tags     *[]string `json:"tags"`
+}
+
+type RoleReq struct {
+	Role string `json:"role" binding:"required,oneof=reader author moderator admin"`
+}
+
+// 后台用户列表的筛选参数，日期格式同文章列表
+type AdminUserListReq struct {
+	Page           int64  `form:"page"`
+	PerPage        int64  `form:"per_page"`
+	Keyword        string `form:"q"`
+	Role           string `form:"role" binding:"omitempty,oneof=reader author moderator admin"`
+	RegisteredFrom string `form:"registered_from"`
+	RegisteredTo   string `form:"registered_to"`
+	ActiveFrom     string `form:"active_from"`
+	ActiveTo       string `form:"active_to"`
+	MinFailed      int    `form:"min_failed" binding:"omitempty,min=0"`
+	Suspended      *bool  `form:"suspended"`
+}
+
+type TagRenameReq struct {
+	Name string `json:"name" binding:"required,max=36"`
 }
 
 type CommentReq struct {
@@ -1,31 +1,99 @@
 package dtos
 
+import (
+	dao "github.com/Jack-samu/the-blog-backend-gin.git/internal/DAO"
+	"github.com/Jack-samu/the-blog-backend-gin.git/internal/utils"
+)
+
+// 开启了两步验证时只返回ChallengeToken，用于提交验证码完成登录
 type LoginResp struct {
+	Token             string    `json:"token"`
+	RefreshToken      string    `json:"refreshToken"`
+	UserInfo          *UserInfo `json:"userInfo"`
+	TwoFactorRequired bool      `json:"twoFactorRequired,omitempty"`
+	ChallengeToken    string    `json:"challengeToken,omitempty"`
+}
+
+type UserInfo struct {
+	ID        string `json:"id"`
+	Username  string `json:"username"`
+	Email     string `json:"email"`
+	Avatar    string `json:"avatar,omitempty"`
+	Role      string `json:"role"`
+	Verified  bool   `json:"verified"`
+	TwoFactor bool   `json:"twoFactor"`
+	Posts     int64  `json:"posts"`
+}
+
+type AdminUserItem struct {
+	ID           string `json:"id"`
+	Username     string `json:"username"`
+	Email        string `json:"email"`
+	Role         string `json:"role"`
+	CreatedAt    string `json:"created_at"`
+	LastActivity string `json:"last_activity"`
+	FailedLogin  int    `json:"failed_login"`
+	SuspendedAt  string `json:"suspended_at,omitempty"`
+}
+
+type AdminUserListResp struct {
+	Users       []AdminUserItem `json:"users"`
+	Cnt         uint            `json:"total"`
+	CurrentPage uint            `json:"current_page"`
+}
+
+type RefreshResp struct {
 	Token        string    `json:"token"`
 	RefreshToken string    `json:"refreshToken"`
 	UserInfo     *UserInfo `json:"userInfo"`
 }
 
-type UserInfo struct {
-	ID       string `json:"id"`
-	Username string `json:"username"`
-	Email    string `json:"email"`
-	Avatar   string `json:"avatar,omitempty"`
-	Posts    int64  `json:"posts"`
+type SessionItem struct {
+	ID         string `json:"id"`
+	UserAgent  string `json:"user_agent"`
+	IP         string `json:"ip"`
+	CreatedAt  string `json:"created_at"`
+	LastSeenAt string `json:"last_seen_at"`
+	Current    bool   `json:"current"`
 }
 
-type RefreshResp struct {
-	Token    string    `json:"token"`
-	UserInfo *UserInfo `json:"userInfo"`
+type SessionsResp struct {
+	Sessions []SessionItem `json:"sessions"`
 }
 
+// 邮箱和草稿数只返回给本人
 type ProfileResp struct {
-	ID       string `json:"id"`
-	Username string `json:"username"`
-	Email    string `json:"email"`
-	Articles int64  `json:"articleCount"`
-	Drafts   int64  `json:"draftCount"`
-	Avatar   string `json:"avatar"`
+	ID          string            `json:"id"`
+	Username    string            `json:"username"`
+	DisplayName string            `json:"displayName"`
+	Email       string            `json:"email,omitempty"`
+	Articles    int64             `json:"articleCount"`
+	Drafts      int64             `json:"draftCount,omitempty"`
+	Avatar      string            `json:"avatar"`
+	Bio         string            `json:"bio"`
+	Website     string            `json:"website"`
+	Links       map[string]string `json:"links"`
+	JoinedAt    string            `json:"joinedAt"`
+	Followers   int64             `json:"followerCount"`
+	Following   int64             `json:"followingCount"`
+}
+
+// 公开的作者主页，不包含邮箱；Followed表示当前登录用户是否已关注
+type AuthorPageResp struct {
+	ID          string            `json:"id"`
+	Username    string            `json:"username"`
+	DisplayName string            `json:"displayName"`
+	Avatar      string            `json:"avatar"`
+	Bio         string            `json:"bio"`
+	Website     string            `json:"website"`
+	Links       map[string]string `json:"links"`
+	JoinedAt    string            `json:"joinedAt"`
+	Articles    int64             `json:"articleCount"`
+	Followers   int64             `json:"followerCount"`
+	Following   int64             `json:"followingCount"`
+	Followed    bool              `json:"followed"`
+	Series      []SeriesItem      `json:"series"`
+	RecentPosts []PostListItem    `json:"recentPosts"`
 }
 
 type PhotoItem struct {
@@ -55,6 +123,8 @@ type PostListItem struct {
 	Views    uint `json:"views"`
 	Likes    uint `json:"likes"`
 	Comments int  `json:"comments"`
+	// 当前登录用户是否收藏，未登录时恒为false
+	Bookmarked bool `json:"is_bookmarked"`
 }
 
 type PostDetailItem struct {
@@ -63,10 +133,36 @@ type PostDetailItem struct {
 	Content string        `json:"content"`
 }
 
+// 游标分页信息，分别指向下一页和上一页
+type CursorInfo struct {
+	NextCursor string `json:"next_cursor,omitempty"`
+	PrevCursor string `json:"prev_cursor,omitempty"`
+}
+
 type PostListResp struct {
 	Posts       []PostListItem `json:"articles"`
 	Cnt         uint           `json:"total"`
 	CurrentPage uint           `json:"current_page"`
+	CursorInfo
+}
+
+type SearchHighlight struct {
+	Title   string `json:"title,omitempty"`
+	Excerpt string `json:"excerpt,omitempty"`
+	Content string `json:"content,omitempty"`
+}
+
+type SearchItem struct {
+	PostListItem
+	Score     float64         `json:"score"`
+	Highlight SearchHighlight `json:"highlight"`
+}
+
+type SearchResp struct {
+	Query       string       `json:"query"`
+	Posts       []SearchItem `json:"articles"`
+	Cnt         uint         `json:"total"`
+	CurrentPage uint         `json:"current_page"`
 }
 
 type PostDetailResp struct {
@@ -81,12 +177,14 @@ type DraftsResp struct {
 	Drafts      []DraftItem `json:"drafts"`
 	Cnt         uint        `json:"total"`
 	CurrentPage uint        `json:"current_page"`
+	CursorInfo
 }
 
 type PostListPersonalResp struct {
 	Posts       interface{} `json:"publishedArticles"`
 	Cnt         uint        `json:"total"`
 	CurrentPage uint        `json:"current_page"`
+	CursorInfo
 }
 
 type SeriesResp struct {
@@ -94,9 +192,34 @@ type SeriesResp struct {
 }
 
 type SeriesItem struct {
-	ID    uint           `json:"id"`
-	Name  string         `json:"name"`
-	Posts []ArticleBasic `json:"articles"`
+	ID          uint           `json:"id"`
+	Name        string         `json:"name"`
+	Description string         `json:"description"`
+	Cover       string         `json:"cover"`
+	Count       int            `json:"count"`
+	Posts       []ArticleBasic `json:"articles"`
+}
+
+type SeriesDetailResp struct {
+	SeriesItem
+	Author    string `json:"author"`
+	CreatedAt string `json:"created_at"`
+	UpdatedAt string `json:"updated_at"`
+}
+
+type TagItem struct {
+	ID    uint   `json:"id"`
+	Name  string `json:"name"`
+	Posts int64  `json:"articles"`
+}
+
+type TagsResp struct {
+	Tags []TagItem `json:"tags"`
+}
+
+type TagDetailResp struct {
+	Tag TagItem `json:"tag"`
+	PostListResp
 }
 
 type DraftDetail struct {
@@ -107,6 +230,9 @@ type DraftDetail struct {
 	Tags     []string      `json:"tags"`
 	Author   AuthorProfile `json:"author"`
 	Content  string        `json:"content"`
+	// 已发布文章的编辑稿才有
+	PostID  *uint `json:"post_id,omitempty"`
+	Version int   `json:"version"`
 }
 
 type DraftItem struct {
@@ -116,6 +242,49 @@ type DraftItem struct {
 	Category string   `json:"category"`
 	Tags     []string `json:"tags"`
 	Author   string   `json:"author"`
+	PostID   *uint    `json:"post_id,omitempty"`
+	Version  int      `json:"version"`
+	// 定时发布的草稿才有
+	PublishAt string `json:"publish_at,omitempty"`
+}
+
+type ScheduledResp struct {
+	Drafts []DraftItem `json:"drafts"`
+}
+
+type AutosaveResp struct {
+	ID        uint   `json:"id"`
+	Version   int    `json:"version"`
+	UpdatedAt string `json:"updated_at"`
+}
+
+type RevisionItem struct {
+	Version   int    `json:"version"`
+	Title     string `json:"title"`
+	Author    string `json:"author"`
+	CreatedAt string `json:"created_at"`
+}
+
+type RevisionsResp struct {
+	PostID    uint           `json:"post_id"`
+	Revisions []RevisionItem `json:"revisions"`
+}
+
+type RevisionDetailResp struct {
+	RevisionItem
+	Excerpt string `json:"excerpt"`
+	Cover   string `json:"cover"`
+	Content string `json:"content"`
+}
+
+// 两个版本间各字段的逐行差异
+type RevisionDiffResp struct {
+	From    int              `json:"from"`
+	To      int              `json:"to"`
+	Title   []utils.DiffLine `json:"title"`
+	Excerpt []utils.DiffLine `json:"excerpt"`
+	Cover   []utils.DiffLine `json:"cover"`
+	Content []utils.DiffLine `json:"content"`
 }
 
 type AuthorProfile struct {
@@ -127,6 +296,7 @@ type AuthorProfile struct {
 type CommentsResp struct {
 	Total    int           `json:"total"`
 	Comments []CommentItem `json:"comments"`
+	CursorInfo
 }
 
 type CommentResp struct {
@@ -147,6 +317,7 @@ type CommentItem struct {
 type RepliesResp struct {
 	Total   int         `json:"total"`
 	Replies []ReplyItem `json:"replies"`
+	CursorInfo
 }
 
 type ReplyResp struct {
@@ -164,6 +335,11 @@ type ReplyItem struct {
 	ParentID  uint          `json:"parent_id"`
 }
 
+type LikeResp struct {
+	Likes int  `json:"likes"`
+	Liked bool `json:"is_liked"`
+}
+
 func NewPostList(list []PostListItem, total, page int64) *PostListResp {
 	return &PostListResp{
 		Posts:       list,
@@ -187,3 +363,65 @@ func NewDraftsPersonal(list []DraftItem, total, page int64) *DraftsResp {
 		CurrentPage: uint(page),
 	}
 }
+
+func NewCursorInfo(info *dao.PageInfo) CursorInfo {
+	return CursorInfo{
+		NextCursor: info.NextCursor,
+		PrevCursor: info.PrevCursor,
+	}
+}
+
+type TwoFactorSetupResp struct {
+	Secret string `json:"secret"`
+	URI    string `json:"uri"`
+}
+
+// 恢复码只在生成时返回一次
+type RecoveryCodesResp struct {
+	Codes []string `json:"codes"`
+}
+
+type FollowResp struct {
+	Followed  bool  `json:"followed"`
+	Followers int64 `json:"followerCount"`
+}
+
+type FollowUserItem struct {
+	ID          string `json:"id"`
+	Username    string `json:"username"`
+	DisplayName string `json:"displayName"`
+	Avatar      string `json:"avatar"`
+	FollowedAt  string `json:"followedAt"`
+}
+
+type FollowListResp struct {
+	Users       []FollowUserItem `json:"users"`
+	Cnt         uint             `json:"total"`
+	CurrentPage uint             `json:"current_page"`
+	CursorInfo
+}
+
+type BookmarkResp struct {
+	Bookmarked bool `json:"is_bookmarked"`
+}
+
+type ReadingListItem struct {
+	ID          uint   `json:"id"`
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	Public      bool   `json:"public"`
+	ShareURL    string `json:"share_url,omitempty"`
+	Count       int    `json:"count"`
+	CreatedAt   string `json:"created_at"`
+	UpdatedAt   string `json:"updated_at"`
+}
+
+type ReadingListsResp struct {
+	Lists []ReadingListItem `json:"reading_lists"`
+}
+
+type ReadingListDetailResp struct {
+	ReadingListItem
+	Owner AuthorProfile  `json:"owner"`
+	Posts []PostListItem `json:"articles"`
+}
@@ -5,6 +5,7 @@ import (
 
 	dao "github.com/Jack-samu/the-blog-backend-gin.git/internal/DAO"
 	"github.com/Jack-samu/the-blog-backend-gin.git/internal/models"
+	"github.com/Jack-samu/the-blog-backend-gin.git/internal/utils"
 )
 
 func ToPostListItem(post *models.Post) PostListItem {
@@ -20,8 +21,12 @@ func ToPostListItem(post *models.Post) PostListItem {
 		Likes:   uint(post.LikeCnt),
 		Cover:   post.Cover,
 		Author:  post.Author.Username,
-		// comment数量
-		Comments: len(post.Comments),
+		// comment数量，列表查询中由子查询得出
+		Comments: post.CommentCnt,
+	}
+
+	if post.Category != nil {
+		p.Category = post.Category.Name
 	}
 
 	for _, tag := range post.Tags {
@@ -53,6 +58,12 @@ func ToDraftsItem(draft *models.Draft) DraftItem {
 		Excerpt: draft.Excerpt,
 		Cover:   draft.Cover,
 		Author:  draft.Author.Username,
+		PostID:  draft.PostID,
+		Version: draft.Version,
+	}
+
+	if draft.PublishAt != nil {
+		d.PublishAt = draft.PublishAt.String()
 	}
 
 	if draft.Category != nil {
@@ -120,6 +131,8 @@ func ToDraftDetail(draft *models.Draft) DraftDetail {
 			Username: draft.Author.Username,
 			Avatar:   draft.Author.Avatar,
 		},
+		PostID:  draft.PostID,
+		Version: draft.Version,
 	}
 
 	if draft.Category != nil {
@@ -127,6 +140,10 @@ func ToDraftDetail(draft *models.Draft) DraftDetail {
 		d.Category = draft.Category.Name
 	}
 
+	for _, tag := range draft.Tags {
+		d.Tags = append(d.Tags, tag.Name)
+	}
+
 	return d
 }
 
@@ -148,6 +165,30 @@ func ToPostsBasic(posts []models.Post) []ArticleBasic {
 	return list
 }
 
+func ToSeriesItem(category *models.Category) SeriesItem {
+	return SeriesItem{
+		ID:          category.ID,
+		Name:        category.Name,
+		Description: category.Description,
+		Cover:       category.Cover,
+		Count:       len(category.Posts),
+		Posts:       ToPostsBasic(category.Posts),
+	}
+}
+
+func ToSeriesDetailResp(category *models.Category) *SeriesDetailResp {
+	resp := &SeriesDetailResp{
+		SeriesItem: ToSeriesItem(category),
+		CreatedAt:  category.CreatedAt.String(),
+		UpdatedAt:  category.UpdatedAt.String(),
+	}
+	if category.User != nil {
+		resp.Author = category.User.Username
+	}
+
+	return resp
+}
+
 func ToDraftList(drafts []models.Draft) []DraftItem {
 	list := make([]DraftItem, len(drafts))
 	for i := range drafts {
@@ -157,19 +198,20 @@ func ToDraftList(drafts []models.Draft) []DraftItem {
 	return list
 }
 
-func ToCommentsResp(comments []*models.Comment, r *dao.DAO) *CommentsResp {
+func ToCommentsResp(comments []*models.Comment, viewerID string, r *dao.DAO) *CommentsResp {
 	resp := &CommentsResp{
 		Total: len(comments),
 	}
 	resp.Comments = make([]CommentItem, len(comments))
 	for i := range comments {
-		resp.Comments[i] = ToCommentItem(comments[i], nil, r)
+		resp.Comments[i] = ToCommentItem(comments[i], nil, viewerID, r)
 	}
 
 	return resp
 }
 
-func ToCommentItem(comment *models.Comment, user *models.User, r *dao.DAO) CommentItem {
+// viewerID为当前访问者，为空时不查询点赞状态
+func ToCommentItem(comment *models.Comment, user *models.User, viewerID string, r *dao.DAO) CommentItem {
 	if user == nil {
 		user = &comment.User
 	}
@@ -189,9 +231,9 @@ func ToCommentItem(comment *models.Comment, user *models.User, r *dao.DAO) Comme
 		Liked: false,
 	}
 
-	if r != nil {
+	if r != nil && viewerID != "" {
 		// 直接查询
-		isLiked, err := r.IsLiked(comment.UserID, "comment", comment.ID)
+		isLiked, err := r.IsLiked(viewerID, models.LikeTargetComment, comment.ID)
 		if err != nil {
 			c.Liked = false
 			return c
@@ -202,7 +244,7 @@ func ToCommentItem(comment *models.Comment, user *models.User, r *dao.DAO) Comme
 	return c
 }
 
-func ToRepliesResp(replies []*models.Reply, r *dao.DAO) *RepliesResp {
+func ToRepliesResp(replies []*models.Reply, viewerID string, r *dao.DAO) *RepliesResp {
 
 	resp := &RepliesResp{
 		Total: len(replies),
@@ -210,13 +252,13 @@ func ToRepliesResp(replies []*models.Reply, r *dao.DAO) *RepliesResp {
 
 	resp.Replies = make([]ReplyItem, len(replies))
 	for i := range replies {
-		resp.Replies[i] = ToReplyItem(replies[i], nil, r)
+		resp.Replies[i] = ToReplyItem(replies[i], nil, viewerID, r)
 	}
 
 	return resp
 }
 
-func ToReplyItem(reply *models.Reply, user *models.User, r *dao.DAO) ReplyItem {
+func ToReplyItem(reply *models.Reply, user *models.User, viewerID string, r *dao.DAO) ReplyItem {
 	if user == nil {
 		user = &reply.User
 	}
@@ -235,9 +277,9 @@ func ToReplyItem(reply *models.Reply, user *models.User, r *dao.DAO) ReplyItem {
 		Liked: false,
 	}
 
-	if r != nil {
+	if r != nil && viewerID != "" {
 		// 直接查询
-		isLiked, err := r.IsLiked(reply.UserID, "reply", reply.ID)
+		isLiked, err := r.IsLiked(viewerID, models.LikeTargetReply, reply.ID)
 		if err != nil {
 			replyItem.Liked = false
 			return replyItem
@@ -247,3 +289,183 @@ func ToReplyItem(reply *models.Reply, user *models.User, r *dao.DAO) ReplyItem {
 
 	return replyItem
 }
+
+func ToTagItem(tag *dao.TagCount) TagItem {
+	return TagItem{
+		ID:    tag.ID,
+		Name:  tag.Name,
+		Posts: tag.PostCnt,
+	}
+}
+
+func ToTagsResp(tags []dao.TagCount) *TagsResp {
+	resp := &TagsResp{
+		Tags: make([]TagItem, len(tags)),
+	}
+	for i := range tags {
+		resp.Tags[i] = ToTagItem(&tags[i])
+	}
+
+	return resp
+}
+
+func ToRevisionItem(revision *models.PostRevision) RevisionItem {
+	return RevisionItem{
+		Version:   revision.Version,
+		Title:     revision.Title,
+		Author:    revision.Author.Username,
+		CreatedAt: revision.CreatedAt.String(),
+	}
+}
+
+func ToRevisionsResp(postID uint, revisions []models.PostRevision) *RevisionsResp {
+	resp := &RevisionsResp{
+		PostID:    postID,
+		Revisions: make([]RevisionItem, len(revisions)),
+	}
+	for i := range revisions {
+		resp.Revisions[i] = ToRevisionItem(&revisions[i])
+	}
+
+	return resp
+}
+
+func ToRevisionDetailResp(revision *models.PostRevision) *RevisionDetailResp {
+	return &RevisionDetailResp{
+		RevisionItem: ToRevisionItem(revision),
+		Excerpt:      revision.Excerpt,
+		Cover:        revision.Cover,
+		Content:      revision.Content,
+	}
+}
+
+func ToRevisionDiffResp(from, to *models.PostRevision) *RevisionDiffResp {
+	return &RevisionDiffResp{
+		From:    from.Version,
+		To:      to.Version,
+		Title:   utils.DiffLines(from.Title, to.Title),
+		Excerpt: utils.DiffLines(from.Excerpt, to.Excerpt),
+		Cover:   utils.DiffLines(from.Cover, to.Cover),
+		Content: utils.DiffLines(from.Content, to.Content),
+	}
+}
+
+func ToAdminUserItem(user *models.User) AdminUserItem {
+	item := AdminUserItem{
+		ID:           user.ID,
+		Username:     user.Username,
+		Email:        user.Email,
+		Role:         user.Role,
+		CreatedAt:    user.CreatedAt.String(),
+		LastActivity: user.LastActivity.String(),
+		FailedLogin:  user.FailedLogin,
+	}
+	if user.SuspendedAt != nil {
+		item.SuspendedAt = user.SuspendedAt.String()
+	}
+	return item
+}
+
+func ToAdminUserList(users []models.User, total, page int64) *AdminUserListResp {
+	list := make([]AdminUserItem, 0, len(users))
+	for i := range users {
+		list = append(list, ToAdminUserItem(&users[i]))
+	}
+
+	return &AdminUserListResp{
+		Users:       list,
+		Cnt:         uint(total),
+		CurrentPage: uint(page),
+	}
+}
+
+func ToSessionsResp(sessions []models.Session, currentID string) *SessionsResp {
+	items := make([]SessionItem, 0, len(sessions))
+	for _, session := range sessions {
+		items = append(items, SessionItem{
+			ID:         session.ID,
+			UserAgent:  session.UserAgent,
+			IP:         session.IP,
+			CreatedAt:  session.CreatedAt.String(),
+			LastSeenAt: session.LastSeenAt.String(),
+			Current:    session.ID == currentID,
+		})
+	}
+
+	return &SessionsResp{Sessions: items}
+}
+
+// followers为true时取关注者一方，否则取被关注的一方
+func ToFollowList(follows []models.Follow, followers bool) []FollowUserItem {
+	list := make([]FollowUserItem, 0, len(follows))
+	for i := range follows {
+		user := follows[i].Followee
+		if followers {
+			user = follows[i].Follower
+		}
+		if user == nil {
+			continue
+		}
+
+		list = append(list, FollowUserItem{
+			ID:          user.ID,
+			Username:    user.Username,
+			DisplayName: user.DisplayName,
+			Avatar:      user.Avatar,
+			FollowedAt:  follows[i].CreatedAt.String(),
+		})
+	}
+
+	return list
+}
+
+// shareBase为分享链接的前缀，列表未公开时不返回链接
+func ToReadingListItem(list *models.ReadingList, shareBase string) ReadingListItem {
+	item := ReadingListItem{
+		ID:          list.ID,
+		Name:        list.Name,
+		Description: list.Description,
+		Public:      list.Public,
+		Count:       len(list.Items),
+		CreatedAt:   list.CreatedAt.String(),
+		UpdatedAt:   list.UpdatedAt.String(),
+	}
+	if list.Public && list.ShareToken != nil {
+		item.ShareURL = shareBase + *list.ShareToken
+	}
+
+	return item
+}
+
+func ToReadingListsResp(lists []models.ReadingList, shareBase string) *ReadingListsResp {
+	items := make([]ReadingListItem, len(lists))
+	for i := range lists {
+		items[i] = ToReadingListItem(&lists[i], shareBase)
+	}
+
+	return &ReadingListsResp{Lists: items}
+}
+
+// 列表中已被删除的文章直接跳过
+func ToReadingListDetail(list *models.ReadingList, shareBase string) *ReadingListDetailResp {
+	resp := &ReadingListDetailResp{
+		ReadingListItem: ToReadingListItem(list, shareBase),
+		Posts:           make([]PostListItem, 0, len(list.Items)),
+	}
+	if list.User != nil {
+		resp.Owner = AuthorProfile{
+			ID:       list.User.ID,
+			Username: list.User.Username,
+			Avatar:   list.User.Avatar,
+		}
+	}
+
+	for _, item := range list.Items {
+		if item.Post != nil {
+			resp.Posts = append(resp.Posts, ToPostListItem(item.Post))
+		}
+	}
+	resp.Count = len(resp.Posts)
+
+	return resp
+}
@@ -0,0 +1,86 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/Jack-samu/the-blog-backend-gin.git/internal/dtos"
+	"github.com/gin-gonic/gin"
+)
+
+func (h *Handler) SetUserRole(c *gin.Context) {
+	req := new(dtos.RoleReq)
+	if err := c.ShouldBindJSON(req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"err": "参数缺失或无效"})
+		return
+	}
+
+	errs := h.s.SetUserRole(c.Param("id"), req.Role)
+	writeResp(c, gin.H{"msg": "角色已更新"}, errs, http.StatusOK)
+}
+
+func (h *Handler) AdminDeletePost(c *gin.Context) {
+	id, ok := parseIDParam(c, "id")
+	if !ok {
+		return
+	}
+
+	errs := h.s.AdminDeletePost(id)
+	writeResp(c, gin.H{"msg": "文章已删除"}, errs, http.StatusOK)
+}
+
+func (h *Handler) RenameTag(c *gin.Context) {
+	id, ok := parseIDParam(c, "id")
+	if !ok {
+		return
+	}
+
+	req := new(dtos.TagRenameReq)
+	if err := c.ShouldBindJSON(req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"err": "参数缺失或无效"})
+		return
+	}
+
+	tagResp, errs := h.s.RenameTag(id, req)
+	writeResp(c, tagResp, errs, http.StatusOK)
+}
+
+func (h *Handler) DeleteTag(c *gin.Context) {
+	id, ok := parseIDParam(c, "id")
+	if !ok {
+		return
+	}
+
+	errs := h.s.DeleteTag(id)
+	writeResp(c, gin.H{"msg": "标签已删除"}, errs, http.StatusOK)
+}
+
+func (h *Handler) GetUsers(c *gin.Context) {
+	var req dtos.AdminUserListReq
+	if err := c.ShouldBindQuery(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"err": "参数缺失或无效"})
+		return
+	}
+
+	usersResp, errs := h.s.GetUsers(&req)
+	writeResp(c, usersResp, errs, http.StatusOK)
+}
+
+func (h *Handler) SuspendUser(c *gin.Context) {
+	errs := h.s.SuspendUser(c.Param("id"), c.GetString("user_id"), true)
+	writeResp(c, gin.H{"msg": "账号已封禁"}, errs, http.StatusOK)
+}
+
+func (h *Handler) UnsuspendUser(c *gin.Context) {
+	errs := h.s.SuspendUser(c.Param("id"), c.GetString("user_id"), false)
+	writeResp(c, gin.H{"msg": "账号已解封"}, errs, http.StatusOK)
+}
+
+func (h *Handler) ResetFailedLogin(c *gin.Context) {
+	errs := h.s.ResetFailedLogin(c.Param("id"))
+	writeResp(c, gin.H{"msg": "登录锁定已重置"}, errs, http.StatusOK)
+}
+
+func (h *Handler) DeleteUser(c *gin.Context) {
+	errs := h.s.DeleteUser(c.Param("id"), c.GetString("user_id"))
+	writeResp(c, gin.H{"msg": "用户已删除"}, errs, http.StatusOK)
+}
@@ -4,25 +4,40 @@ import (
 	"log"
 	"net/http"
 	"strconv"
+	"time"
 
 	"github.com/Jack-samu/the-blog-backend-gin.git/internal/dtos"
 	"github.com/gin-gonic/gin"
 )
 
 func (h *Handler) GetArticles(c *gin.Context) {
-	pageParam := c.Query("page")
-	if pageParam == "" {
-		pageParam = "1"
+	var req dtos.ArticleListReq
+	if err := c.ShouldBindQuery(&req); err != nil {
+		log.Printf("%s\n", err.Error())
+		c.JSON(http.StatusBadRequest, gin.H{"err": "参数缺失或无效"})
+		return
 	}
-	perPageParam := c.Query("per_page")
-	if perPageParam == "" {
-		perPageParam = "10"
+
+	postsResp, err := h.s.GetPosts(&req, c.GetString("user_id"))
+	if err != nil {
+		if err.Err != nil {
+			c.JSON(err.Code, gin.H{"err": err.Err.Error()})
+		} else {
+			c.JSON(err.Code, gin.H{"err": err.Msg})
+		}
+	} else {
+		c.JSON(http.StatusOK, postsResp)
 	}
+}
 
-	page, _ := strconv.ParseInt(pageParam, 10, 64)
-	perPage, _ := strconv.ParseInt(perPageParam, 10, 64)
+func (h *Handler) SearchArticles(c *gin.Context) {
+	var req dtos.PageReq
+	if err := c.ShouldBindQuery(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"err": "参数缺失或无效"})
+		return
+	}
 
-	postsResp, err := h.s.GetPosts(page, perPage)
+	searchResp, err := h.s.SearchPosts(c.Query("q"), &req, c.GetString("user_id"))
 	if err != nil {
 		if err.Err != nil {
 			c.JSON(err.Code, gin.H{"err": err.Err.Error()})
@@ -30,7 +45,7 @@ func (h *Handler) GetArticles(c *gin.Context) {
 			c.JSON(err.Code, gin.H{"err": err.Msg})
 		}
 	} else {
-		c.JSON(http.StatusOK, postsResp)
+		c.JSON(http.StatusOK, searchResp)
 	}
 }
 
@@ -48,7 +63,7 @@ func (h *Handler) GetArticle(c *gin.Context) {
 		return
 	}
 
-	postResp, errs := h.s.GetPost(uint(id))
+	postResp, errs := h.s.GetPost(uint(id), c.GetString("user_id"))
 	if errs != nil {
 		if errs.Err != nil {
 			c.JSON(errs.Code, gin.H{"err": errs.Err.Error()})
@@ -56,6 +71,7 @@ func (h *Handler) GetArticle(c *gin.Context) {
 			c.JSON(errs.Code, gin.H{"err": errs.Msg})
 		}
 	} else {
+		h.s.RecordView(uint(id), c.GetString("user_id"), c.ClientIP(), c.Request.UserAgent())
 		c.JSON(http.StatusOK, postResp)
 	}
 }
@@ -74,7 +90,7 @@ func (h *Handler) GetDraftEditable(c *gin.Context) {
 		return
 	}
 
-	draftResp, errs := h.s.GetDraft(uint(id))
+	draftResp, errs := h.s.GetDraft(uint(id), c.GetString("user_id"))
 	if errs != nil {
 		if errs.Err != nil {
 			c.JSON(errs.Code, gin.H{"err": errs.Err.Error()})
@@ -87,13 +103,10 @@ func (h *Handler) GetDraftEditable(c *gin.Context) {
 }
 
 func (h *Handler) GetPostsOfUser(c *gin.Context) {
-	pageParam := c.Query("page")
-	if pageParam == "" {
-		pageParam = "1"
-	}
-	perPageParam := c.Query("per_page")
-	if perPageParam == "" {
-		perPageParam = "10"
+	var req dtos.PageReq
+	if err := c.ShouldBindQuery(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"err": "参数缺失或无效"})
+		return
 	}
 
 	user_id := c.GetString("user_id")
@@ -102,10 +115,7 @@ func (h *Handler) GetPostsOfUser(c *gin.Context) {
 		return
 	}
 
-	page, _ := strconv.ParseInt(pageParam, 10, 64)
-	perPage, _ := strconv.ParseInt(perPageParam, 10, 64)
-
-	postsResp, err := h.s.GetPostsOfUser(page, perPage, user_id)
+	postsResp, err := h.s.GetPostsOfUser(&req, user_id)
 	if err != nil {
 		if err.Err != nil {
 			c.JSON(err.Code, gin.H{"err": err.Err.Error()})
@@ -118,13 +128,10 @@ func (h *Handler) GetPostsOfUser(c *gin.Context) {
 }
 
 func (h *Handler) GetDraftOfUser(c *gin.Context) {
-	pageParam := c.Query("page")
-	if pageParam == "" {
-		pageParam = "1"
-	}
-	perPageParam := c.Query("per_page")
-	if perPageParam == "" {
-		perPageParam = "10"
+	var req dtos.PageReq
+	if err := c.ShouldBindQuery(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"err": "参数缺失或无效"})
+		return
 	}
 
 	user_id := c.GetString("user_id")
@@ -133,10 +140,7 @@ func (h *Handler) GetDraftOfUser(c *gin.Context) {
 		return
 	}
 
-	page, _ := strconv.ParseInt(pageParam, 10, 64)
-	perPage, _ := strconv.ParseInt(perPageParam, 10, 64)
-
-	draftsResp, err := h.s.GetDraftsOfUser(page, perPage, user_id)
+	draftsResp, err := h.s.GetDraftsOfUser(&req, user_id)
 	if err != nil {
 		if err.Err != nil {
 			c.JSON(err.Code, gin.H{"err": err.Err.Error()})
@@ -183,10 +187,19 @@ func (h *Handler) PublishArticle(c *gin.Context) {
 		return
 	}
 
+	// 定时发布
+	if req.PublishAt != nil && req.PublishAt.After(time.Now()) {
+		draftResp, errs := h.s.ScheduleArticle(req, user_id)
+		writeResp(c, draftResp, errs, http.StatusAccepted)
+		return
+	}
+
 	post_id, err := h.s.PublishArticle(req, user_id)
 	if err != nil {
 		if err.Err != nil {
 			c.JSON(err.Code, gin.H{"err": err.Err.Error()})
+		} else {
+			c.JSON(err.Code, gin.H{"err": err.Msg})
 		}
 	} else {
 		c.JSON(http.StatusCreated, gin.H{"msg": "发表成功", "id": post_id})
@@ -213,6 +226,8 @@ func (h *Handler) SaveDraft(c *gin.Context) {
 	if err != nil {
 		if err.Err != nil {
 			c.JSON(err.Code, gin.H{"err": err.Err.Error()})
+		} else {
+			c.JSON(err.Code, gin.H{"err": err.Msg})
 		}
 	} else {
 		c.JSON(http.StatusCreated, gin.H{"msg": "草稿保存成功", "id": draft_id})
@@ -229,14 +244,12 @@ func (h *Handler) DeletePost(c *gin.Context) {
 	post_id, err := strconv.ParseUint(idParam, 10, 32)
 	if err != nil {
 		log.Printf("转换%s格式出错：%s\n", idParam, err.Error())
-		c.JSON(http.StatusInternalServerError, gin.H{"err": "服务器错误"})
+		c.JSON(http.StatusBadRequest, gin.H{"err": "无效参数"})
+		return
 	}
 
-	if err := h.s.DeletePost(uint(post_id)); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"err": "服务器错误"})
-	} else {
-		c.JSON(http.StatusCreated, gin.H{"msg": "文章已删除"})
-	}
+	errs := h.s.DeletePost(uint(post_id), c.GetString("user_id"))
+	writeResp(c, gin.H{"msg": "文章已删除"}, errs, http.StatusCreated)
 }
 
 func (h *Handler) DeleteDraft(c *gin.Context) {
@@ -249,12 +262,10 @@ func (h *Handler) DeleteDraft(c *gin.Context) {
 	draft_id, err := strconv.ParseUint(idParam, 10, 32)
 	if err != nil {
 		log.Printf("转换%s格式出错：%s\n", idParam, err.Error())
-		c.JSON(http.StatusInternalServerError, gin.H{"err": "服务器错误"})
+		c.JSON(http.StatusBadRequest, gin.H{"err": "无效参数"})
+		return
 	}
 
-	if err := h.s.DeletePost(uint(draft_id)); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"err": "服务器错误"})
-	} else {
-		c.JSON(http.StatusCreated, gin.H{"msg": "草稿已删除"})
-	}
+	errs := h.s.DeleteDraft(uint(draft_id), c.GetString("user_id"))
+	writeResp(c, gin.H{"msg": "草稿已删除"}, errs, http.StatusCreated)
 }
@@ -0,0 +1,141 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/Jack-samu/the-blog-backend-gin.git/internal/dtos"
+	"github.com/gin-gonic/gin"
+)
+
+func (h *Handler) BookmarkPost(c *gin.Context) {
+	h.toggleBookmark(c, true)
+}
+
+func (h *Handler) UnbookmarkPost(c *gin.Context) {
+	h.toggleBookmark(c, false)
+}
+
+func (h *Handler) toggleBookmark(c *gin.Context, bookmark bool) {
+	id, ok := parseIDParam(c, "id")
+	if !ok {
+		return
+	}
+
+	bookmarkResp, errs := h.s.Bookmark(c.GetString("user_id"), id, bookmark)
+	writeResp(c, bookmarkResp, errs, http.StatusOK)
+}
+
+func (h *Handler) GetBookmarks(c *gin.Context) {
+	var req dtos.PageReq
+	if err := c.ShouldBindQuery(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"err": "无效参数"})
+		return
+	}
+
+	postsResp, errs := h.s.GetBookmarks(c.GetString("user_id"), &req)
+	writeResp(c, postsResp, errs, http.StatusOK)
+}
+
+func (h *Handler) GetReadingLists(c *gin.Context) {
+	listsResp, errs := h.s.GetReadingLists(c.GetString("user_id"))
+	writeResp(c, listsResp, errs, http.StatusOK)
+}
+
+func (h *Handler) GetReadingList(c *gin.Context) {
+	id, ok := parseIDParam(c, "id")
+	if !ok {
+		return
+	}
+
+	listResp, errs := h.s.GetReadingList(id, c.GetString("user_id"))
+	writeResp(c, listResp, errs, http.StatusOK)
+}
+
+// 分享链接，登录用户会带上自己的收藏状态
+func (h *Handler) GetSharedReadingList(c *gin.Context) {
+	listResp, errs := h.s.GetSharedReadingList(c.Param("token"), c.GetString("user_id"))
+	writeResp(c, listResp, errs, http.StatusOK)
+}
+
+func (h *Handler) CreateReadingList(c *gin.Context) {
+	req := new(dtos.ReadingListReq)
+	if err := c.ShouldBindJSON(req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"err": "参数缺失或无效"})
+		return
+	}
+
+	listResp, errs := h.s.CreateReadingList(req, c.GetString("user_id"))
+	writeResp(c, listResp, errs, http.StatusCreated)
+}
+
+func (h *Handler) UpdateReadingList(c *gin.Context) {
+	id, ok := parseIDParam(c, "id")
+	if !ok {
+		return
+	}
+
+	req := new(dtos.ReadingListUpdateReq)
+	if err := c.ShouldBindJSON(req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"err": "参数缺失或无效"})
+		return
+	}
+
+	listResp, errs := h.s.UpdateReadingList(id, req, c.GetString("user_id"))
+	writeResp(c, listResp, errs, http.StatusOK)
+}
+
+func (h *Handler) DeleteReadingList(c *gin.Context) {
+	id, ok := parseIDParam(c, "id")
+	if !ok {
+		return
+	}
+
+	errs := h.s.DeleteReadingList(id, c.GetString("user_id"))
+	writeResp(c, gin.H{"msg": "阅读列表已删除"}, errs, http.StatusOK)
+}
+
+func (h *Handler) AddReadingListItem(c *gin.Context) {
+	id, ok := parseIDParam(c, "id")
+	if !ok {
+		return
+	}
+
+	req := new(dtos.ReadingListItemReq)
+	if err := c.ShouldBindJSON(req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"err": "参数缺失或无效"})
+		return
+	}
+
+	listResp, errs := h.s.AddReadingListItem(id, req, c.GetString("user_id"))
+	writeResp(c, listResp, errs, http.StatusOK)
+}
+
+func (h *Handler) RemoveReadingListItem(c *gin.Context) {
+	id, ok := parseIDParam(c, "id")
+	if !ok {
+		return
+	}
+	postID, ok := parseIDParam(c, "postId")
+	if !ok {
+		return
+	}
+
+	listResp, errs := h.s.RemoveReadingListItem(id, postID, c.GetString("user_id"))
+	writeResp(c, listResp, errs, http.StatusOK)
+}
+
+func (h *Handler) ReorderReadingList(c *gin.Context) {
+	id, ok := parseIDParam(c, "id")
+	if !ok {
+		return
+	}
+
+	req := new(dtos.ReadingListOrderReq)
+	if err := c.ShouldBindJSON(req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"err": "参数缺失或无效"})
+		return
+	}
+
+	listResp, errs := h.s.ReorderReadingList(id, req, c.GetString("user_id"))
+	writeResp(c, listResp, errs, http.StatusOK)
+}
@@ -21,7 +21,13 @@ func (h *Handler) GetComments(c *gin.Context) {
 		return
 	}
 
-	resp, errs := h.s.GetComments(id)
+	var req dtos.PageReq
+	if err := c.ShouldBindQuery(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"err": "参数错误"})
+		return
+	}
+
+	resp, errs := h.s.GetComments(id, &req, c.GetString("user_id"))
 	if errs != nil {
 		switch errs.Code {
 		case http.StatusBadRequest:
@@ -49,7 +55,13 @@ func (h *Handler) GetReplies(c *gin.Context) {
 		return
 	}
 
-	resp, errs := h.s.GetReplies(id)
+	var req dtos.PageReq
+	if err := c.ShouldBindQuery(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"err": "参数错误"})
+		return
+	}
+
+	resp, errs := h.s.GetReplies(id, &req, c.GetString("user_id"))
 	if errs != nil {
 		switch errs.Code {
 		case http.StatusBadRequest:
@@ -159,7 +171,7 @@ func (h *Handler) ModifyComment(c *gin.Context) {
 		return
 	}
 
-	commentResp, errs := h.s.ModifyComment(&req, userID)
+	commentResp, errs := h.s.ModifyComment(&req, userID, c.GetString("role"))
 	if errs != nil {
 		switch errs.Code {
 		case http.StatusNotFound:
@@ -195,7 +207,7 @@ func (h *Handler) ModifyReply(c *gin.Context) {
 		return
 	}
 
-	commentResp, errs := h.s.ModifyReply(&req, userID)
+	commentResp, errs := h.s.ModifyReply(&req, userID, c.GetString("role"))
 	if errs != nil {
 		switch errs.Code {
 		case http.StatusNotFound:
@@ -227,7 +239,7 @@ func (h *Handler) DeleteComment(c *gin.Context) {
 		return
 	}
 
-	errs := h.s.DeleteComment(id, userID)
+	errs := h.s.DeleteComment(id, userID, c.GetString("role"))
 	if errs != nil {
 		switch errs.Code {
 		case http.StatusNotFound:
@@ -259,7 +271,7 @@ func (h *Handler) DeleteReply(c *gin.Context) {
 		return
 	}
 
-	errs := h.s.DeleteReply(id, userID)
+	errs := h.s.DeleteReply(id, userID, c.GetString("role"))
 	if errs != nil {
 		switch errs.Code {
 		case http.StatusNotFound:
@@ -0,0 +1,55 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/Jack-samu/the-blog-backend-gin.git/internal/dtos"
+	"github.com/gin-gonic/gin"
+)
+
+func (h *Handler) AutosaveDraft(c *gin.Context) {
+	id, ok := parseIDParam(c, "id")
+	if !ok {
+		return
+	}
+
+	req := new(dtos.AutosaveReq)
+	if err := c.ShouldBindJSON(req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"err": "参数缺失或无效"})
+		return
+	}
+
+	autosaveResp, errs := h.s.AutosaveDraft(id, req, c.GetString("user_id"))
+	writeResp(c, autosaveResp, errs, http.StatusOK)
+}
+
+func (h *Handler) GetScheduled(c *gin.Context) {
+	scheduledResp, errs := h.s.GetScheduled(c.GetString("user_id"))
+	writeResp(c, scheduledResp, errs, http.StatusOK)
+}
+
+func (h *Handler) Reschedule(c *gin.Context) {
+	id, ok := parseIDParam(c, "id")
+	if !ok {
+		return
+	}
+
+	req := new(dtos.ScheduleReq)
+	if err := c.ShouldBindJSON(req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"err": "参数缺失或无效"})
+		return
+	}
+
+	draftResp, errs := h.s.Reschedule(id, req.PublishAt, c.GetString("user_id"))
+	writeResp(c, draftResp, errs, http.StatusOK)
+}
+
+func (h *Handler) CancelSchedule(c *gin.Context) {
+	id, ok := parseIDParam(c, "id")
+	if !ok {
+		return
+	}
+
+	errs := h.s.CancelSchedule(id, c.GetString("user_id"))
+	writeResp(c, gin.H{"msg": "已取消定时发布"}, errs, http.StatusOK)
+}
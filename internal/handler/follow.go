@@ -0,0 +1,49 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/Jack-samu/the-blog-backend-gin.git/internal/dtos"
+	"github.com/gin-gonic/gin"
+)
+
+func (h *Handler) Follow(c *gin.Context) {
+	followResp, errs := h.s.Follow(c.GetString("user_id"), c.Param("username"), true)
+	writeResp(c, followResp, errs, http.StatusOK)
+}
+
+func (h *Handler) Unfollow(c *gin.Context) {
+	followResp, errs := h.s.Follow(c.GetString("user_id"), c.Param("username"), false)
+	writeResp(c, followResp, errs, http.StatusOK)
+}
+
+func (h *Handler) getFollows(c *gin.Context, followers bool) {
+	var req dtos.PageReq
+	if err := c.ShouldBindQuery(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"err": "无效参数"})
+		return
+	}
+
+	listResp, errs := h.s.GetFollows(c.Param("username"), &req, followers)
+	writeResp(c, listResp, errs, http.StatusOK)
+}
+
+func (h *Handler) GetFollowers(c *gin.Context) {
+	h.getFollows(c, true)
+}
+
+func (h *Handler) GetFollowing(c *gin.Context) {
+	h.getFollows(c, false)
+}
+
+// 关注的作者的文章动态
+func (h *Handler) GetFeed(c *gin.Context) {
+	var req dtos.PageReq
+	if err := c.ShouldBindQuery(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"err": "无效参数"})
+		return
+	}
+
+	feedResp, errs := h.s.GetFeed(c.GetString("user_id"), &req)
+	writeResp(c, feedResp, errs, http.StatusOK)
+}
@@ -1,7 +1,12 @@
 package handler
 
 import (
+	"net/http"
+	"strconv"
+
+	"github.com/Jack-samu/the-blog-backend-gin.git/internal/errs"
 	"github.com/Jack-samu/the-blog-backend-gin.git/internal/service"
+	"github.com/gin-gonic/gin"
 )
 
 type Handler struct {
@@ -11,3 +16,26 @@ type Handler struct {
 func NewHandler(s *service.Service) *Handler {
 	return &Handler{s: s}
 }
+
+// 统一写出service的结果，出错时按错误码返回错误信息
+func writeResp(c *gin.Context, resp interface{}, errResp *errs.ErrorResp, status int) {
+	if errResp != nil {
+		if errResp.Err != nil {
+			c.JSON(errResp.Code, gin.H{"err": errResp.Err.Error()})
+		} else {
+			c.JSON(errResp.Code, gin.H{"err": errResp.Msg})
+		}
+	} else {
+		c.JSON(status, resp)
+	}
+}
+
+// 解析路径中的数字id，失败时直接返回400
+func parseIDParam(c *gin.Context, name string) (uint, bool) {
+	id, err := strconv.ParseUint(c.Param(name), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"err": "无效参数"})
+		return 0, false
+	}
+	return uint(id), true
+}
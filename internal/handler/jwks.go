@@ -0,0 +1,14 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/Jack-samu/the-blog-backend-gin.git/internal/utils"
+	"github.com/gin-gonic/gin"
+)
+
+// 公开token校验用的公钥，供其他服务验证本服务签发的token
+func (h *Handler) JWKS(c *gin.Context) {
+	c.Header("Cache-Control", "public, max-age=300")
+	c.JSON(http.StatusOK, utils.JWKS())
+}
@@ -0,0 +1,59 @@
+package handler
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/Jack-samu/the-blog-backend-gin.git/internal/models"
+	"github.com/gin-gonic/gin"
+)
+
+func (h *Handler) LikePost(c *gin.Context) {
+	h.toggleLike(c, models.LikeTargetPost, true)
+}
+
+func (h *Handler) UnlikePost(c *gin.Context) {
+	h.toggleLike(c, models.LikeTargetPost, false)
+}
+
+func (h *Handler) LikeComment(c *gin.Context) {
+	h.toggleLike(c, models.LikeTargetComment, true)
+}
+
+func (h *Handler) UnlikeComment(c *gin.Context) {
+	h.toggleLike(c, models.LikeTargetComment, false)
+}
+
+func (h *Handler) LikeReply(c *gin.Context) {
+	h.toggleLike(c, models.LikeTargetReply, true)
+}
+
+func (h *Handler) UnlikeReply(c *gin.Context) {
+	h.toggleLike(c, models.LikeTargetReply, false)
+}
+
+// 各类点赞接口的共用部分
+func (h *Handler) toggleLike(c *gin.Context, targetType string, like bool) {
+	userID := c.GetString("user_id")
+	if userID == "" {
+		c.JSON(http.StatusUnauthorized, gin.H{"err": "用户id读取失败"})
+		return
+	}
+
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"err": "无效参数"})
+		return
+	}
+
+	likeResp, errs := h.s.ToggleLike(userID, targetType, uint(id), like)
+	if errs != nil {
+		if errs.Err != nil {
+			c.JSON(errs.Code, gin.H{"err": errs.Err.Error()})
+		} else {
+			c.JSON(errs.Code, gin.H{"err": errs.Msg})
+		}
+	} else {
+		c.JSON(http.StatusOK, likeResp)
+	}
+}
@@ -0,0 +1,35 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// 跳转到第三方的授权页面
+func (h *Handler) OIDCLogin(c *gin.Context) {
+	authURL, errs := h.s.OIDCStart(c.Param("provider"))
+	if errs != nil {
+		writeResp(c, nil, errs, http.StatusOK)
+		return
+	}
+
+	c.Redirect(http.StatusFound, authURL)
+}
+
+// 第三方授权后的回调，返回和密码登录相同的LoginResp
+func (h *Handler) OIDCCallback(c *gin.Context) {
+	if errMsg := c.Query("error"); errMsg != "" {
+		c.JSON(http.StatusBadRequest, gin.H{"err": "第三方授权失败：" + errMsg})
+		return
+	}
+
+	code, state := c.Query("code"), c.Query("state")
+	if code == "" || state == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"err": "无效参数"})
+		return
+	}
+
+	loginResp, errs := h.s.OIDCCallback(c.Param("provider"), code, state, c.ClientIP(), c.Request.UserAgent())
+	writeResp(c, loginResp, errs, http.StatusOK)
+}
@@ -0,0 +1,61 @@
+package handler
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// 已发布文章重新进入编辑，返回关联的编辑稿
+func (h *Handler) EditPost(c *gin.Context) {
+	id, ok := parseIDParam(c, "id")
+	if !ok {
+		return
+	}
+
+	draftResp, errs := h.s.EditPost(id, c.GetString("user_id"))
+	writeResp(c, draftResp, errs, http.StatusOK)
+}
+
+func (h *Handler) GetRevisions(c *gin.Context) {
+	id, ok := parseIDParam(c, "id")
+	if !ok {
+		return
+	}
+
+	revisionsResp, errs := h.s.GetRevisions(id, c.GetString("user_id"))
+	writeResp(c, revisionsResp, errs, http.StatusOK)
+}
+
+func (h *Handler) GetRevision(c *gin.Context) {
+	id, ok := parseIDParam(c, "id")
+	if !ok {
+		return
+	}
+	version, err := strconv.Atoi(c.Param("version"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"err": "无效参数"})
+		return
+	}
+
+	revisionResp, errs := h.s.GetRevision(id, version, c.GetString("user_id"))
+	writeResp(c, revisionResp, errs, http.StatusOK)
+}
+
+// ?from=1&to=2
+func (h *Handler) DiffRevisions(c *gin.Context) {
+	id, ok := parseIDParam(c, "id")
+	if !ok {
+		return
+	}
+	from, err1 := strconv.Atoi(c.Query("from"))
+	to, err2 := strconv.Atoi(c.Query("to"))
+	if err1 != nil || err2 != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"err": "参数缺失或无效"})
+		return
+	}
+
+	diffResp, errs := h.s.DiffRevisions(id, from, to, c.GetString("user_id"))
+	writeResp(c, diffResp, errs, http.StatusOK)
+}
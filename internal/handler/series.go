@@ -0,0 +1,72 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/Jack-samu/the-blog-backend-gin.git/internal/dtos"
+	"github.com/gin-gonic/gin"
+)
+
+func (h *Handler) GetSeriesDetail(c *gin.Context) {
+	id, ok := parseIDParam(c, "id")
+	if !ok {
+		return
+	}
+
+	seriesResp, errs := h.s.GetSeriesDetail(id)
+	writeResp(c, seriesResp, errs, http.StatusOK)
+}
+
+func (h *Handler) CreateSeries(c *gin.Context) {
+	req := new(dtos.SeriesReq)
+	if err := c.ShouldBindJSON(req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"err": "参数缺失或无效"})
+		return
+	}
+
+	seriesResp, errs := h.s.CreateSeries(req, c.GetString("user_id"))
+	writeResp(c, seriesResp, errs, http.StatusCreated)
+}
+
+func (h *Handler) UpdateSeries(c *gin.Context) {
+	id, ok := parseIDParam(c, "id")
+	if !ok {
+		return
+	}
+
+	req := new(dtos.SeriesUpdateReq)
+	if err := c.ShouldBindJSON(req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"err": "参数缺失或无效"})
+		return
+	}
+
+	seriesResp, errs := h.s.UpdateSeries(id, req, c.GetString("user_id"))
+	writeResp(c, seriesResp, errs, http.StatusOK)
+}
+
+// ?posts=detach（默认，文章移出系列）或 ?posts=delete（文章一并删除）
+func (h *Handler) DeleteSeries(c *gin.Context) {
+	id, ok := parseIDParam(c, "id")
+	if !ok {
+		return
+	}
+
+	errs := h.s.DeleteSeries(id, c.Query("posts"), c.GetString("user_id"))
+	writeResp(c, gin.H{"msg": "系列已删除"}, errs, http.StatusOK)
+}
+
+func (h *Handler) ReorderSeries(c *gin.Context) {
+	id, ok := parseIDParam(c, "id")
+	if !ok {
+		return
+	}
+
+	req := new(dtos.SeriesOrderReq)
+	if err := c.ShouldBindJSON(req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"err": "参数缺失或无效"})
+		return
+	}
+
+	seriesResp, errs := h.s.ReorderSeries(id, req, c.GetString("user_id"))
+	writeResp(c, seriesResp, errs, http.StatusOK)
+}
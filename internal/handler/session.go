@@ -0,0 +1,22 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+func (h *Handler) GetSessions(c *gin.Context) {
+	sessionsResp, errs := h.s.GetSessions(c.GetString("user_id"), c.GetString("session_id"))
+	writeResp(c, sessionsResp, errs, http.StatusOK)
+}
+
+func (h *Handler) RevokeSession(c *gin.Context) {
+	errs := h.s.RevokeSession(c.GetString("user_id"), c.Param("id"))
+	writeResp(c, gin.H{"msg": "会话已退出"}, errs, http.StatusOK)
+}
+
+func (h *Handler) RevokeOtherSessions(c *gin.Context) {
+	n, errs := h.s.RevokeOtherSessions(c.GetString("user_id"), c.GetString("session_id"))
+	writeResp(c, gin.H{"msg": "其他会话已退出", "revoked": n}, errs, http.StatusOK)
+}
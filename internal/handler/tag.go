@@ -0,0 +1,55 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/Jack-samu/the-blog-backend-gin.git/internal/dtos"
+	"github.com/gin-gonic/gin"
+)
+
+func (h *Handler) GetTags(c *gin.Context) {
+	var req dtos.TagListReq
+	if err := c.ShouldBindQuery(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"err": "参数缺失或无效"})
+		return
+	}
+
+	tagsResp, errs := h.s.GetTags(&req)
+	writeResp(c, tagsResp, errs, http.StatusOK)
+}
+
+func (h *Handler) SuggestTags(c *gin.Context) {
+	var req dtos.TagSuggestReq
+	if err := c.ShouldBindQuery(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"err": "参数缺失或无效"})
+		return
+	}
+
+	tagsResp, errs := h.s.SuggestTags(&req)
+	writeResp(c, tagsResp, errs, http.StatusOK)
+}
+
+func (h *Handler) GetTagPosts(c *gin.Context) {
+	name := c.Param("name")
+	if name == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"err": "参数不明确"})
+		return
+	}
+
+	var req dtos.PageReq
+	if err := c.ShouldBindQuery(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"err": "参数缺失或无效"})
+		return
+	}
+
+	tagResp, err := h.s.GetTagPosts(name, &req, c.GetString("user_id"))
+	if err != nil {
+		if err.Err != nil {
+			c.JSON(err.Code, gin.H{"err": err.Err.Error()})
+		} else {
+			c.JSON(err.Code, gin.H{"err": err.Msg})
+		}
+	} else {
+		c.JSON(http.StatusOK, tagResp)
+	}
+}
@@ -0,0 +1,47 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/Jack-samu/the-blog-backend-gin.git/internal/dtos"
+	"github.com/gin-gonic/gin"
+)
+
+func (h *Handler) SetupTwoFactor(c *gin.Context) {
+	resp, errs := h.s.SetupTwoFactor(c.GetString("user_id"))
+	writeResp(c, resp, errs, http.StatusOK)
+}
+
+func (h *Handler) EnableTwoFactor(c *gin.Context) {
+	var req dtos.TwoFactorCodeReq
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"err": "无效参数"})
+		return
+	}
+
+	resp, errs := h.s.EnableTwoFactor(c.GetString("user_id"), req.Code)
+	writeResp(c, resp, errs, http.StatusOK)
+}
+
+func (h *Handler) DisableTwoFactor(c *gin.Context) {
+	var req dtos.TwoFactorCodeReq
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"err": "无效参数"})
+		return
+	}
+
+	errs := h.s.DisableTwoFactor(c.GetString("user_id"), req.Code, c.ClientIP())
+	writeResp(c, gin.H{"msg": "两步验证已关闭"}, errs, http.StatusOK)
+}
+
+// 登录第二步，提交密码登录返回的challengeToken和验证码
+func (h *Handler) LoginTwoFactor(c *gin.Context) {
+	var req dtos.TwoFactorLoginReq
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"err": "无效参数"})
+		return
+	}
+
+	resp, errs := h.s.LoginTwoFactor(req.ChallengeToken, req.Code, c.ClientIP(), c.Request.UserAgent())
+	writeResp(c, resp, errs, http.StatusOK)
+}
@@ -4,9 +4,9 @@ import (
 	"log"
 	"net/http"
 	"strconv"
+	"strings"
 
 	"github.com/Jack-samu/the-blog-backend-gin.git/internal/dtos"
-	"github.com/Jack-samu/the-blog-backend-gin.git/internal/utils"
 	"github.com/gin-gonic/gin"
 )
 
@@ -92,17 +92,17 @@ func (h *Handler) Login(c *gin.Context) {
 		return
 	}
 
-	loginResp, errs := h.s.Login(req.Username, req.Password)
+	loginResp, errs := h.s.Login(req.Username, req.Password, c.ClientIP(), c.Request.UserAgent())
 	if errs != nil {
 		switch errs.Code {
-		case 400:
-			c.JSON(http.StatusBadRequest, gin.H{
-				"err": errs.Msg,
-			})
 		case 500:
 			c.JSON(http.StatusInternalServerError, gin.H{
 				"err": errs.Msg + errs.Err.Error(),
 			})
+		default:
+			c.JSON(errs.Code, gin.H{
+				"err": errs.Msg,
+			})
 		}
 	} else {
 		c.JSON(http.StatusOK, loginResp)
@@ -113,9 +113,10 @@ func (h *Handler) Logout(c *gin.Context) {
 	user_id := c.GetString("user_id")
 	if user_id == "" {
 		c.JSON(http.StatusUnauthorized, gin.H{"err": "用户状态信息查询出错，请重试"})
+		return
 	}
 
-	last_activity, err := h.s.Logout(user_id)
+	last_activity, err := h.s.Logout(user_id, c.GetString("session_id"))
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"err": "退出出错" + err.Err.Error()})
 	} else {
@@ -124,26 +125,27 @@ func (h *Handler) Logout(c *gin.Context) {
 }
 
 func (h *Handler) RefreshTheToken(c *gin.Context) {
-	user_id := c.GetString("user_id")
-	if user_id == "" {
-		c.JSON(http.StatusUnauthorized, gin.H{"err": "用户状态信息查询出错，请重试"})
+	var req dtos.RefreshReq
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"err": "无效参数"})
+			return
+		}
 	}
 
-	refreshResp, errs := h.s.RefreshTheToken(user_id)
-	if errs != nil {
-		switch errs.Code {
-		case 400:
-			c.JSON(http.StatusBadRequest, gin.H{
-				"err": errs.Msg,
-			})
-		case 500:
-			c.JSON(http.StatusInternalServerError, gin.H{
-				"err": errs.Msg,
-			})
+	if req.RefreshToken == "" {
+		parts := strings.SplitN(c.GetHeader("Authorization"), " ", 2)
+		if len(parts) == 2 && parts[0] == "Bearer" {
+			req.RefreshToken = parts[1]
 		}
-	} else {
-		c.JSON(http.StatusOK, refreshResp)
 	}
+	if req.RefreshToken == "" {
+		c.JSON(http.StatusUnauthorized, gin.H{"err": "refreshToken缺失"})
+		return
+	}
+
+	refreshResp, errs := h.s.RefreshTheToken(req.RefreshToken)
+	writeResp(c, refreshResp, errs, http.StatusOK)
 }
 
 func (h *Handler) GetCaptcha(c *gin.Context) {
@@ -158,11 +160,7 @@ func (h *Handler) GetCaptcha(c *gin.Context) {
 	}
 
 	errs := h.s.SendCaptcha(req.Username)
-	if errs != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"err": ""})
-		return
-	}
-	c.JSON(http.StatusOK, gin.H{"msg": "验证码已发送"})
+	writeResp(c, gin.H{"msg": "验证码已发送"}, errs, http.StatusOK)
 }
 
 func (h *Handler) VerifyCaptcha(c *gin.Context) {
@@ -174,41 +172,28 @@ func (h *Handler) VerifyCaptcha(c *gin.Context) {
 		return
 	}
 
-	err := h.s.Verify(req.Username, req.Code)
-	if err != nil {
-		switch err.Code {
-		case 400:
-			c.JSON(http.StatusBadRequest, gin.H{
-				"err": err.Msg,
-			})
-		case 500:
-			c.JSON(http.StatusInternalServerError, gin.H{
-				"err": err.Msg,
-			})
-		}
-		log.Println("啥？我漏了？")
-		return
-	}
-	c.JSON(http.StatusOK, gin.H{"msg": "校验通过，密码重设链接已发送到邮箱"})
+	errs := h.s.Verify(req.Username, req.Code)
+	writeResp(c, gin.H{"msg": "校验通过，密码重设链接已发送到邮箱"}, errs, http.StatusOK)
 }
 
+const resetForm = `<!DOCTYPE html>
+<html>
+<body>
+	<form method="post">
+		<input type="password" name="password" placeholder="输入新密码" required />
+		<input type="password" name="pwdRepeat" placeholder="确认新密码" required />
+		<input type="submit" value="重置密码">
+	</form>
+</body>
+</html>`
+
+// 邮件链接打开的表单页面，token在提交时才核销
 func (h *Handler) ResetPassword(c *gin.Context) {
 	token := c.Param("token")
 
-	payload, err := utils.ParseToken(token)
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"err": "token无效或者已过期"})
-		return
-	}
-
 	switch c.Request.Method {
 	case http.MethodGet:
-		c.HTML(http.StatusOK, `            
-				<form method="post">
-					<input type="password" name="password" placeholder="输入新密码" required />
-					<input type="password" name="pwdRepeat" placeholder="确认新密码" required />
-					<input type="submit" value="重置密码">                   
-				</form>`, nil)
+		c.Data(http.StatusOK, "text/html; charset=utf-8", []byte(resetForm))
 	case http.MethodPost:
 		password := c.PostForm("password")
 		pwdConfirm := c.PostForm("pwdRepeat")
@@ -218,18 +203,28 @@ func (h *Handler) ResetPassword(c *gin.Context) {
 			return
 		}
 
-		err := h.s.Reset(payload.ID, password)
-		if err != nil {
-			switch err.Code {
-			case http.StatusBadRequest:
-				c.JSON(http.StatusBadRequest, gin.H{"err": err.Msg})
-			case http.StatusInternalServerError:
-				c.JSON(http.StatusInternalServerError, gin.H{"err": err.Err.Error()})
-			}
-		} else {
-			c.JSON(http.StatusOK, gin.H{"msg": "密码已重置"})
-		}
+		errs := h.s.Reset(token, password)
+		writeResp(c, gin.H{"msg": "密码已重置"}, errs, http.StatusOK)
+	default:
+		c.JSON(http.StatusMethodNotAllowed, gin.H{"err": "不支持的请求方法"})
+	}
+}
+
+// 供前端页面调用的json版本
+func (h *Handler) ResetPasswordJSON(c *gin.Context) {
+	var req dtos.ResetReq
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"err": "无效参数"})
+		return
+	}
+
+	if req.Password != req.PwdRepeat {
+		c.JSON(http.StatusBadRequest, gin.H{"err": "密码不一致"})
+		return
 	}
+
+	errs := h.s.Reset(req.Token, req.Password)
+	writeResp(c, gin.H{"msg": "密码已重置"}, errs, http.StatusOK)
 }
 
 func (h *Handler) Profile(c *gin.Context) {
@@ -240,7 +235,7 @@ func (h *Handler) Profile(c *gin.Context) {
 		return
 	}
 
-	profileResp, err := h.s.Profile(id)
+	profileResp, err := h.s.Profile(id, c.GetString("user_id"))
 	if err != nil {
 		switch err.Code {
 		case http.StatusBadRequest:
@@ -384,3 +379,71 @@ func (h *Handler) UploadImg(c *gin.Context) {
 		})
 	}
 }
+
+// 邮件中的验证链接
+func (h *Handler) VerifyEmail(c *gin.Context) {
+	token := c.Query("token")
+	if token == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"err": "验证链接无效"})
+		return
+	}
+
+	errs := h.s.VerifyEmail(token)
+	writeResp(c, gin.H{"msg": "邮箱验证成功"}, errs, http.StatusOK)
+}
+
+func (h *Handler) ResendVerification(c *gin.Context) {
+	errs := h.s.ResendVerification(c.GetString("user_id"))
+	writeResp(c, gin.H{"msg": "验证邮件已发送"}, errs, http.StatusOK)
+}
+
+func (h *Handler) ChangePassword(c *gin.Context) {
+	var req dtos.ChangePasswordReq
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"err": "无效参数"})
+		return
+	}
+
+	errs := h.s.ChangePassword(c.GetString("user_id"), c.GetString("session_id"), c.ClientIP(), &req)
+	writeResp(c, gin.H{"msg": "密码已修改"}, errs, http.StatusOK)
+}
+
+func (h *Handler) ChangeEmail(c *gin.Context) {
+	var req dtos.ChangeEmailReq
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"err": "无效参数"})
+		return
+	}
+
+	errs := h.s.RequestEmailChange(c.GetString("user_id"), c.ClientIP(), &req)
+	writeResp(c, gin.H{"msg": "确认邮件已发送到新邮箱"}, errs, http.StatusOK)
+}
+
+// 新邮箱收到的确认链接
+func (h *Handler) ConfirmEmail(c *gin.Context) {
+	token := c.Query("token")
+	if token == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"err": "确认链接无效"})
+		return
+	}
+
+	errs := h.s.ConfirmEmailChange(token)
+	writeResp(c, gin.H{"msg": "邮箱已修改"}, errs, http.StatusOK)
+}
+
+func (h *Handler) UpdateProfile(c *gin.Context) {
+	var req dtos.ProfileUpdateReq
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"err": "无效参数"})
+		return
+	}
+
+	profileResp, errs := h.s.UpdateProfile(c.GetString("user_id"), &req)
+	writeResp(c, profileResp, errs, http.StatusOK)
+}
+
+// 公开的作者主页
+func (h *Handler) GetAuthor(c *gin.Context) {
+	authorResp, errs := h.s.AuthorPage(c.Param("username"), c.GetString("user_id"))
+	writeResp(c, authorResp, errs, http.StatusOK)
+}
@@ -4,10 +4,37 @@ import (
 	"net/http"
 	"strings"
 
+	"github.com/Jack-samu/the-blog-backend-gin.git/internal/errs"
+	"github.com/Jack-samu/the-blog-backend-gin.git/internal/models"
 	"github.com/Jack-samu/the-blog-backend-gin.git/internal/utils"
 	"github.com/gin-gonic/gin"
 )
 
+// token签名校验之外的额外检查，例如账号是否被封禁，由main启动时注册；
+// 返回用户当前的角色，角色变更后不必等token过期就能生效，返回空时沿用token中的角色
+type TokenValidator func(payload *utils.Payload) (string, *errs.ErrorResp)
+
+var validators []TokenValidator
+
+func AddValidator(v TokenValidator) {
+	validators = append(validators, v)
+}
+
+// 校验通过时返回请求使用的角色
+func validate(payload *utils.Payload) (string, *errs.ErrorResp) {
+	role := roleOf(payload)
+	for _, v := range validators {
+		current, errResp := v(payload)
+		if errResp != nil {
+			return "", errResp
+		}
+		if current != "" {
+			role = current
+		}
+	}
+	return role, nil
+}
+
 func Auth() gin.HandlerFunc {
 	return func(c *gin.Context) {
 		authHeader := c.GetHeader("Authorization")
@@ -25,13 +52,68 @@ func Auth() gin.HandlerFunc {
 		}
 
 		payload, err := utils.ParseToken(parts[1])
-		if err != nil {
+		if err != nil || payload.Type != utils.TokenAccess {
 			c.JSON(http.StatusUnauthorized, gin.H{"err": "token无效或者已过期"})
 			c.Abort()
 			return
 		}
 
+		role, errResp := validate(payload)
+		if errResp != nil {
+			c.JSON(errResp.Code, gin.H{"err": errResp.Msg})
+			c.Abort()
+			return
+		}
+
 		c.Set("user_id", payload.ID)
+		c.Set("role", role)
+		c.Set("session_id", payload.SessionID)
+		c.Next()
+	}
+}
+
+// 旧token里没有角色信息，按注册时的默认角色处理
+func roleOf(payload *utils.Payload) string {
+	if payload.Role == "" {
+		return models.RoleAuthor
+	}
+	return payload.Role
+}
+
+// 限定角色，需放在Auth之后；管理员不受限制
+func RequireRole(roles ...string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		role := c.GetString("role")
+		if role == models.RoleAdmin {
+			c.Next()
+			return
+		}
+
+		for _, r := range roles {
+			if role == r {
+				c.Next()
+				return
+			}
+		}
+
+		c.JSON(http.StatusForbidden, gin.H{"err": "权限不足"})
+		c.Abort()
+	}
+}
+
+// 公共路由使用，token有效时写入user_id，无token或者token无效时按游客处理
+func OptionalAuth() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		parts := strings.SplitN(c.GetHeader("Authorization"), " ", 2)
+		if len(parts) == 2 && parts[0] == "Bearer" {
+			if payload, err := utils.ParseToken(parts[1]); err == nil && payload.Type == utils.TokenAccess {
+				if role, errResp := validate(payload); errResp == nil {
+					c.Set("user_id", payload.ID)
+					c.Set("role", role)
+				}
+			}
+		}
+
 		c.Next()
 	}
 }
@@ -14,12 +14,15 @@ type Tag struct {
 	Drafts []Draft `gorm:"many2many:draft_tags"`
 }
 
+// 用户的系列（分类），名称在同一用户下唯一
 type Category struct {
-	ID        uint      `gorm:"primaryKey;autoIncrement"`
-	Name      string    `gorm:"size:20;uniqueIndex;not null"`
-	UserID    string    `gorm:"type:varchar(36);not null"`
-	CreatedAt time.Time `gorm:"autoUpdateTime:false;index"`
-	UpdatedAt time.Time `gorm:"autoUpdateTime:milli"`
+	ID          uint   `gorm:"primaryKey;autoIncrement"`
+	Name        string `gorm:"size:20;uniqueIndex:idx_category_user_name;not null"`
+	Description string `gorm:"size:500"`
+	Cover       string
+	UserID      string    `gorm:"type:varchar(36);not null;uniqueIndex:idx_category_user_name"`
+	CreatedAt   time.Time `gorm:"autoUpdateTime:false;index"`
+	UpdatedAt   time.Time `gorm:"autoUpdateTime:milli"`
 
 	User   *User   `gorm:"foreignKey:UserID;references:ID"`
 	Posts  []Post  `gorm:"foreignKey:CategoryID;constraint:OnDelete:CASCADE"`
@@ -42,10 +45,16 @@ type Post struct {
 	Author     User      `gorm:"foreignKey:UserID"`
 	CategoryID *uint     `gorm:"index"`
 	Category   *Category `gorm:"foreignKey:CategoryID"`
-	Draft      *Draft    `gorm:"foreignKey:PostID"`
-	Tags       []Tag     `gorm:"many2many:post_tags;"`
+	// 在系列中的顺序，多篇连载按此排列
+	SeriesOrder int    `gorm:"not null;default:0"`
+	Draft       *Draft `gorm:"foreignKey:PostID;constraint:OnDelete:SET NULL"`
+	Tags        []Tag  `gorm:"many2many:post_tags;"`
 
-	Comments []Comment `gorm:"foreignKey:PostID;constraint:OnDelete:CASCADE"`
+	Comments  []Comment      `gorm:"foreignKey:PostID;constraint:OnDelete:CASCADE"`
+	Revisions []PostRevision `gorm:"foreignKey:PostID;constraint:OnDelete:CASCADE"`
+
+	// 列表查询时通过子查询填充的评论数，不建列
+	CommentCnt int `gorm:"->;-:migration"`
 }
 
 type Draft struct {
@@ -64,6 +73,26 @@ type Draft struct {
 	Category   *Category `gorm:"foreignKey:CategoryID"`
 	Tags       []Tag     `gorm:"many2many:draft_tags"`
 	PostID     *uint     `gorm:"uniqueIndex"`
+	// 每次保存递增，用于多处同时编辑时的冲突检测
+	Version int `gorm:"not null;default:1"`
+	// 定时发布时间，为空表示普通草稿
+	PublishAt *time.Time `gorm:"index"`
+}
+
+// 文章每次发布时的快照，version在同一篇文章内从1递增
+type PostRevision struct {
+	ID        uint   `gorm:"primaryKey;autoIncrement"`
+	PostID    uint   `gorm:"not null;uniqueIndex:idx_revision_post_version"`
+	Version   int    `gorm:"not null;uniqueIndex:idx_revision_post_version"`
+	Title     string `gorm:"size:100;not null"`
+	Excerpt   string `gorm:"size:200"`
+	Content   string `gorm:"type:longtext"`
+	Cover     string
+	CreatedAt time.Time `gorm:"index"`
+
+	// 发布该版本的用户
+	UserID string `gorm:"type:varchar(36);not null;index"`
+	Author User   `gorm:"foreignKey:UserID;constraint:OnDelete:CASCADE"`
 }
 
 type Img struct {
@@ -108,3 +137,7 @@ func (d *Draft) GetTagsName() []string {
 
 	return tags
 }
+
+func (c *Category) GetUserID() string {
+	return c.UserID
+}
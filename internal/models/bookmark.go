@@ -0,0 +1,44 @@
+package models
+
+import "time"
+
+// 收藏的文章，同一篇文章每个用户只收藏一次
+type Bookmark struct {
+	ID        uint      `gorm:"primaryKey"`
+	UserID    string    `gorm:"type:char(36);not null;uniqueIndex:idx_bookmark_user_post"`
+	PostID    uint      `gorm:"not null;uniqueIndex:idx_bookmark_user_post;index"`
+	CreatedAt time.Time `gorm:"index"`
+
+	User *User `gorm:"foreignKey:UserID;constraint:OnDelete:CASCADE"`
+	Post *Post `gorm:"foreignKey:PostID;constraint:OnDelete:CASCADE"`
+}
+
+// 阅读列表，公开后可以通过ShareToken生成的链接分享
+type ReadingList struct {
+	ID          uint      `gorm:"primaryKey"`
+	UserID      string    `gorm:"type:char(36);not null;index"`
+	Name        string    `gorm:"type:varchar(50);not null"`
+	Description string    `gorm:"type:varchar(500)"`
+	Public      bool      `gorm:"default:false"`
+	ShareToken  *string   `gorm:"type:varchar(64);uniqueIndex"`
+	CreatedAt   time.Time `gorm:"index"`
+	UpdatedAt   time.Time
+
+	User  *User             `gorm:"foreignKey:UserID;constraint:OnDelete:CASCADE"`
+	Items []ReadingListItem `gorm:"foreignKey:ListID;constraint:OnDelete:CASCADE"`
+}
+
+func (l *ReadingList) GetUserID() string {
+	return l.UserID
+}
+
+// 阅读列表中的文章，按Position升序排列
+type ReadingListItem struct {
+	ID        uint `gorm:"primaryKey"`
+	ListID    uint `gorm:"not null;uniqueIndex:idx_list_post"`
+	PostID    uint `gorm:"not null;uniqueIndex:idx_list_post;index"`
+	Position  int  `gorm:"not null;default:0"`
+	CreatedAt time.Time
+
+	Post *Post `gorm:"foreignKey:PostID;constraint:OnDelete:CASCADE"`
+}
@@ -36,9 +36,25 @@ type Reply struct {
 	Replies  []*Reply `gorm:"foreignKey:ParentID;constraint:OnDelete:CASCADE"`
 }
 
+// 点赞目标类型
+const (
+	LikeTargetPost    = "post"
+	LikeTargetComment = "comment"
+	LikeTargetReply   = "reply"
+)
+
+// 同一用户对同一目标只能有一条点赞记录
 type Like struct {
-	UserID     string    `gorm:"type:varchar(36);not null"`
-	TargetType string    `gorm:"index"`
-	TargetID   uint      `gorm:"index"`
+	UserID     string    `gorm:"type:varchar(36);not null;uniqueIndex:idx_like_user_target"`
+	TargetType string    `gorm:"type:varchar(16);index;uniqueIndex:idx_like_user_target"`
+	TargetID   uint      `gorm:"index;uniqueIndex:idx_like_user_target"`
 	CreatedAt  time.Time `gorm:"autoUpdateTime;index"`
 }
+
+func (c *Comment) GetUserID() string {
+	return c.UserID
+}
+
+func (r *Reply) GetUserID() string {
+	return r.UserID
+}
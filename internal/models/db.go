@@ -26,6 +26,9 @@ func InitDB() *gorm.DB {
 		log.Fatalf("数据库句柄创建失败，%s\n", err.Error())
 	}
 
+	// 邮箱验证上线前注册的用户视为已验证
+	backfillVerified := db.Migrator().HasTable(&User{}) && !db.Migrator().HasColumn(&User{}, "EmailVerifiedAt")
+
 	// 数据库迁移
 	err = db.AutoMigrate(
 		&User{},
@@ -37,10 +40,41 @@ func InitDB() *gorm.DB {
 		&Comment{},
 		&Reply{},
 		&Like{},
+		&PostRevision{},
+		&RefreshToken{},
+		&Session{},
+		&UserToken{},
+		&RecoveryCode{},
+		&UserIdentity{},
+		&Follow{},
+		&Bookmark{},
+		&ReadingList{},
+		&ReadingListItem{},
 	)
 	if err != nil {
 		log.Fatalf("数据库迁移失败：%v\n", err)
 	}
 
+	if backfillVerified {
+		if err = db.Exec("UPDATE users SET email_verified_at = created_at WHERE email_verified_at IS NULL").Error; err != nil {
+			log.Fatalf("已有用户邮箱验证状态补全失败：%v\n", err)
+		}
+	}
+
+	// 分类名改为按用户唯一，去掉旧的全局唯一索引
+	if db.Migrator().HasIndex(&Category{}, "idx_categories_name") {
+		if err = db.Migrator().DropIndex(&Category{}, "idx_categories_name"); err != nil {
+			log.Fatalf("旧分类索引删除失败：%v\n", err)
+		}
+	}
+
+	// 文章全文索引，ngram分词以支持中文搜索
+	if !db.Migrator().HasIndex(&Post{}, "idx_posts_fulltext") {
+		err = db.Exec("CREATE FULLTEXT INDEX idx_posts_fulltext ON posts (title, excerpt, content) WITH PARSER ngram").Error
+		if err != nil {
+			log.Fatalf("全文索引创建失败：%v\n", err)
+		}
+	}
+
 	return db
 }
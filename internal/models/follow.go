@@ -0,0 +1,14 @@
+package models
+
+import "time"
+
+// 关注关系，Follower关注了Followee
+type Follow struct {
+	ID         uint      `gorm:"primaryKey"`
+	FollowerID string    `gorm:"type:char(36);not null;uniqueIndex:idx_follow_pair"`
+	FolloweeID string    `gorm:"type:char(36);not null;uniqueIndex:idx_follow_pair;index"`
+	CreatedAt  time.Time `gorm:"index"`
+
+	Follower *User `gorm:"foreignKey:FollowerID;constraint:OnDelete:CASCADE"`
+	Followee *User `gorm:"foreignKey:FolloweeID;constraint:OnDelete:CASCADE"`
+}
@@ -0,0 +1,14 @@
+package models
+
+import "time"
+
+// 第三方登录的外部身份，同一提供方下的sub唯一对应一个用户
+type UserIdentity struct {
+	ID          uint   `gorm:"primaryKey"`
+	UserID      string `gorm:"type:char(36);not null;index"`
+	Provider    string `gorm:"type:varchar(32);not null;uniqueIndex:idx_identity_subject"`
+	Subject     string `gorm:"type:varchar(255);not null;uniqueIndex:idx_identity_subject"`
+	Email       string
+	CreatedAt   time.Time
+	LastLoginAt time.Time
+}
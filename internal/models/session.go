@@ -0,0 +1,20 @@
+package models
+
+import "time"
+
+// 一次登录对应一个会话，会话ID写在token的sid里，也是refresh token的FamilyID；
+// 会话吊销后，该会话签发的access token和refresh token都会失效
+type Session struct {
+	ID         string `gorm:"type:char(36);primaryKey"`
+	UserID     string `gorm:"type:char(36);not null;index"`
+	UserAgent  string `gorm:"size:255"`
+	IP         string `gorm:"size:64"`
+	CreatedAt  time.Time
+	LastSeenAt time.Time
+	ExpiresAt  time.Time `gorm:"index"`
+	RevokedAt  *time.Time
+}
+
+func (s *Session) Active(now time.Time) bool {
+	return s.RevokedAt == nil && now.Before(s.ExpiresAt)
+}
@@ -0,0 +1,44 @@
+package models
+
+import "time"
+
+// 服务端保存的refresh token，每次刷新都会轮换；
+// 同一次登录轮换出的token属于同一个FamilyID（即会话ID），旧token被重复使用时整个会话吊销
+type RefreshToken struct {
+	ID        uint      `gorm:"primaryKey"`
+	UserID    string    `gorm:"type:char(36);not null;index"`
+	FamilyID  string    `gorm:"type:char(36);not null;index"`
+	TokenHash string    `gorm:"type:char(64);not null;uniqueIndex"`
+	ExpiresAt time.Time `gorm:"not null"`
+	UsedAt    *time.Time
+	RevokedAt *time.Time
+	CreatedAt time.Time
+}
+
+// 一次性链接的用途
+const (
+	PurposeVerifyEmail   = "verify_email"
+	PurposeResetPassword = "reset_password"
+	PurposeChangeEmail   = "change_email"
+)
+
+// 邮件中一次性链接携带的token，只保存哈希，使用后即失效
+type UserToken struct {
+	ID        uint      `gorm:"primaryKey"`
+	UserID    string    `gorm:"type:char(36);not null;index"`
+	Purpose   string    `gorm:"type:varchar(32);not null;index"`
+	TokenHash string    `gorm:"type:char(64);not null;uniqueIndex"`
+	Data      string    `gorm:"type:varchar(255)"` // 附带的数据，如修改邮箱时的新地址
+	ExpiresAt time.Time `gorm:"not null"`
+	UsedAt    *time.Time
+	CreatedAt time.Time
+}
+
+// 两步验证的恢复码，只保存哈希，每个只能使用一次
+type RecoveryCode struct {
+	ID        uint   `gorm:"primaryKey"`
+	UserID    string `gorm:"type:char(36);not null;index"`
+	CodeHash  string `gorm:"type:char(64);not null"`
+	UsedAt    *time.Time
+	CreatedAt time.Time
+}
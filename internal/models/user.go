@@ -7,6 +7,32 @@ import (
 	"golang.org/x/crypto/bcrypt"
 )
 
+// 用户角色，权限依次递增
+const (
+	RoleReader    = "reader"
+	RoleAuthor    = "author"
+	RoleModerator = "moderator"
+	RoleAdmin     = "admin"
+)
+
+func ValidRole(role string) bool {
+	switch role {
+	case RoleReader, RoleAuthor, RoleModerator, RoleAdmin:
+		return true
+	}
+	return false
+}
+
+// 作者、版主和管理员可以发布和编辑文章
+func CanPublish(role string) bool {
+	return role == RoleAuthor || role == RoleModerator || role == RoleAdmin
+}
+
+// 版主和管理员可以管理别人的评论
+func CanModerate(role string) bool {
+	return role == RoleModerator || role == RoleAdmin
+}
+
 type User struct {
 	ID            string `gorm:"type:char(36);primaryKey"`
 	Username      string `gorm:"unique;not null"`
@@ -18,13 +44,31 @@ type User struct {
 	UpdatedAt     time.Time `gorm:"autoUpdateTime:milli"`
 	LastActivity  time.Time `gorm:"autoUpdateTime:false"`
 	FailedLogin   int
+	LockedUntil   *time.Time
 	CaptchaReqCnt int
+	Role          string `gorm:"type:varchar(16);not null;default:author"`
+	SuspendedAt   *time.Time
+	// 为空表示邮箱未验证
+	EmailVerifiedAt *time.Time
+	// 两步验证，密钥在确认第一个验证码后才启用；LastStep记录最近使用的周期，防止验证码重放
+	TOTPSecret    string `gorm:"type:varchar(64)"`
+	TOTPEnabledAt *time.Time
+	TOTPLastStep  int64
+	// 个人主页资料，SocialLinks按平台保存链接
+	DisplayName string            `gorm:"type:varchar(50)"`
+	Website     string            `gorm:"type:varchar(255)"`
+	SocialLinks map[string]string `gorm:"serializer:json;type:text"`
 
 	// 外键外联
-	Posts      []Post     `gorm:"foreignKey:UserID;constraint:OnDelete:CASCADE"`
-	Drafts     []Draft    `gorm:"foreignKey:UserID;constraint:OnDelete:CASCADE"`
-	Images     []Img      `gorm:"foreignKey:UserID;constraint:OnDelete:CASCADE"`
-	Categories []Category `gorm:"foreignKey:UserID;constraint:OnDelete:CASCADE"`
+	Posts      []Post         `gorm:"foreignKey:UserID;constraint:OnDelete:CASCADE"`
+	Drafts     []Draft        `gorm:"foreignKey:UserID;constraint:OnDelete:CASCADE"`
+	Images     []Img          `gorm:"foreignKey:UserID;constraint:OnDelete:CASCADE"`
+	Categories []Category     `gorm:"foreignKey:UserID;constraint:OnDelete:CASCADE"`
+	Tokens     []RefreshToken `gorm:"foreignKey:UserID;constraint:OnDelete:CASCADE"`
+	Sessions   []Session      `gorm:"foreignKey:UserID;constraint:OnDelete:CASCADE"`
+	UserTokens []UserToken    `gorm:"foreignKey:UserID;constraint:OnDelete:CASCADE"`
+	Recovery   []RecoveryCode `gorm:"foreignKey:UserID;constraint:OnDelete:CASCADE"`
+	Identities []UserIdentity `gorm:"foreignKey:UserID;constraint:OnDelete:CASCADE"`
 
 	Comments []Comment `gorm:"foreignKey:UserID;constraint:OnDelete:SET NULL"`
 	Replies  []Reply   `gorm:"foreignKey:UserID;constraint:OnDelete:SET NULL"`
@@ -0,0 +1,179 @@
+package service
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	dao "github.com/Jack-samu/the-blog-backend-gin.git/internal/DAO"
+	"github.com/Jack-samu/the-blog-backend-gin.git/internal/dtos"
+	"github.com/Jack-samu/the-blog-backend-gin.git/internal/errs"
+	"github.com/Jack-samu/the-blog-backend-gin.git/internal/models"
+	"github.com/Jack-samu/the-blog-backend-gin.git/internal/utils"
+	"gorm.io/gorm"
+)
+
+var errEmailTaken = errors.New("邮箱已被占用")
+
+func (s *Service) accountUser(userID string) (*models.User, *errs.ErrorResp) {
+	user, err := s.r.GetUserById(nil, userID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errs.NewError(http.StatusUnauthorized, "用户不存在", nil)
+		}
+		log.Printf("用户查询出错：%s\n", err.Error())
+		return nil, errs.NewError(http.StatusInternalServerError, "用户查询出错", err)
+	}
+	return user, nil
+}
+
+// 已登录时再次校验密码，错误次数和登录一起计算，防止拿到会话后借此猜测密码
+func (s *Service) checkCurrentPassword(user *models.User, password, ip, msg string) *errs.ErrorResp {
+	now := time.Now()
+	if errResp := s.checkAttempt(user, ip, now); errResp != nil {
+		return errResp
+	}
+
+	if !user.CheckPassword(password) {
+		if errResp := s.attemptFailed(user, ip, now); errResp != nil {
+			return errResp
+		}
+		return errs.NewError(http.StatusBadRequest, msg, nil)
+	}
+	s.attemptPassed(user, ip)
+	return nil
+}
+
+// 修改密码需要校验原密码，成功后保留当前会话，吊销其他会话和未使用的重设链接
+func (s *Service) ChangePassword(userID, sessionID, ip string, req *dtos.ChangePasswordReq) *errs.ErrorResp {
+	user, errResp := s.accountUser(userID)
+	if errResp != nil {
+		return errResp
+	}
+
+	if errResp := s.checkCurrentPassword(user, req.OldPassword, ip, "原密码错误"); errResp != nil {
+		return errResp
+	}
+	if len(req.NewPassword) < 8 {
+		return errs.NewError(http.StatusBadRequest, "密码长度不该小于8", nil)
+	}
+	if req.NewPassword == req.OldPassword {
+		return errs.NewError(http.StatusBadRequest, "新密码不能和原密码相同", nil)
+	}
+
+	if err := user.SetPassword(req.NewPassword); err != nil {
+		log.Printf("新密码设置出错：%s\n", err.Error())
+		return errs.NewError(http.StatusInternalServerError, "", err)
+	}
+
+	err := s.r.Transaction(func(tx *gorm.DB) error {
+		if err := s.r.UpdatePassword(tx, user.ID, user.Pwd); err != nil {
+			return err
+		}
+		if err := s.r.InvalidateUserTokens(tx, user.ID, models.PurposeResetPassword); err != nil {
+			return err
+		}
+		_, err := s.r.RevokeOtherSessions(tx, user.ID, sessionID)
+		return err
+	})
+	if err != nil {
+		log.Printf("密码修改出错：%s\n", err.Error())
+		return errs.NewError(http.StatusInternalServerError, "", err)
+	}
+
+	return nil
+}
+
+// 修改邮箱：确认链接发到新邮箱，同时提醒原邮箱；新邮箱确认后才写入用户信息
+func (s *Service) RequestEmailChange(userID, ip string, req *dtos.ChangeEmailReq) *errs.ErrorResp {
+	user, errResp := s.accountUser(userID)
+	if errResp != nil {
+		return errResp
+	}
+
+	if errResp := s.checkCurrentPassword(user, req.Password, ip, "密码错误"); errResp != nil {
+		return errResp
+	}
+	if errResp := s.checkResendCooldown(user.ID, models.PurposeChangeEmail); errResp != nil {
+		return errResp
+	}
+
+	email := strings.TrimSpace(req.Email)
+	if strings.EqualFold(email, user.Email) {
+		return errs.NewError(http.StatusBadRequest, "新邮箱和当前邮箱相同", nil)
+	}
+
+	exists, err := s.r.ExistByEmail(nil, email)
+	if err != nil {
+		log.Printf("用户查询出错：%s\n", err.Error())
+		return errs.NewError(http.StatusInternalServerError, "用户查询出错", err)
+	}
+	if exists {
+		return errs.NewError(http.StatusBadRequest, "用户邮箱已注册", nil)
+	}
+
+	// 只保留最近一次申请的链接
+	var token string
+	err = s.r.Transaction(func(tx *gorm.DB) error {
+		if err := s.r.InvalidateUserTokens(tx, user.ID, models.PurposeChangeEmail); err != nil {
+			return err
+		}
+
+		var err error
+		token, err = s.issueLink(tx, user.ID, models.PurposeChangeEmail, email,
+			envDuration("EMAIL_CHANGE_TTL", time.Hour))
+		return err
+	})
+	if err != nil {
+		log.Printf("邮箱修改链接生成出错：%s\n", err.Error())
+		return errs.NewError(http.StatusInternalServerError, "", err)
+	}
+
+	if err = utils.SendChangeEmailLink(email, publicBaseURL()+"/auth/confirm-email?token="+url.QueryEscape(token)); err != nil {
+		log.Printf("邮箱修改确认邮件发送失败：%s\n", err.Error())
+		return errs.NewError(http.StatusInternalServerError, "确认邮件发送失败", err)
+	}
+
+	// 提醒邮件只是通知，发送失败不影响修改
+	if err = utils.SendEmailChangeNotice(user.Email, email); err != nil {
+		log.Printf("邮箱修改提醒发送失败：%s\n", err.Error())
+	}
+
+	return nil
+}
+
+func (s *Service) ConfirmEmailChange(token string) *errs.ErrorResp {
+	err := s.r.Transaction(func(tx *gorm.DB) error {
+		record, err := s.consumeLink(tx, token, models.PurposeChangeEmail)
+		if err != nil {
+			return err
+		}
+
+		// 申请之后邮箱可能已被别人注册
+		exists, err := s.r.ExistByEmail(tx, record.Data)
+		if err != nil {
+			return err
+		}
+		if exists {
+			return errEmailTaken
+		}
+
+		return s.r.UpdateEmail(tx, record.UserID, record.Data)
+	})
+
+	if err != nil {
+		if errors.Is(err, dao.ErrTokenUsed) {
+			return errs.NewError(http.StatusBadRequest, "确认链接无效或已过期", nil)
+		}
+		if errors.Is(err, errEmailTaken) {
+			return errs.NewError(http.StatusConflict, "用户邮箱已注册", nil)
+		}
+		log.Printf("邮箱修改出错：%s\n", err.Error())
+		return errs.NewError(http.StatusInternalServerError, "", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,211 @@
+package service
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	dao "github.com/Jack-samu/the-blog-backend-gin.git/internal/DAO"
+	"github.com/Jack-samu/the-blog-backend-gin.git/internal/dtos"
+	"github.com/Jack-samu/the-blog-backend-gin.git/internal/errs"
+	"github.com/Jack-samu/the-blog-backend-gin.git/internal/models"
+	"gorm.io/gorm"
+)
+
+// 管理员调整用户角色，新角色在用户下次登录或刷新token后生效
+func (s *Service) SetUserRole(id, role string) *errs.ErrorResp {
+	if !models.ValidRole(role) {
+		return errs.NewError(http.StatusBadRequest, "无效的角色", nil)
+	}
+
+	if err := s.r.SetUserRole(id, role); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return errs.NewError(http.StatusNotFound, "用户不存在", nil)
+		}
+		log.Printf("修改用户角色出错：%s\n", err.Error())
+		return errs.NewError(http.StatusInternalServerError, "", err)
+	}
+
+	return nil
+}
+
+// 管理员删除任意文章
+func (s *Service) AdminDeletePost(id uint) *errs.ErrorResp {
+	err := s.r.Transaction(func(tx *gorm.DB) error {
+		post, err := s.r.GetPost(id, tx)
+		if err != nil {
+			return err
+		}
+		return s.r.DeleteArticle(post, tx)
+	})
+
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return errs.NewError(http.StatusNotFound, "文章不存在", nil)
+		}
+		log.Printf("管理员删除文章出错：%s\n", err.Error())
+		return errs.NewError(http.StatusInternalServerError, "", err)
+	}
+
+	return nil
+}
+
+func (s *Service) getTag(id uint) (*models.Tag, *errs.ErrorResp) {
+	tag, err := s.r.GetTag(id)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errs.NewError(http.StatusNotFound, "标签不存在", nil)
+		}
+		log.Printf("标签查询出错：%s\n", err.Error())
+		return nil, errs.NewError(http.StatusInternalServerError, "", err)
+	}
+	return tag, nil
+}
+
+func (s *Service) RenameTag(id uint, req *dtos.TagRenameReq) (*dtos.TagItem, *errs.ErrorResp) {
+	name := strings.ToLower(strings.TrimSpace(req.Name))
+	if name == "" {
+		return nil, errs.NewError(http.StatusBadRequest, "标签名不能为空", nil)
+	}
+
+	tag, errResp := s.getTag(id)
+	if errResp != nil {
+		return nil, errResp
+	}
+
+	exist, err := s.r.ExistTagName(name, tag.ID)
+	if err != nil {
+		log.Printf("标签名查重出错：%s\n", err.Error())
+		return nil, errs.NewError(http.StatusInternalServerError, "", err)
+	}
+	if exist {
+		return nil, errs.NewError(http.StatusConflict, "标签名已存在", nil)
+	}
+
+	if err = s.r.RenameTag(tag, name); err != nil {
+		log.Printf("标签改名出错：%s\n", err.Error())
+		return nil, errs.NewError(http.StatusInternalServerError, "", err)
+	}
+
+	return &dtos.TagItem{ID: tag.ID, Name: name}, nil
+}
+
+func (s *Service) DeleteTag(id uint) *errs.ErrorResp {
+	tag, errResp := s.getTag(id)
+	if errResp != nil {
+		return errResp
+	}
+
+	err := s.r.Transaction(func(tx *gorm.DB) error {
+		return s.r.DeleteTag(tx, tag)
+	})
+	if err != nil {
+		log.Printf("删除标签出错：%s\n", err.Error())
+		return errs.NewError(http.StatusInternalServerError, "", err)
+	}
+
+	return nil
+}
+
+func (s *Service) GetUsers(req *dtos.AdminUserListReq) (*dtos.AdminUserListResp, *errs.ErrorResp) {
+	page, perPage := normalizePage(req.Page, req.PerPage)
+	paging := &dao.Paging{Page: page, PerPage: perPage}
+
+	filter := &dao.UserFilter{
+		Keyword:   strings.TrimSpace(req.Keyword),
+		Role:      req.Role,
+		MinFailed: req.MinFailed,
+		Suspended: req.Suspended,
+	}
+
+	ranges := []struct {
+		val  string
+		end  bool
+		dest **time.Time
+	}{
+		{req.RegisteredFrom, false, &filter.RegisteredFrom},
+		{req.RegisteredTo, true, &filter.RegisteredTo},
+		{req.ActiveFrom, false, &filter.ActiveFrom},
+		{req.ActiveTo, true, &filter.ActiveTo},
+	}
+	for _, r := range ranges {
+		if r.val == "" {
+			continue
+		}
+		t, ok := parseDate(r.val, r.end)
+		if !ok {
+			return nil, errs.NewError(http.StatusBadRequest, "日期格式错误", nil)
+		}
+		*r.dest = &t
+	}
+
+	users, total, err := s.r.GetUsers(paging, filter)
+	if err != nil {
+		log.Printf("用户列表查询出错：%s\n", err.Error())
+		return nil, errs.NewError(http.StatusInternalServerError, "", err)
+	}
+
+	return dtos.ToAdminUserList(users, total, paging.Page), nil
+}
+
+// 封禁后无法登录，已签发的token也会在鉴权时被拒绝
+func (s *Service) SuspendUser(id, operatorID string, suspend bool) *errs.ErrorResp {
+	if id == operatorID {
+		return errs.NewError(http.StatusBadRequest, "不能封禁自己", nil)
+	}
+
+	var suspendedAt *time.Time
+	if suspend {
+		now := time.Now()
+		suspendedAt = &now
+	}
+
+	if err := s.r.SetUserSuspended(id, suspendedAt); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return errs.NewError(http.StatusNotFound, "用户不存在", nil)
+		}
+		log.Printf("修改用户封禁状态出错：%s\n", err.Error())
+		return errs.NewError(http.StatusInternalServerError, "", err)
+	}
+
+	return nil
+}
+
+func (s *Service) ResetFailedLogin(id string) *errs.ErrorResp {
+	if err := s.r.ResetFailedLogin(id); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return errs.NewError(http.StatusNotFound, "用户不存在", nil)
+		}
+		log.Printf("重置登录失败计数出错：%s\n", err.Error())
+		return errs.NewError(http.StatusInternalServerError, "", err)
+	}
+	s.clearSourceFails(id)
+
+	return nil
+}
+
+func (s *Service) DeleteUser(id, operatorID string) *errs.ErrorResp {
+	if id == operatorID {
+		return errs.NewError(http.StatusBadRequest, "不能删除自己", nil)
+	}
+
+	err := s.r.Transaction(func(tx *gorm.DB) error {
+		var user models.User
+		if err := tx.Where("id = ?", id).First(&user).Error; err != nil {
+			return err
+		}
+		return s.r.DeleteUser(tx, &user)
+	})
+
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return errs.NewError(http.StatusNotFound, "用户不存在", nil)
+		}
+		log.Printf("删除用户出错：%s\n", err.Error())
+		return errs.NewError(http.StatusInternalServerError, "", err)
+	}
+
+	return nil
+}
@@ -7,33 +7,69 @@ import (
 	"strings"
 	"time"
 
+	dao "github.com/Jack-samu/the-blog-backend-gin.git/internal/DAO"
 	"github.com/Jack-samu/the-blog-backend-gin.git/internal/dtos"
 	"github.com/Jack-samu/the-blog-backend-gin.git/internal/errs"
 	"github.com/Jack-samu/the-blog-backend-gin.git/internal/models"
 	"gorm.io/gorm"
 )
 
-func (s *Service) GetPosts(page, perPage int64) (*dtos.PostListResp, *errs.ErrorResp) {
+func (s *Service) GetPosts(req *dtos.ArticleListReq, viewerID string) (*dtos.PostListResp, *errs.ErrorResp) {
 
-	posts, total, err := s.r.GetAllPosts(page, perPage)
+	paging, errResp := toPaging(&req.PageReq)
+	if errResp != nil {
+		return nil, errResp
+	}
+
+	filter := &dao.PostFilter{
+		Tag:      strings.ToLower(strings.TrimSpace(req.Tag)),
+		Category: strings.ToLower(strings.TrimSpace(req.Category)),
+		Author:   strings.TrimSpace(req.Author),
+		Sort:     req.Sort,
+	}
+
+	if req.From != "" {
+		from, ok := parseDate(req.From, false)
+		if !ok {
+			return nil, errs.NewError(http.StatusBadRequest, "起始日期格式错误", nil)
+		}
+		filter.From = &from
+	}
+	if req.To != "" {
+		to, ok := parseDate(req.To, true)
+		if !ok {
+			return nil, errs.NewError(http.StatusBadRequest, "截止日期格式错误", nil)
+		}
+		filter.To = &to
+	}
+	if filter.From != nil && filter.To != nil && !filter.From.Before(*filter.To) {
+		return nil, errs.NewError(http.StatusBadRequest, "日期范围无效", nil)
+	}
+
+	posts, info, err := s.r.GetAllPosts(paging, filter)
 	if err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
 			return nil, errs.NewError(http.StatusNotFound, "你找的啥啊？", nil)
 		}
+		if errors.Is(err, dao.ErrCursorUnsupported) {
+			return nil, errs.NewError(http.StatusBadRequest, err.Error(), nil)
+		}
 		log.Printf("404以外的：%s\n", err.Error())
 		return nil, errs.NewError(http.StatusInternalServerError, "", err)
 	}
 
 	// 转换为响应用的post列表格式
 	postList := dtos.ToPostList(posts)
+	s.markBookmarked(viewerID, postList)
 
 	// 进行实质的响应构造
-	postListResp := dtos.NewPostList(postList, total, page)
+	postListResp := dtos.NewPostList(postList, info.Total, paging.Page)
+	postListResp.CursorInfo = dtos.NewCursorInfo(info)
 
 	return postListResp, nil
 }
 
-func (s *Service) GetPost(id uint) (*dtos.PostDetailResp, *errs.ErrorResp) {
+func (s *Service) GetPost(id uint, viewerID string) (*dtos.PostDetailResp, *errs.ErrorResp) {
 
 	post, err := s.r.GetPost(id, nil)
 	if err != nil {
@@ -45,21 +81,27 @@ func (s *Service) GetPost(id uint) (*dtos.PostDetailResp, *errs.ErrorResp) {
 	}
 
 	postDetail := dtos.ToPostDetail(post)
+	// 加上还在缓冲中没写库的浏览量
+	postDetail.Views += uint(s.v.pendingOf(post.ID))
+	if viewerID != "" {
+		marked, err := s.r.BookmarkedPostIDs(viewerID, []uint{post.ID})
+		if err != nil {
+			log.Printf("收藏状态查询出错：%s\n", err.Error())
+		}
+		postDetail.Bookmarked = marked[post.ID]
+	}
 
 	return &dtos.PostDetailResp{
 		Post: postDetail,
 	}, nil
 }
 
-func (s *Service) GetDraft(id uint) (*dtos.DraftDetailResp, *errs.ErrorResp) {
+// 草稿只有作者本人能看
+func (s *Service) GetDraft(id uint, userID string) (*dtos.DraftDetailResp, *errs.ErrorResp) {
 
-	draft, err := s.r.GetDraft(id, nil)
-	if err != nil {
-		if errors.Is(err, gorm.ErrRecordNotFound) {
-			return nil, errs.NewError(http.StatusNotFound, "你找的啥啊？", nil)
-		}
-		log.Printf("404以外的：%s\n", err.Error())
-		return nil, errs.NewError(http.StatusInternalServerError, "", err)
+	draft, errResp := s.ownDraft(id, userID, nil)
+	if errResp != nil {
+		return nil, errResp
 	}
 
 	draftDetail := dtos.ToDraftDetail(draft)
@@ -69,9 +111,14 @@ func (s *Service) GetDraft(id uint) (*dtos.DraftDetailResp, *errs.ErrorResp) {
 	}, nil
 }
 
-func (s *Service) GetPostsOfUser(page, perPage int64, id string) (*dtos.PostListPersonalResp, *errs.ErrorResp) {
+func (s *Service) GetPostsOfUser(req *dtos.PageReq, id string) (*dtos.PostListPersonalResp, *errs.ErrorResp) {
+
+	paging, errResp := toPaging(req)
+	if errResp != nil {
+		return nil, errResp
+	}
 
-	posts, total, err := s.r.GetAllUsersPosts(page, perPage, id)
+	posts, info, err := s.r.GetAllUsersPosts(paging, id)
 	if err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
 			return nil, errs.NewError(http.StatusNotFound, "你找的啥啊？", nil)
@@ -82,15 +129,22 @@ func (s *Service) GetPostsOfUser(page, perPage int64, id string) (*dtos.PostList
 
 	// 转换为响应用的post列表格式
 	postList := dtos.ToPostList(posts)
+	s.markBookmarked(id, postList)
 
 	// 进行实质的响应构造
-	postListResp := dtos.NewPostListPersonal(postList, total, page)
+	postListResp := dtos.NewPostListPersonal(postList, info.Total, paging.Page)
+	postListResp.CursorInfo = dtos.NewCursorInfo(info)
 
 	return postListResp, nil
 }
 
-func (s *Service) GetDraftsOfUser(page, perPage int64, id string) (*dtos.DraftsResp, *errs.ErrorResp) {
-	drafts, total, err := s.r.GetAllUsersDrafts(page, perPage, id)
+func (s *Service) GetDraftsOfUser(req *dtos.PageReq, id string) (*dtos.DraftsResp, *errs.ErrorResp) {
+	paging, errResp := toPaging(req)
+	if errResp != nil {
+		return nil, errResp
+	}
+
+	drafts, info, err := s.r.GetAllUsersDrafts(paging, id)
 	if err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
 			return nil, errs.NewError(http.StatusNotFound, "你找的啥啊？", nil)
@@ -103,7 +157,8 @@ func (s *Service) GetDraftsOfUser(page, perPage int64, id string) (*dtos.DraftsR
 	draftList := dtos.ToDraftList(drafts)
 
 	// 进行实质的响应构造
-	draftsResp := dtos.NewDraftsPersonal(draftList, total, page)
+	draftsResp := dtos.NewDraftsPersonal(draftList, info.Total, paging.Page)
+	draftsResp.CursorInfo = dtos.NewCursorInfo(info)
 
 	return draftsResp, nil
 }
@@ -121,10 +176,7 @@ func (s *Service) GetSeries(id string) (*dtos.SeriesResp, *errs.ErrorResp) {
 
 	series := make([]dtos.SeriesItem, len(categories))
 	for i := range categories {
-		series[i].ID = categories[i].ID
-		series[i].Name = categories[i].Name
-		// 构造基础post列表
-		series[i].Posts = dtos.ToPostsBasic(categories[i].Posts)
+		series[i] = dtos.ToSeriesItem(&categories[i])
 	}
 
 	return &dtos.SeriesResp{
@@ -140,6 +192,10 @@ func (s *Service) PublishArticle(req *dtos.ArticleReq, userID string) (int, *err
 		return post_id, nil
 	}
 
+	if errResp := s.checkVerified(userID, ActionPublish); errResp != nil {
+		return post_id, errResp
+	}
+
 	// 参数的深层次校验，后续看看有么有需要
 
 	err := s.r.Transaction(func(tx *gorm.DB) error {
@@ -166,9 +222,10 @@ func (s *Service) PublishArticle(req *dtos.ArticleReq, userID string) (int, *err
 			return err
 		} else {
 			// draft
-			post, err = s.r.Draft2Post(draft, tx)
-			if err != nil {
-				log.Printf("draft转换post中出错：%s\n", err.Error())
+			if errResp := checkOwnership(draft, nil, userID, "草稿"); errResp != nil {
+				return deny(errResp)
+			}
+			if post, err = s.publishDraft(tx, draft); err != nil {
 				return err
 			}
 		}
@@ -188,17 +245,44 @@ func (s *Service) PublishArticle(req *dtos.ArticleReq, userID string) (int, *err
 			}
 		}
 
+		// 每次发布都留一个版本
+		if _, err = s.r.CreateRevision(tx, post, userID); err != nil {
+			log.Printf("记录文章版本出错：%s\n", err.Error())
+			return err
+		}
+
 		post_id = int(post.ID)
 		return nil
 	})
 
 	if err != nil {
+		if errResp, ok := asDenied(err); ok {
+			return post_id, errResp
+		}
 		return post_id, errs.NewError(http.StatusInternalServerError, "", err)
 	}
 
 	return post_id, nil
 }
 
+// 草稿转为文章，已发布文章的编辑稿会原地更新
+func (s *Service) publishDraft(tx *gorm.DB, draft *models.Draft) (*models.Post, error) {
+	if draft.PostID != nil {
+		if err := s.r.EnsureBaseRevision(tx, *draft.PostID); err != nil {
+			log.Printf("补录初始版本出错：%s\n", err.Error())
+			return nil, err
+		}
+	}
+
+	post, err := s.r.Draft2Post(draft, tx)
+	if err != nil {
+		log.Printf("draft转换post中出错：%s\n", err.Error())
+		return nil, err
+	}
+
+	return post, nil
+}
+
 func (s *Service) SaveDraft(req *dtos.ArticleReq, userID string) (int, *errs.ErrorResp) {
 
 	draft_id := -1
@@ -210,49 +294,9 @@ func (s *Service) SaveDraft(req *dtos.ArticleReq, userID string) (int, *errs.Err
 	// 参数的深层次校验，后续看看有么有需要
 
 	err := s.r.Transaction(func(tx *gorm.DB) error {
-		draft, err := s.r.GetDraft(req.Id, tx)
-		if draft == nil && errors.Is(err, gorm.ErrRecordNotFound) {
-			// 创建draft
-			draft = &models.Draft{
-				Title:     req.Title,
-				Excerpt:   req.Excerpt,
-				Content:   req.Content,
-				Cover:     req.Cover,
-				UserID:    userID,
-				CreatedAt: time.Now(),
-			}
-
-			if err = tx.Create(draft).Error; err != nil {
-				tx.Rollback()
-				log.Printf("保存草稿过程中创建draft实例出错：%s\n", err.Error())
-				return err
-			}
-		} else if err != nil {
-			log.Printf("查询对应草稿的其他错误：%s\n", err.Error())
+		draft, err := s.saveDraft(tx, req, userID)
+		if err != nil {
 			return err
-		} else {
-			// draft 字段更新
-			draft.Title = req.Title
-			draft.Excerpt = req.Excerpt
-			draft.Content = req.Content
-			draft.Cover = req.Cover
-		}
-
-		// 更新category
-		if req.Category != "" {
-			category_n := strings.ToLower(req.Category)
-			if err = s.r.UpdateCategory(tx, draft, category_n); err != nil {
-				log.Printf("更新category出错：%s\n", err.Error())
-				return err
-			}
-		}
-
-		// 更新tags
-		if len(req.Tags) != 0 {
-			toAdd, toRemove := s.compareTags(draft.GetTagsName(), req.Tags)
-			if err = s.r.UpdateTags(tx, draft, toAdd, toRemove); err != nil {
-				return err
-			}
 		}
 
 		draft_id = int(draft.ID)
@@ -260,6 +304,9 @@ func (s *Service) SaveDraft(req *dtos.ArticleReq, userID string) (int, *errs.Err
 	})
 
 	if err != nil {
+		if errResp, ok := asDenied(err); ok {
+			return draft_id, errResp
+		}
 		log.Printf("功亏一篑，提交出错：%s\n", err.Error())
 		return draft_id, errs.NewError(http.StatusInternalServerError, "", err)
 	}
@@ -267,54 +314,168 @@ func (s *Service) SaveDraft(req *dtos.ArticleReq, userID string) (int, *errs.Err
 	return draft_id, nil
 }
 
-func (s *Service) DeletePost(post_id uint) *errs.ErrorResp {
+// 新建或整篇更新草稿，连同分类和标签
+func (s *Service) saveDraft(tx *gorm.DB, req *dtos.ArticleReq, userID string) (*models.Draft, error) {
+	draft, err := s.r.GetDraft(req.Id, tx)
+	if draft == nil && errors.Is(err, gorm.ErrRecordNotFound) {
+		// 创建draft
+		draft = &models.Draft{
+			Title:     req.Title,
+			Excerpt:   req.Excerpt,
+			Content:   req.Content,
+			Cover:     req.Cover,
+			UserID:    userID,
+			CreatedAt: time.Now(),
+		}
 
-	err := s.r.Transaction(func(tx *gorm.DB) error {
-		post, err := s.r.GetPost(post_id, tx)
+		if err = tx.Create(draft).Error; err != nil {
+			log.Printf("保存草稿过程中创建draft实例出错：%s\n", err.Error())
+			return nil, err
+		}
+	} else if err != nil {
+		log.Printf("查询对应草稿的其他错误：%s\n", err.Error())
+		return nil, err
+	} else {
+		if errResp := checkOwnership(draft, nil, userID, "草稿"); errResp != nil {
+			return nil, deny(errResp)
+		}
+
+		// draft 字段更新
+		draft.Title = req.Title
+		draft.Excerpt = req.Excerpt
+		draft.Content = req.Content
+		draft.Cover = req.Cover
+		_, err = s.r.UpdateDraftVersioned(tx, draft.ID, 0, map[string]interface{}{
+			"title":   draft.Title,
+			"excerpt": draft.Excerpt,
+			"content": draft.Content,
+			"cover":   draft.Cover,
+		})
 		if err != nil {
-			log.Printf("查询要删除的post出错：%s\n", err.Error())
-			return err
+			log.Printf("更新草稿出错：%s\n", err.Error())
+			return nil, err
+		}
+	}
+
+	// 更新category
+	if req.Category != "" {
+		category_n := strings.ToLower(req.Category)
+		if err = s.r.UpdateCategory(tx, draft, category_n); err != nil {
+			log.Printf("更新category出错：%s\n", err.Error())
+			return nil, err
+		}
+	}
+
+	// 更新tags
+	if len(req.Tags) != 0 {
+		toAdd, toRemove := s.compareTags(draft.GetTagsName(), req.Tags)
+		if err = s.r.UpdateTags(tx, draft, toAdd, toRemove); err != nil {
+			return nil, err
+		}
+	}
+
+	return draft, nil
+}
+
+func (s *Service) DeletePost(post_id uint, userID string) *errs.ErrorResp {
+
+	err := s.r.Transaction(func(tx *gorm.DB) error {
+		post, errResp := s.ownPost(post_id, userID, tx)
+		if errResp != nil {
+			return deny(errResp)
 		}
 
-		err = s.r.DeleteArticle(post, tx)
+		err := s.r.DeleteArticle(post, tx)
 		if err != nil {
-			tx.Rollback()
 			log.Printf("删除%s出错：%s\n", post.Title, err.Error())
 		}
-		return nil
+		return err
 	})
 
 	if err != nil {
+		if errResp, ok := asDenied(err); ok {
+			return errResp
+		}
 		return errs.NewError(http.StatusInternalServerError, "", err)
 	}
 
 	return nil
 }
 
-func (s *Service) DeleteDraft(draft_id uint) *errs.ErrorResp {
+func (s *Service) DeleteDraft(draft_id uint, userID string) *errs.ErrorResp {
 
 	err := s.r.Transaction(func(tx *gorm.DB) error {
-		draft, err := s.r.GetDraft(draft_id, tx)
-		if err != nil {
-			log.Printf("查询要删除的draft出错：%s\n", err.Error())
-			return err
+		draft, errResp := s.ownDraft(draft_id, userID, tx)
+		if errResp != nil {
+			return deny(errResp)
 		}
 
-		err = s.r.DeleteArticle(draft, tx)
+		err := s.r.DeleteArticle(draft, tx)
 		if err != nil {
-			tx.Rollback()
 			log.Printf("删除%s出错：%s\n", draft.Title, err.Error())
 		}
-		return nil
+		return err
 	})
 
 	if err != nil {
+		if errResp, ok := asDenied(err); ok {
+			return errResp
+		}
 		return errs.NewError(http.StatusInternalServerError, "", err)
 	}
 
 	return nil
 }
 
+// 分页请求转换为dao层的分页参数
+func toPaging(req *dtos.PageReq) (*dao.Paging, *errs.ErrorResp) {
+	page, perPage := normalizePage(req.Page, req.PerPage)
+	paging := &dao.Paging{
+		Page:    page,
+		PerPage: perPage,
+	}
+
+	if req.Cursor != "" {
+		cursor, err := dao.DecodeCursor(req.Cursor)
+		if err != nil {
+			return nil, errs.NewError(http.StatusBadRequest, err.Error(), nil)
+		}
+		paging.Cursor = cursor
+	}
+
+	return paging, nil
+}
+
+// 分页参数兜底，避免非法值和一次取过多数据
+func normalizePage(page, perPage int64) (int64, int64) {
+	if page < 1 {
+		page = 1
+	}
+	if perPage < 1 {
+		perPage = 10
+	}
+	if perPage > 100 {
+		perPage = 100
+	}
+	return page, perPage
+}
+
+// 解析日期参数，只给出日期时作为截止日期要包含当天
+func parseDate(val string, isEnd bool) (time.Time, bool) {
+	if t, err := time.Parse(time.RFC3339, val); err == nil {
+		return t, true
+	}
+
+	t, err := time.ParseInLocation(time.DateOnly, val, time.Local)
+	if err != nil {
+		return time.Time{}, false
+	}
+	if isEnd {
+		t = t.AddDate(0, 0, 1)
+	}
+	return t, true
+}
+
 func (s *Service) compareTags(currentTags, newTags []string) (toAdd, toRemove []string) {
 	currentNames := make(map[string]bool)
 	newNames := make(map[string]bool)
@@ -0,0 +1,111 @@
+package service
+
+import (
+	"errors"
+	"log"
+	"net/http"
+
+	"github.com/Jack-samu/the-blog-backend-gin.git/internal/errs"
+	"github.com/Jack-samu/the-blog-backend-gin.git/internal/models"
+	"gorm.io/gorm"
+)
+
+// 有归属的资源，models.Article、系列和评论都满足
+type ownedResource interface {
+	GetUserID() string
+}
+
+// 事务内校验失败时返回，携带最终要给到客户端的错误
+type deniedError struct {
+	resp *errs.ErrorResp
+}
+
+func (e *deniedError) Error() string {
+	return e.resp.Msg
+}
+
+func deny(errResp *errs.ErrorResp) error {
+	return &deniedError{resp: errResp}
+}
+
+// 从事务返回的错误中取出校验失败的响应
+func asDenied(err error) (*errs.ErrorResp, bool) {
+	var denied *deniedError
+	if errors.As(err, &denied) {
+		return denied.resp, true
+	}
+	return nil, false
+}
+
+// 统一的归属校验：查询不到返回404，不是本人的返回403
+func checkOwnership(res ownedResource, err error, userID, name string) *errs.ErrorResp {
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return errs.NewError(http.StatusNotFound, name+"不存在", nil)
+		}
+		log.Printf("查询%s出错：%s\n", name, err.Error())
+		return errs.NewError(http.StatusInternalServerError, "", err)
+	}
+
+	if userID == "" || res.GetUserID() != userID {
+		return errs.NewError(http.StatusForbidden, "无权操作别人的"+name, nil)
+	}
+
+	return nil
+}
+
+// 评论和回复的校验，版主和管理员可以操作别人的
+func checkModeration(res ownedResource, err error, userID, role, name string) *errs.ErrorResp {
+	if err == nil && models.CanModerate(role) {
+		return nil
+	}
+	return checkOwnership(res, err, userID, name)
+}
+
+func (s *Service) ownPost(id uint, userID string, tx *gorm.DB) (*models.Post, *errs.ErrorResp) {
+	post, err := s.r.GetPost(id, tx)
+	if errResp := checkOwnership(post, err, userID, "文章"); errResp != nil {
+		return nil, errResp
+	}
+	return post, nil
+}
+
+func (s *Service) ownDraft(id uint, userID string, tx *gorm.DB) (*models.Draft, *errs.ErrorResp) {
+	draft, err := s.r.GetDraft(id, tx)
+	if errResp := checkOwnership(draft, err, userID, "草稿"); errResp != nil {
+		return nil, errResp
+	}
+	return draft, nil
+}
+
+func (s *Service) ownSeries(id uint, userID string, tx *gorm.DB) (*models.Category, *errs.ErrorResp) {
+	category, err := s.r.GetCategory(id, tx)
+	if errResp := checkOwnership(category, err, userID, "系列"); errResp != nil {
+		return nil, errResp
+	}
+	return category, nil
+}
+
+func (s *Service) ownReadingList(id uint, userID string, tx *gorm.DB) (*models.ReadingList, *errs.ErrorResp) {
+	list, err := s.r.GetReadingList(id, tx)
+	if errResp := checkOwnership(list, err, userID, "阅读列表"); errResp != nil {
+		return nil, errResp
+	}
+	return list, nil
+}
+
+func (s *Service) ownComment(id int64, userID, role string, tx *gorm.DB) (*models.Comment, *errs.ErrorResp) {
+	comment, err := s.r.GetComment(id, tx)
+	if errResp := checkModeration(comment, err, userID, role, "评论"); errResp != nil {
+		return nil, errResp
+	}
+	return comment, nil
+}
+
+func (s *Service) ownReply(id int64, userID, role string, tx *gorm.DB) (*models.Reply, *errs.ErrorResp) {
+	reply, err := s.r.GetReply(id, tx)
+	if errResp := checkModeration(reply, err, userID, role, "回复"); errResp != nil {
+		return nil, errResp
+	}
+	return reply, nil
+}
@@ -0,0 +1,318 @@
+package service
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/Jack-samu/the-blog-backend-gin.git/internal/dtos"
+	"github.com/Jack-samu/the-blog-backend-gin.git/internal/errs"
+	"github.com/Jack-samu/the-blog-backend-gin.git/internal/models"
+	"github.com/Jack-samu/the-blog-backend-gin.git/internal/utils"
+	"gorm.io/gorm"
+)
+
+// 公开阅读列表的分享链接前缀
+func readingListShareBase() string {
+	return publicBaseURL() + "/shared/reading-lists/"
+}
+
+// 给文章列表标上当前用户的收藏状态，查询出错时只记日志，不影响列表本身
+func (s *Service) markBookmarked(viewerID string, posts []dtos.PostListItem) {
+	if viewerID == "" || len(posts) == 0 {
+		return
+	}
+
+	ids := make([]uint, len(posts))
+	for i := range posts {
+		ids[i] = posts[i].Id
+	}
+
+	marked, err := s.r.BookmarkedPostIDs(viewerID, ids)
+	if err != nil {
+		log.Printf("收藏状态查询出错：%s\n", err.Error())
+		return
+	}
+
+	for i := range posts {
+		posts[i].Bookmarked = marked[posts[i].Id]
+	}
+}
+
+// 收藏或取消收藏，重复操作不报错
+func (s *Service) Bookmark(userID string, postID uint, bookmark bool) (*dtos.BookmarkResp, *errs.ErrorResp) {
+	if postID == 0 {
+		return nil, errs.NewError(http.StatusBadRequest, "参数错误", nil)
+	}
+
+	exists, err := s.r.LikeTargetExists(nil, models.LikeTargetPost, postID)
+	if err != nil {
+		log.Printf("文章查询出错：%s\n", err.Error())
+		return nil, errs.NewError(http.StatusInternalServerError, "", err)
+	}
+	if !exists {
+		return nil, errs.NewError(http.StatusNotFound, "文章不存在", nil)
+	}
+
+	if bookmark {
+		_, err = s.r.CreateBookmark(userID, postID)
+	} else {
+		_, err = s.r.DeleteBookmark(userID, postID)
+	}
+	if err != nil {
+		log.Printf("收藏状态修改出错：%s\n", err.Error())
+		return nil, errs.NewError(http.StatusInternalServerError, "", err)
+	}
+
+	return &dtos.BookmarkResp{Bookmarked: bookmark}, nil
+}
+
+// 收藏的文章，最近收藏的在前
+func (s *Service) GetBookmarks(userID string, req *dtos.PageReq) (*dtos.PostListResp, *errs.ErrorResp) {
+	paging, errResp := toPaging(req)
+	if errResp != nil {
+		return nil, errResp
+	}
+
+	bookmarks, info, err := s.r.GetBookmarks(paging, userID)
+	if err != nil {
+		log.Printf("收藏列表查询出错：%s\n", err.Error())
+		return nil, errs.NewError(http.StatusInternalServerError, "", err)
+	}
+
+	postList := make([]dtos.PostListItem, 0, len(bookmarks))
+	for _, bookmark := range bookmarks {
+		if bookmark.Post == nil {
+			continue
+		}
+		item := dtos.ToPostListItem(bookmark.Post)
+		item.Bookmarked = true
+		postList = append(postList, item)
+	}
+
+	resp := dtos.NewPostList(postList, info.Total, paging.Page)
+	resp.CursorInfo = dtos.NewCursorInfo(info)
+	return resp, nil
+}
+
+func (s *Service) GetReadingLists(userID string) (*dtos.ReadingListsResp, *errs.ErrorResp) {
+	lists, err := s.r.GetUserReadingLists(userID)
+	if err != nil {
+		log.Printf("阅读列表查询出错：%s\n", err.Error())
+		return nil, errs.NewError(http.StatusInternalServerError, "", err)
+	}
+
+	return dtos.ToReadingListsResp(lists, readingListShareBase()), nil
+}
+
+// 阅读列表详情，只有本人能看；公开的列表通过分享链接访问
+func (s *Service) GetReadingList(id uint, userID string) (*dtos.ReadingListDetailResp, *errs.ErrorResp) {
+	list, errResp := s.ownReadingList(id, userID, nil)
+	if errResp != nil {
+		return nil, errResp
+	}
+
+	return s.readingListDetail(list, userID), nil
+}
+
+// 通过分享链接查看公开的阅读列表，未公开或者作者被封禁时视为不存在
+func (s *Service) GetSharedReadingList(token, viewerID string) (*dtos.ReadingListDetailResp, *errs.ErrorResp) {
+	list, err := s.r.GetReadingListByToken(token)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errs.NewError(http.StatusNotFound, "阅读列表不存在", nil)
+		}
+		log.Printf("阅读列表查询出错：%s\n", err.Error())
+		return nil, errs.NewError(http.StatusInternalServerError, "", err)
+	}
+	if !list.Public || (list.User != nil && list.User.SuspendedAt != nil) {
+		return nil, errs.NewError(http.StatusNotFound, "阅读列表不存在", nil)
+	}
+
+	return s.readingListDetail(list, viewerID), nil
+}
+
+func (s *Service) readingListDetail(list *models.ReadingList, viewerID string) *dtos.ReadingListDetailResp {
+	resp := dtos.ToReadingListDetail(list, readingListShareBase())
+	s.markBookmarked(viewerID, resp.Posts)
+	return resp
+}
+
+// 公开时生成分享token，取消公开时作废，已经公开的保留原链接
+func (s *Service) shareToken(list *models.ReadingList, public bool) (*string, error) {
+	if !public {
+		return nil, nil
+	}
+	if list != nil && list.Public && list.ShareToken != nil {
+		return list.ShareToken, nil
+	}
+
+	token, err := utils.RandomString(24)
+	if err != nil {
+		return nil, err
+	}
+	return &token, nil
+}
+
+func (s *Service) CreateReadingList(req *dtos.ReadingListReq, userID string) (*dtos.ReadingListDetailResp, *errs.ErrorResp) {
+	name := strings.TrimSpace(req.Name)
+	if name == "" {
+		return nil, errs.NewError(http.StatusBadRequest, "列表名不能为空", nil)
+	}
+
+	token, err := s.shareToken(nil, req.Public)
+	if err != nil {
+		log.Printf("分享链接生成出错：%s\n", err.Error())
+		return nil, errs.NewError(http.StatusInternalServerError, "", err)
+	}
+
+	list := &models.ReadingList{
+		UserID:      userID,
+		Name:        name,
+		Description: strings.TrimSpace(req.Description),
+		Public:      req.Public,
+		ShareToken:  token,
+	}
+	if err = s.r.CreateReadingList(list); err != nil {
+		log.Printf("阅读列表创建出错：%s\n", err.Error())
+		return nil, errs.NewError(http.StatusInternalServerError, "", err)
+	}
+
+	return s.GetReadingList(list.ID, userID)
+}
+
+func (s *Service) UpdateReadingList(id uint, req *dtos.ReadingListUpdateReq, userID string) (*dtos.ReadingListDetailResp, *errs.ErrorResp) {
+	list, errResp := s.ownReadingList(id, userID, nil)
+	if errResp != nil {
+		return nil, errResp
+	}
+
+	fields := make(map[string]interface{})
+	if req.Name != nil {
+		name := strings.TrimSpace(*req.Name)
+		if name == "" {
+			return nil, errs.NewError(http.StatusBadRequest, "列表名不能为空", nil)
+		}
+		fields["name"] = name
+	}
+	if req.Description != nil {
+		fields["description"] = strings.TrimSpace(*req.Description)
+	}
+	if req.Public != nil {
+		token, err := s.shareToken(list, *req.Public)
+		if err != nil {
+			log.Printf("分享链接生成出错：%s\n", err.Error())
+			return nil, errs.NewError(http.StatusInternalServerError, "", err)
+		}
+		fields["public"] = *req.Public
+		fields["share_token"] = token
+	}
+
+	if len(fields) > 0 {
+		if err := s.r.UpdateReadingList(list, fields); err != nil {
+			log.Printf("阅读列表更新出错：%s\n", err.Error())
+			return nil, errs.NewError(http.StatusInternalServerError, "", err)
+		}
+	}
+
+	return s.GetReadingList(list.ID, userID)
+}
+
+func (s *Service) DeleteReadingList(id uint, userID string) *errs.ErrorResp {
+	list, errResp := s.ownReadingList(id, userID, nil)
+	if errResp != nil {
+		return errResp
+	}
+
+	err := s.r.Transaction(func(tx *gorm.DB) error {
+		return s.r.DeleteReadingList(tx, list)
+	})
+	if err != nil {
+		log.Printf("阅读列表删除出错：%s\n", err.Error())
+		return errs.NewError(http.StatusInternalServerError, "", err)
+	}
+
+	return nil
+}
+
+// 把文章加到列表末尾，已在列表中时不重复添加
+func (s *Service) AddReadingListItem(id uint, req *dtos.ReadingListItemReq, userID string) (*dtos.ReadingListDetailResp, *errs.ErrorResp) {
+	list, errResp := s.ownReadingList(id, userID, nil)
+	if errResp != nil {
+		return nil, errResp
+	}
+
+	err := s.r.Transaction(func(tx *gorm.DB) error {
+		exists, err := s.r.LikeTargetExists(tx, models.LikeTargetPost, req.ArticleID)
+		if err != nil {
+			return err
+		}
+		if !exists {
+			return deny(errs.NewError(http.StatusNotFound, "文章不存在", nil))
+		}
+
+		_, err = s.r.AddReadingListItem(tx, list.ID, req.ArticleID)
+		return err
+	})
+	if err != nil {
+		if errResp, ok := asDenied(err); ok {
+			return nil, errResp
+		}
+		log.Printf("阅读列表添加文章出错：%s\n", err.Error())
+		return nil, errs.NewError(http.StatusInternalServerError, "", err)
+	}
+
+	return s.GetReadingList(list.ID, userID)
+}
+
+func (s *Service) RemoveReadingListItem(id, postID uint, userID string) (*dtos.ReadingListDetailResp, *errs.ErrorResp) {
+	list, errResp := s.ownReadingList(id, userID, nil)
+	if errResp != nil {
+		return nil, errResp
+	}
+
+	removed, err := s.r.RemoveReadingListItem(list.ID, postID)
+	if err != nil {
+		log.Printf("阅读列表移除文章出错：%s\n", err.Error())
+		return nil, errs.NewError(http.StatusInternalServerError, "", err)
+	}
+	if !removed {
+		return nil, errs.NewError(http.StatusNotFound, "文章不在该列表中", nil)
+	}
+
+	return s.GetReadingList(list.ID, userID)
+}
+
+// 调整列表内文章顺序，只接受已在列表中的文章
+func (s *Service) ReorderReadingList(id uint, req *dtos.ReadingListOrderReq, userID string) (*dtos.ReadingListDetailResp, *errs.ErrorResp) {
+	list, errResp := s.ownReadingList(id, userID, nil)
+	if errResp != nil {
+		return nil, errResp
+	}
+
+	inList := make(map[uint]bool, len(list.Items))
+	for _, item := range list.Items {
+		inList[item.PostID] = true
+	}
+	seen := make(map[uint]bool, len(req.ArticleIDs))
+	for _, postID := range req.ArticleIDs {
+		if !inList[postID] {
+			return nil, errs.NewError(http.StatusBadRequest, "文章不在该列表中", nil)
+		}
+		if seen[postID] {
+			return nil, errs.NewError(http.StatusBadRequest, "文章id重复", nil)
+		}
+		seen[postID] = true
+	}
+
+	err := s.r.Transaction(func(tx *gorm.DB) error {
+		return s.r.ReorderReadingList(tx, list, req.ArticleIDs)
+	})
+	if err != nil {
+		log.Printf("阅读列表排序出错：%s\n", err.Error())
+		return nil, errs.NewError(http.StatusInternalServerError, "", err)
+	}
+
+	return s.GetReadingList(list.ID, userID)
+}
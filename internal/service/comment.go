@@ -11,14 +11,18 @@ import (
 	"gorm.io/gorm"
 )
 
-// 只加载基础评论
-func (s *Service) GetComments(id int64) (*dtos.CommentsResp, *errs.ErrorResp) {
-	// 后续添加触底刷新
+// 只加载基础评论，通过游标实现触底刷新
+func (s *Service) GetComments(id int64, req *dtos.PageReq, viewerID string) (*dtos.CommentsResp, *errs.ErrorResp) {
 	if id == -1 {
 		return nil, errs.NewError(http.StatusBadRequest, "参数错误", nil)
 	}
 
-	comments, err := s.r.GetComments(id)
+	paging, errResp := toPaging(req)
+	if errResp != nil {
+		return nil, errResp
+	}
+
+	comments, info, err := s.r.GetComments(id, paging)
 	if err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
 			log.Println("没找到对应文章，也就无法查询comments了")
@@ -28,17 +32,24 @@ func (s *Service) GetComments(id int64) (*dtos.CommentsResp, *errs.ErrorResp) {
 		return nil, errs.NewError(http.StatusInternalServerError, "", err)
 	}
 
-	commentsResp := dtos.ToCommentsResp(comments, s.r)
+	commentsResp := dtos.ToCommentsResp(comments, viewerID, s.r)
+	commentsResp.Total = int(info.Total)
+	commentsResp.CursorInfo = dtos.NewCursorInfo(info)
 	return commentsResp, nil
 }
 
 // 由前台点击触发二级评论加载
-func (s *Service) GetReplies(id int64) (*dtos.RepliesResp, *errs.ErrorResp) {
+func (s *Service) GetReplies(id int64, req *dtos.PageReq, viewerID string) (*dtos.RepliesResp, *errs.ErrorResp) {
 	if id == -1 {
 		return nil, errs.NewError(http.StatusBadRequest, "参数错误", nil)
 	}
 
-	replies, err := s.r.GetReplies(id)
+	paging, errResp := toPaging(req)
+	if errResp != nil {
+		return nil, errResp
+	}
+
+	replies, info, err := s.r.GetReplies(id, paging)
 	if err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
 			log.Println("没找到对应comment，也就无法查询comments了")
@@ -48,7 +59,9 @@ func (s *Service) GetReplies(id int64) (*dtos.RepliesResp, *errs.ErrorResp) {
 		return nil, errs.NewError(http.StatusInternalServerError, "", err)
 	}
 
-	repliesResp := dtos.ToRepliesResp(replies, s.r)
+	repliesResp := dtos.ToRepliesResp(replies, viewerID, s.r)
+	repliesResp.Total = int(info.Total)
+	repliesResp.CursorInfo = dtos.NewCursorInfo(info)
 	return repliesResp, nil
 }
 
@@ -57,6 +70,10 @@ func (s *Service) CreateComment(req *dtos.CommentReq, userID string) (*dtos.Comm
 		return nil, errs.NewError(http.StatusBadRequest, "无效参数", nil)
 	}
 
+	if errResp := s.checkVerified(userID, ActionComment); errResp != nil {
+		return nil, errResp
+	}
+
 	var comment *models.Comment
 	var user *models.User
 
@@ -95,7 +112,7 @@ func (s *Service) CreateComment(req *dtos.CommentReq, userID string) (*dtos.Comm
 	}
 
 	return &dtos.CommentResp{
-		Comment: dtos.ToCommentItem(comment, user, nil),
+		Comment: dtos.ToCommentItem(comment, user, userID, nil),
 	}, nil
 }
 
@@ -104,6 +121,10 @@ func (s *Service) CreateReply(req *dtos.CommentReq, userID string) (*dtos.ReplyR
 		return nil, errs.NewError(http.StatusBadRequest, "无效参数", nil)
 	}
 
+	if errResp := s.checkVerified(userID, ActionComment); errResp != nil {
+		return nil, errResp
+	}
+
 	var reply *models.Reply
 	var user *models.User
 
@@ -145,11 +166,12 @@ func (s *Service) CreateReply(req *dtos.CommentReq, userID string) (*dtos.ReplyR
 	}
 
 	return &dtos.ReplyResp{
-		Reply: dtos.ToReplyItem(reply, user, nil),
+		Reply: dtos.ToReplyItem(reply, user, userID, nil),
 	}, nil
 }
 
-func (s *Service) ModifyComment(req *dtos.CommentReq, userID string) (*dtos.CommentResp, *errs.ErrorResp) {
+// role为版主或管理员时可以修改别人的评论
+func (s *Service) ModifyComment(req *dtos.CommentReq, userID, role string) (*dtos.CommentResp, *errs.ErrorResp) {
 	if req.CommentID == 0 {
 		return nil, errs.NewError(http.StatusBadRequest, "参数无效", nil)
 	}
@@ -161,39 +183,34 @@ func (s *Service) ModifyComment(req *dtos.CommentReq, userID string) (*dtos.Comm
 	var comment *models.Comment
 
 	err := s.r.Transaction(func(tx *gorm.DB) error {
-		err := tx.Model(&models.Comment{}).Where("id = ?", req.CommentID).First(&comment).Error
-		if err != nil {
-			log.Printf("查询出错：%s\n", err.Error())
-			return gorm.ErrRecordNotFound
-		}
-
-		if comment.UserID != userID {
-			return errors.New("无权操作")
+		var errResp *errs.ErrorResp
+		if comment, errResp = s.ownComment(req.CommentID, userID, role, tx); errResp != nil {
+			return deny(errResp)
 		}
 
 		if comment.Content == req.Content {
 			return errors.New("没有改动")
 		}
 
-		err = tx.Model(&comment).Update("content", req.Content).Error
+		err := tx.Model(comment).Update("content", req.Content).Error
 
 		return err
 	})
 
 	if err != nil {
 		// 详细错误鉴别并回应
-		if errors.Is(err, gorm.ErrRecordNotFound) {
-			return nil, errs.NewError(http.StatusInternalServerError, "没找到要修改的评论", nil)
+		if errResp, ok := asDenied(err); ok {
+			return nil, errResp
 		}
 		return nil, errs.NewError(http.StatusInternalServerError, "", err)
 	}
 
 	return &dtos.CommentResp{
-		Comment: dtos.ToCommentItem(comment, nil, s.r),
+		Comment: dtos.ToCommentItem(comment, nil, userID, s.r),
 	}, nil
 }
 
-func (s *Service) ModifyReply(req *dtos.CommentReq, userID string) (*dtos.ReplyResp, *errs.ErrorResp) {
+func (s *Service) ModifyReply(req *dtos.CommentReq, userID, role string) (*dtos.ReplyResp, *errs.ErrorResp) {
 	if req.ReplyID == 0 {
 		return nil, errs.NewError(http.StatusBadRequest, "参数无效", nil)
 	}
@@ -205,60 +222,47 @@ func (s *Service) ModifyReply(req *dtos.CommentReq, userID string) (*dtos.ReplyR
 	var reply *models.Reply
 
 	err := s.r.Transaction(func(tx *gorm.DB) error {
-		err := tx.Model(&models.Reply{}).Where("id = ?", req.ReplyID).First(&reply).Error
-		if err != nil {
-			log.Printf("查询出错：%s\n", err.Error())
-			return gorm.ErrRecordNotFound
-		}
-
-		if reply.UserID != userID {
-			return errors.New("无权操作")
+		var errResp *errs.ErrorResp
+		if reply, errResp = s.ownReply(int64(req.ReplyID), userID, role, tx); errResp != nil {
+			return deny(errResp)
 		}
 
 		if reply.Content == req.Content {
 			return errors.New("没有改动")
 		}
 
-		err = tx.Model(&reply).Update("content", req.Content).Error
+		err := tx.Model(reply).Update("content", req.Content).Error
 
 		return err
 	})
 
 	if err != nil {
 		// 详细错误鉴别并回应
-		if errors.Is(err, gorm.ErrRecordNotFound) {
-			return nil, errs.NewError(http.StatusInternalServerError, "没找到要修改的评论", nil)
+		if errResp, ok := asDenied(err); ok {
+			return nil, errResp
 		}
 		return nil, errs.NewError(http.StatusInternalServerError, "", err)
 	}
 
 	return &dtos.ReplyResp{
-		Reply: dtos.ToReplyItem(reply, nil, s.r),
+		Reply: dtos.ToReplyItem(reply, nil, userID, s.r),
 	}, nil
 }
 
-func (s *Service) DeleteComment(id int64, userID string) *errs.ErrorResp {
+func (s *Service) DeleteComment(id int64, userID, role string) *errs.ErrorResp {
 
 	err := s.r.Transaction(func(tx *gorm.DB) error {
-		var comment models.Comment
-		err := tx.Model(&models.Comment{}).
-			Preload("User").
-			Where("id = ?", id).First(&comment).Error
-		if errors.Is(err, gorm.ErrRecordNotFound) {
-			return gorm.ErrRecordNotFound
-		}
-
-		if comment.User.ID != userID {
-			return errors.New("用户无权删除别人的评论")
+		comment, errResp := s.ownComment(id, userID, role, tx)
+		if errResp != nil {
+			return deny(errResp)
 		}
 
-		err = tx.Delete(&comment).Error
-		return err
+		return s.r.DeleteComment(tx, comment)
 	})
 
 	if err != nil {
-		if errors.Is(err, gorm.ErrRecordNotFound) {
-			return errs.NewError(http.StatusNotFound, "要删除的comment没找到", nil)
+		if errResp, ok := asDenied(err); ok {
+			return errResp
 		}
 		log.Printf("删除过程中报错：%s\n", err.Error())
 		return errs.NewError(http.StatusInternalServerError, "", err)
@@ -267,28 +271,20 @@ func (s *Service) DeleteComment(id int64, userID string) *errs.ErrorResp {
 	return nil
 }
 
-func (s *Service) DeleteReply(id int64, userID string) *errs.ErrorResp {
+func (s *Service) DeleteReply(id int64, userID, role string) *errs.ErrorResp {
 
 	err := s.r.Transaction(func(tx *gorm.DB) error {
-		var reply models.Reply
-		err := tx.Model(&models.Reply{}).
-			Preload("User").
-			Where("id = ?", id).First(&reply).Error
-		if errors.Is(err, gorm.ErrRecordNotFound) {
-			return gorm.ErrRecordNotFound
+		reply, errResp := s.ownReply(id, userID, role, tx)
+		if errResp != nil {
+			return deny(errResp)
 		}
 
-		if reply.User.ID != userID {
-			return errors.New("用户无权删除别人的评论")
-		}
-
-		err = tx.Delete(&reply).Error
-		return err
+		return s.r.DeleteReply(tx, reply)
 	})
 
 	if err != nil {
-		if errors.Is(err, gorm.ErrRecordNotFound) {
-			return errs.NewError(http.StatusNotFound, "要删除的reply没找到", nil)
+		if errResp, ok := asDenied(err); ok {
+			return errResp
 		}
 		log.Printf("删除过程中报错：%s\n", err.Error())
 		return errs.NewError(http.StatusInternalServerError, "", err)
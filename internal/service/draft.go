@@ -0,0 +1,97 @@
+package service
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/Jack-samu/the-blog-backend-gin.git/internal/dtos"
+	"github.com/Jack-samu/the-blog-backend-gin.git/internal/errs"
+	"gorm.io/gorm"
+)
+
+// 草稿已被别处保存过，客户端持有的版本过期
+var errVersionConflict = errors.New("草稿版本冲突")
+
+// 自动保存草稿，只写入传了的字段，版本号不一致时返回409
+func (s *Service) AutosaveDraft(id uint, req *dtos.AutosaveReq, userID string) (*dtos.AutosaveResp, *errs.ErrorResp) {
+	var resp *dtos.AutosaveResp
+
+	err := s.r.Transaction(func(tx *gorm.DB) error {
+		draft, errResp := s.ownDraft(id, userID, tx)
+		if errResp != nil {
+			return deny(errResp)
+		}
+		if draft.Version != req.Version {
+			return errVersionConflict
+		}
+
+		fields := make(map[string]interface{})
+		if req.Title != nil {
+			fields["title"] = *req.Title
+		}
+		if req.Excerpt != nil {
+			fields["excerpt"] = *req.Excerpt
+		}
+		if req.Content != nil {
+			fields["content"] = *req.Content
+		}
+		if req.Cover != nil {
+			fields["cover"] = *req.Cover
+		}
+
+		// 条件更新，防止读取之后被别的请求抢先保存
+		ok, err := s.r.UpdateDraftVersioned(tx, draft.ID, req.Version, fields)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			return errVersionConflict
+		}
+
+		if req.Category != nil {
+			category := strings.ToLower(strings.TrimSpace(*req.Category))
+			if category == "" {
+				err = s.r.ClearDraftCategory(tx, draft.ID)
+			} else {
+				err = s.r.UpdateCategory(tx, draft, category)
+			}
+			if err != nil {
+				log.Printf("更新category出错：%s\n", err.Error())
+				return err
+			}
+		}
+
+		if req.Tags != nil {
+			toAdd, toRemove := s.compareTags(draft.GetTagsName(), *req.Tags)
+			if err = s.r.UpdateTags(tx, draft, toAdd, toRemove); err != nil {
+				return err
+			}
+		}
+
+		saved, err := s.r.GetDraft(draft.ID, tx)
+		if err != nil {
+			return err
+		}
+		resp = &dtos.AutosaveResp{
+			ID:        saved.ID,
+			Version:   saved.Version,
+			UpdatedAt: saved.UpdatedAt.String(),
+		}
+		return nil
+	})
+
+	if err != nil {
+		if errResp, ok := asDenied(err); ok {
+			return nil, errResp
+		}
+		if errors.Is(err, errVersionConflict) {
+			return nil, errs.NewError(http.StatusConflict, "草稿已在别处修改，请刷新后再编辑", nil)
+		}
+		log.Printf("自动保存草稿出错：%s\n", err.Error())
+		return nil, errs.NewError(http.StatusInternalServerError, "", err)
+	}
+
+	return resp, nil
+}
@@ -0,0 +1,113 @@
+package service
+
+import (
+	"errors"
+	"log"
+	"net/http"
+
+	dao "github.com/Jack-samu/the-blog-backend-gin.git/internal/DAO"
+	"github.com/Jack-samu/the-blog-backend-gin.git/internal/dtos"
+	"github.com/Jack-samu/the-blog-backend-gin.git/internal/errs"
+	"github.com/Jack-samu/the-blog-backend-gin.git/internal/models"
+	"gorm.io/gorm"
+)
+
+// 按用户名查找可以关注的作者，被封禁的用户视为不存在
+func (s *Service) followTarget(username string) (*models.User, *errs.ErrorResp) {
+	user, err := s.r.GetUserByName(username)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errs.NewError(http.StatusNotFound, "作者不存在", nil)
+		}
+		log.Printf("用户查询出错：%s\n", err.Error())
+		return nil, errs.NewError(http.StatusInternalServerError, "用户查询出错", err)
+	}
+	if user.SuspendedAt != nil {
+		return nil, errs.NewError(http.StatusNotFound, "作者不存在", nil)
+	}
+	return user, nil
+}
+
+// 关注或取消关注，重复操作不报错
+func (s *Service) Follow(followerID, username string, follow bool) (*dtos.FollowResp, *errs.ErrorResp) {
+	target, errResp := s.followTarget(username)
+	if errResp != nil {
+		return nil, errResp
+	}
+	if target.ID == followerID {
+		return nil, errs.NewError(http.StatusBadRequest, "不能关注自己", nil)
+	}
+
+	var err error
+	if follow {
+		_, err = s.r.CreateFollow(followerID, target.ID)
+	} else {
+		_, err = s.r.DeleteFollow(followerID, target.ID)
+	}
+	if err != nil {
+		log.Printf("关注状态修改出错：%s\n", err.Error())
+		return nil, errs.NewError(http.StatusInternalServerError, "", err)
+	}
+
+	followers, _, err := s.r.CountFollows(target.ID)
+	if err != nil {
+		log.Printf("粉丝数查询出错：%s\n", err.Error())
+		return nil, errs.NewError(http.StatusInternalServerError, "", err)
+	}
+
+	return &dtos.FollowResp{Followed: follow, Followers: followers}, nil
+}
+
+// 作者的粉丝列表，followers为false时返回作者关注的人
+func (s *Service) GetFollows(username string, req *dtos.PageReq, followers bool) (*dtos.FollowListResp, *errs.ErrorResp) {
+	target, errResp := s.followTarget(username)
+	if errResp != nil {
+		return nil, errResp
+	}
+
+	paging, errResp := toPaging(req)
+	if errResp != nil {
+		return nil, errResp
+	}
+
+	var follows []models.Follow
+	var info *dao.PageInfo
+	var err error
+	if followers {
+		follows, info, err = s.r.GetFollowers(paging, target.ID)
+	} else {
+		follows, info, err = s.r.GetFollowing(paging, target.ID)
+	}
+	if err != nil {
+		log.Printf("关注列表查询出错：%s\n", err.Error())
+		return nil, errs.NewError(http.StatusInternalServerError, "", err)
+	}
+
+	return &dtos.FollowListResp{
+		Users:       dtos.ToFollowList(follows, followers),
+		Cnt:         uint(info.Total),
+		CurrentPage: uint(paging.Page),
+		CursorInfo:  dtos.NewCursorInfo(info),
+	}, nil
+}
+
+// 关注的作者发布的文章，按发布时间倒序
+func (s *Service) GetFeed(userID string, req *dtos.PageReq) (*dtos.PostListResp, *errs.ErrorResp) {
+	paging, errResp := toPaging(req)
+	if errResp != nil {
+		return nil, errResp
+	}
+
+	posts, info, err := s.r.GetFeed(paging, userID)
+	if err != nil {
+		log.Printf("关注动态查询出错：%s\n", err.Error())
+		return nil, errs.NewError(http.StatusInternalServerError, "", err)
+	}
+
+	postList := dtos.ToPostList(posts)
+	s.markBookmarked(userID, postList)
+
+	resp := dtos.NewPostList(postList, info.Total, paging.Page)
+	resp.CursorInfo = dtos.NewCursorInfo(info)
+	return resp, nil
+}
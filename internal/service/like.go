@@ -0,0 +1,65 @@
+package service
+
+import (
+	"errors"
+	"log"
+	"net/http"
+
+	"github.com/Jack-samu/the-blog-backend-gin.git/internal/dtos"
+	"github.com/Jack-samu/the-blog-backend-gin.git/internal/errs"
+	"gorm.io/gorm"
+)
+
+// 点赞/取消点赞，同一用户重复操作不会重复计数
+func (s *Service) ToggleLike(userID, targetType string, targetID uint, like bool) (*dtos.LikeResp, *errs.ErrorResp) {
+	if targetID == 0 {
+		return nil, errs.NewError(http.StatusBadRequest, "参数错误", nil)
+	}
+
+	var likes int
+
+	err := s.r.Transaction(func(tx *gorm.DB) error {
+		exists, err := s.r.LikeTargetExists(tx, targetType, targetID)
+		if err != nil {
+			return err
+		}
+		if !exists {
+			return gorm.ErrRecordNotFound
+		}
+
+		var changed bool
+		delta := 1
+		if like {
+			changed, err = s.r.CreateLike(tx, userID, targetType, targetID)
+		} else {
+			changed, err = s.r.DeleteLike(tx, userID, targetType, targetID)
+			delta = -1
+		}
+		if err != nil {
+			return err
+		}
+
+		// 只有记录真正发生变化时才调整计数
+		if changed {
+			if err = s.r.UpdateLikeCnt(tx, targetType, targetID, delta); err != nil {
+				return err
+			}
+		}
+
+		likes, err = s.r.GetLikeCnt(tx, targetType, targetID)
+		return err
+	})
+
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errs.NewError(http.StatusNotFound, "点赞对象不存在", nil)
+		}
+		log.Printf("%s点赞操作出错：%s\n", targetType, err.Error())
+		return nil, errs.NewError(http.StatusInternalServerError, "", err)
+	}
+
+	return &dtos.LikeResp{
+		Likes: likes,
+		Liked: like,
+	}, nil
+}
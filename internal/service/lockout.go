@@ -0,0 +1,178 @@
+package service
+
+import (
+	"log"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Jack-samu/the-blog-backend-gin.git/internal/errs"
+	"github.com/Jack-samu/the-blog-backend-gin.git/internal/models"
+)
+
+// 登录锁定策略：连续失败达到threshold次后锁定duration，
+// 解锁后每再失败一次，锁定时长翻倍，最长不超过max
+type lockoutPolicy struct {
+	threshold int
+	duration  time.Duration
+	max       time.Duration
+}
+
+func loadLockoutPolicy() lockoutPolicy {
+	return lockoutPolicy{
+		threshold: envInt("LOGIN_LOCK_THRESHOLD", 5),
+		duration:  envDuration("LOGIN_LOCK_DURATION", 15*time.Minute),
+		max:       envDuration("LOGIN_LOCK_MAX", 24*time.Hour),
+	}
+}
+
+// failed为本次失败后库里的连续失败次数，返回0表示不需要锁定
+func (p lockoutPolicy) lockFor(failed int) time.Duration {
+	if failed < p.threshold {
+		return 0
+	}
+
+	d := p.duration
+	for i := failed - p.threshold; i > 0 && d < p.max; i-- {
+		d *= 2
+	}
+	return min(d, p.max)
+}
+
+// 同一来源对同一账号的失败限额，低于锁定阈值，单个来源无法把别人的账号锁定；
+// 窗口默认与首次锁定时长一致
+func newSourceLimiter(p lockoutPolicy) *ipLimiter {
+	return newIPLimiter(envInt("LOGIN_SOURCE_LIMIT", max(p.threshold-1, 1)),
+		envDuration("LOGIN_SOURCE_WINDOW", p.duration))
+}
+
+func sourceKey(ip string, user *models.User) string {
+	if ip == "" {
+		return ""
+	}
+	return ip + "|" + user.ID
+}
+
+// 校验凭据之前的检查：来源对该账号失败过多时直接拒绝，账号锁定期间也不再校验
+func (s *Service) checkAttempt(user *models.User, ip string, now time.Time) *errs.ErrorResp {
+	if !s.source.allow(sourceKey(ip, user)) {
+		log.Printf("来源%s对用户'%s'的登录失败次数过多\n", ip, user.Username)
+		return errs.NewError(http.StatusTooManyRequests, "登录尝试过于频繁，请稍后再试", nil)
+	}
+	if user.LockedUntil != nil && now.Before(*user.LockedUntil) {
+		log.Printf("用户'%s'的登录已锁定\n", user.Username)
+		return lockedError(*user.LockedUntil)
+	}
+	return nil
+}
+
+// 凭据校验失败，分别计入来源和账号的失败次数，账号达到阈值时返回锁定的错误
+func (s *Service) attemptFailed(user *models.User, ip string, now time.Time) *errs.ErrorResp {
+	s.ip.fail(ip)
+	s.source.fail(sourceKey(ip, user))
+	return s.loginFailed(user, now)
+}
+
+// 凭据校验通过，清掉该来源对该账号之前的失败记录
+func (s *Service) attemptPassed(user *models.User, ip string) {
+	s.source.clear(sourceKey(ip, user))
+}
+
+// 清掉所有来源对该账号的失败记录，管理员重置失败计数时使用
+func (s *Service) clearSourceFails(userID string) {
+	suffix := "|" + userID
+	s.source.clearIf(func(key string) bool {
+		return strings.HasSuffix(key, suffix)
+	})
+}
+
+// 清理各限流器里窗口已过的记录，由后台轮询定时调用
+func (s *Service) sweepLimiters() {
+	s.ip.sweep()
+	s.source.sweep()
+}
+
+// 按key统计滑动窗口内的登录失败次数，超过limit后直接拒绝，不再累加账号的失败计数；
+// key为IP时限制单个来源的总尝试次数，为来源加账号时见newSourceLimiter
+type ipLimiter struct {
+	mu     sync.Mutex
+	limit  int
+	window time.Duration
+	fails  map[string][]time.Time
+}
+
+func newIPLimiter(limit int, window time.Duration) *ipLimiter {
+	return &ipLimiter{
+		limit:  limit,
+		window: window,
+		fails:  make(map[string][]time.Time),
+	}
+}
+
+// 清掉窗口外的记录，调用方持有锁
+func (l *ipLimiter) prune(ip string, now time.Time) []time.Time {
+	fails := l.fails[ip]
+	i := 0
+	for i < len(fails) && now.Sub(fails[i]) >= l.window {
+		i++
+	}
+	fails = fails[i:]
+	if len(fails) == 0 {
+		delete(l.fails, ip)
+	} else {
+		l.fails[ip] = fails
+	}
+	return fails
+}
+
+func (l *ipLimiter) allow(ip string) bool {
+	if ip == "" {
+		return true
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return len(l.prune(ip, time.Now())) < l.limit
+}
+
+func (l *ipLimiter) clear(ip string) {
+	if ip == "" {
+		return
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	delete(l.fails, ip)
+}
+
+func (l *ipLimiter) clearIf(match func(key string) bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	for key := range l.fails {
+		if match(key) {
+			delete(l.fails, key)
+		}
+	}
+}
+
+// 只在同一个key再次出现时才会清理，长期运行需要定时整体清一遍
+func (l *ipLimiter) sweep() {
+	now := time.Now()
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	for key := range l.fails {
+		l.prune(key, now)
+	}
+}
+
+func (l *ipLimiter) fail(ip string) {
+	if ip == "" {
+		return
+	}
+
+	now := time.Now()
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.fails[ip] = append(l.prune(ip, now), now)
+}
@@ -0,0 +1,302 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Jack-samu/the-blog-backend-gin.git/internal/dtos"
+	"github.com/Jack-samu/the-blog-backend-gin.git/internal/errs"
+	"github.com/Jack-samu/the-blog-backend-gin.git/internal/models"
+	"github.com/Jack-samu/the-blog-backend-gin.git/internal/utils"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// 授权请求的有效期，超时未回调的state作废
+const oidcStateTTL = 10 * time.Minute
+
+type oidcState struct {
+	provider string
+	nonce    string
+	verifier string
+	expires  time.Time
+}
+
+// 进行中的授权请求，发起登录不需要鉴权，数量有上限，超时的由后台轮询定时清理
+type oidcStateStore struct {
+	mu     sync.Mutex
+	limit  int
+	states map[string]*oidcState
+}
+
+func newOIDCStateStore(limit int) *oidcStateStore {
+	return &oidcStateStore{
+		limit:  limit,
+		states: make(map[string]*oidcState),
+	}
+}
+
+// 达到上限时先清理一次超时的记录，仍然放不下就拒绝
+func (o *oidcStateStore) put(state string, st *oidcState, now time.Time) bool {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	if len(o.states) >= o.limit {
+		o.prune(now)
+		if len(o.states) >= o.limit {
+			return false
+		}
+	}
+	o.states[state] = st
+	return true
+}
+
+// state只能使用一次，取出即删除
+func (o *oidcStateStore) take(state string) *oidcState {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	st := o.states[state]
+	delete(o.states, state)
+	return st
+}
+
+// 调用方持有锁
+func (o *oidcStateStore) prune(now time.Time) {
+	for key, st := range o.states {
+		if now.After(st.expires) {
+			delete(o.states, key)
+		}
+	}
+}
+
+func (o *oidcStateStore) sweep() {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.prune(time.Now())
+}
+
+// 从环境变量加载第三方登录配置：OIDC_PROVIDERS为逗号分隔的提供方名称，
+// 每个提供方读取OIDC_<NAME>_ISSUER、_CLIENT_ID、_CLIENT_SECRET、_REDIRECT_URL和_SCOPES
+func loadOIDCProviders() map[string]*utils.OIDCProvider {
+	providers := make(map[string]*utils.OIDCProvider)
+
+	for _, name := range strings.Split(os.Getenv("OIDC_PROVIDERS"), ",") {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if name == "" {
+			continue
+		}
+
+		prefix := "OIDC_" + strings.ToUpper(strings.ReplaceAll(name, "-", "_")) + "_"
+		p := &utils.OIDCProvider{
+			Name:         name,
+			Issuer:       os.Getenv(prefix + "ISSUER"),
+			ClientID:     os.Getenv(prefix + "CLIENT_ID"),
+			ClientSecret: os.Getenv(prefix + "CLIENT_SECRET"),
+			RedirectURL:  os.Getenv(prefix + "REDIRECT_URL"),
+			Scopes:       utils.ParseScopes(os.Getenv(prefix + "SCOPES")),
+		}
+		if p.Issuer == "" || p.ClientID == "" {
+			log.Printf("第三方登录%s缺少issuer或client_id，已忽略\n", name)
+			continue
+		}
+		if p.RedirectURL == "" {
+			p.RedirectURL = publicBaseURL() + "/auth/oidc/" + name + "/callback"
+		}
+
+		providers[name] = p
+	}
+
+	return providers
+}
+
+func (s *Service) oidcProvider(name string) (*utils.OIDCProvider, *errs.ErrorResp) {
+	p, ok := s.oidc[name]
+	if !ok {
+		return nil, errs.NewError(http.StatusNotFound, "不支持该登录方式", nil)
+	}
+	return p, nil
+}
+
+// 发起第三方登录，返回跳转到提供方的授权地址
+func (s *Service) OIDCStart(provider string) (string, *errs.ErrorResp) {
+	p, errResp := s.oidcProvider(provider)
+	if errResp != nil {
+		return "", errResp
+	}
+
+	var state, nonce, verifier string
+	var err error
+	for _, v := range []*string{&state, &nonce, &verifier} {
+		if *v, err = utils.RandomString(32); err != nil {
+			log.Printf("随机数生成出错：%s\n", err.Error())
+			return "", errs.NewError(http.StatusInternalServerError, "", err)
+		}
+	}
+
+	authURL, err := p.AuthURL(state, nonce, verifier)
+	if err != nil {
+		log.Printf("第三方登录%s授权地址生成出错：%s\n", provider, err.Error())
+		return "", errs.NewError(http.StatusBadGateway, "第三方登录暂不可用", nil)
+	}
+
+	now := time.Now()
+	ok := s.oidcStates.put(state, &oidcState{
+		provider: provider,
+		nonce:    nonce,
+		verifier: verifier,
+		expires:  now.Add(oidcStateTTL),
+	}, now)
+	if !ok {
+		log.Printf("进行中的第三方登录请求过多\n")
+		return "", errs.NewError(http.StatusServiceUnavailable, "登录请求过多，请稍后再试", nil)
+	}
+
+	return authURL, nil
+}
+
+// 提供方回调：校验state，兑换授权码并校验id_token，然后按外部身份登录
+func (s *Service) OIDCCallback(provider, code, state, ip, userAgent string) (*dtos.LoginResp, *errs.ErrorResp) {
+	p, errResp := s.oidcProvider(provider)
+	if errResp != nil {
+		return nil, errResp
+	}
+
+	// state只能使用一次
+	st := s.oidcStates.take(state)
+	if st == nil || st.provider != provider || time.Now().After(st.expires) {
+		return nil, errs.NewError(http.StatusBadRequest, "登录请求无效或已过期，请重新登录", nil)
+	}
+
+	rawIDToken, err := p.Exchange(code, st.verifier)
+	if err != nil {
+		log.Printf("第三方登录%s授权码兑换出错：%s\n", provider, err.Error())
+		return nil, errs.NewError(http.StatusUnauthorized, "第三方登录失败", nil)
+	}
+
+	claims, err := p.VerifyIDToken(rawIDToken, st.nonce)
+	if err != nil {
+		log.Printf("第三方登录%s的id_token校验失败：%s\n", provider, err.Error())
+		return nil, errs.NewError(http.StatusUnauthorized, "第三方登录失败", nil)
+	}
+
+	user, errResp := s.oidcUser(provider, claims)
+	if errResp != nil {
+		return nil, errResp
+	}
+
+	if user.SuspendedAt != nil {
+		log.Printf("用户'%s'已被封禁\n", user.Username)
+		return nil, errs.NewError(http.StatusForbidden, "账号已被封禁", nil)
+	}
+
+	return s.firstFactorPassed(user, ip, userAgent)
+}
+
+// 查找外部身份对应的用户，首次登录时创建账号
+func (s *Service) oidcUser(provider string, claims *utils.IDClaims) (*models.User, *errs.ErrorResp) {
+	identity, err := s.r.GetIdentity(provider, claims.Subject)
+	if err == nil {
+		if err = s.r.TouchIdentity(identity.ID); err != nil {
+			log.Printf("第三方登录时间更新失败：%s\n", err.Error())
+		}
+
+		user, err := s.r.GetUserById(nil, identity.UserID)
+		if err != nil {
+			log.Printf("用户查询出错：%s\n", err.Error())
+			return nil, errs.NewError(http.StatusInternalServerError, "用户查询出错", err)
+		}
+		return user, nil
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		log.Printf("第三方身份查询出错：%s\n", err.Error())
+		return nil, errs.NewError(http.StatusInternalServerError, "", err)
+	}
+
+	if claims.Email == "" {
+		return nil, errs.NewError(http.StatusBadRequest, "第三方账号未提供邮箱，无法注册", nil)
+	}
+
+	// 不按邮箱自动关联已有账号，避免提供方的邮箱未经验证时被冒用
+	exists, err := s.r.ExistByEmail(nil, claims.Email)
+	if err != nil {
+		log.Printf("用户查询出错：%s\n", err.Error())
+		return nil, errs.NewError(http.StatusInternalServerError, "用户查询出错", err)
+	}
+	if exists {
+		return nil, errs.NewError(http.StatusConflict, "该邮箱已注册，请使用密码登录", nil)
+	}
+
+	username, err := s.uniqueUsername(utils.UsernameFromClaims(claims))
+	if err != nil {
+		log.Printf("用户名生成出错：%s\n", err.Error())
+		return nil, errs.NewError(http.StatusInternalServerError, "", err)
+	}
+
+	now := time.Now()
+	user := &models.User{
+		ID:           uuid.New().String(),
+		Username:     username,
+		Email:        claims.Email,
+		Role:         models.RoleAuthor,
+		CreatedAt:    now,
+		LastActivity: now,
+	}
+	if claims.EmailVerified {
+		user.EmailVerifiedAt = &now
+	}
+
+	// 第三方注册的账号没有可用的密码，需要时可以走找回密码设置
+	pwd, err := utils.RandomString(32)
+	if err == nil {
+		err = user.SetPassword(pwd)
+	}
+	if err != nil {
+		log.Printf("哈希加密出错：%s\n", err.Error())
+		return nil, errs.NewError(http.StatusInternalServerError, "", err)
+	}
+
+	err = s.r.Transaction(func(tx *gorm.DB) error {
+		if err := s.r.CreateUser(tx, user); err != nil {
+			return err
+		}
+		return s.r.CreateIdentity(tx, &models.UserIdentity{
+			UserID:      user.ID,
+			Provider:    provider,
+			Subject:     claims.Subject,
+			Email:       claims.Email,
+			LastLoginAt: now,
+		})
+	})
+	if err != nil {
+		log.Printf("第三方登录用户创建出错：%s\n", err.Error())
+		return nil, errs.NewError(http.StatusInternalServerError, "用户注册失败", err)
+	}
+
+	return user, nil
+}
+
+// 用户名重复时追加数字后缀
+func (s *Service) uniqueUsername(base string) (string, error) {
+	name := base
+	for i := 2; i < 100; i++ {
+		exists, err := s.r.ExistByUsername(name)
+		if err != nil {
+			return "", err
+		}
+		if !exists {
+			return name, nil
+		}
+
+		suffix := fmt.Sprintf("-%d", i)
+		name = base[:min(len(base), 20-len(suffix))] + suffix
+	}
+
+	return "", errors.New("没有可用的用户名")
+}
@@ -0,0 +1,238 @@
+package service
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/Jack-samu/the-blog-backend-gin.git/internal/dtos"
+	"github.com/Jack-samu/the-blog-backend-gin.git/internal/errs"
+	"github.com/Jack-samu/the-blog-backend-gin.git/internal/models"
+	"gorm.io/gorm"
+)
+
+const (
+	maxDisplayName = 30
+	maxBio         = 500
+	// 作者主页展示的最近文章数
+	authorRecentPosts = 5
+)
+
+// 个人主页可以填写的社交平台
+var socialPlatforms = map[string]bool{
+	"github":   true,
+	"twitter":  true,
+	"weibo":    true,
+	"zhihu":    true,
+	"bilibili": true,
+	"linkedin": true,
+	"mastodon": true,
+}
+
+// 只接受http和https的完整链接
+func checkLink(raw string) bool {
+	u, err := url.Parse(raw)
+	return err == nil && (u.Scheme == "http" || u.Scheme == "https") && u.Host != "" && len(raw) <= 255
+}
+
+func checkText(s string) bool {
+	for _, r := range s {
+		if unicode.IsControl(r) && r != '\n' && r != '\r' && r != '\t' {
+			return false
+		}
+	}
+	return true
+}
+
+func toProfileResp(user *models.User, posts int64) *dtos.ProfileResp {
+	links := user.SocialLinks
+	if links == nil {
+		links = map[string]string{}
+	}
+
+	return &dtos.ProfileResp{
+		ID:          user.ID,
+		Username:    user.Username,
+		DisplayName: user.DisplayName,
+		Articles:    posts,
+		Avatar:      user.Avatar,
+		Bio:         user.Bio,
+		Website:     user.Website,
+		Links:       links,
+		JoinedAt:    user.CreatedAt.String(),
+	}
+}
+
+// 查看用户信息，只有本人能看到邮箱和草稿数
+func (s *Service) Profile(id, viewerID string) (*dtos.ProfileResp, *errs.ErrorResp) {
+	user, err := s.r.GetUserById(nil, id)
+	if err != nil && user == nil {
+		log.Printf("用户查询出错：%s\n", err.Error())
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errs.NewError(http.StatusBadRequest, "用户不存在", nil)
+		}
+		return nil, errs.NewError(http.StatusInternalServerError, "用户查询出错", err)
+	}
+
+	posts, err := s.r.GetUserPosts(id)
+	if err != nil {
+		log.Printf("用户已发表文章数查询报错：%s\n", err.Error())
+		posts = 0
+	}
+
+	resp := toProfileResp(user, posts)
+	if resp.Followers, resp.Following, err = s.r.CountFollows(user.ID); err != nil {
+		log.Printf("关注数查询出错：%s\n", err.Error())
+	}
+	if viewerID != user.ID {
+		return resp, nil
+	}
+
+	drafts, err := s.r.GetUserDrafts(id)
+	if err != nil {
+		log.Printf("用户草稿数查询报错：%s\n", err.Error())
+		drafts = 0
+	}
+	resp.Email = user.Email
+	resp.Drafts = drafts
+
+	return resp, nil
+}
+
+// 更新个人资料，返回更新后的资料
+func (s *Service) UpdateProfile(userID string, req *dtos.ProfileUpdateReq) (*dtos.ProfileResp, *errs.ErrorResp) {
+	fields := make(map[string]interface{})
+
+	if req.DisplayName != nil {
+		name := strings.TrimSpace(*req.DisplayName)
+		if !checkText(name) || strings.ContainsAny(name, "\r\n\t") {
+			return nil, errs.NewError(http.StatusBadRequest, "昵称不能包含换行等控制字符", nil)
+		}
+		if utf8.RuneCountInString(name) > maxDisplayName {
+			return nil, errs.NewError(http.StatusBadRequest, fmt.Sprintf("昵称不能超过%d个字", maxDisplayName), nil)
+		}
+		fields["display_name"] = name
+	}
+
+	if req.Bio != nil {
+		bio := strings.TrimSpace(*req.Bio)
+		if !checkText(bio) {
+			return nil, errs.NewError(http.StatusBadRequest, "简介不能包含控制字符", nil)
+		}
+		if utf8.RuneCountInString(bio) > maxBio {
+			return nil, errs.NewError(http.StatusBadRequest, fmt.Sprintf("简介不能超过%d个字", maxBio), nil)
+		}
+		fields["bio"] = bio
+	}
+
+	if req.Website != nil {
+		website := strings.TrimSpace(*req.Website)
+		if website != "" && !checkLink(website) {
+			return nil, errs.NewError(http.StatusBadRequest, "个人网站需要是http或https链接", nil)
+		}
+		fields["website"] = website
+	}
+
+	if req.Links != nil {
+		links := make(map[string]string, len(req.Links))
+		for platform, link := range req.Links {
+			platform = strings.ToLower(strings.TrimSpace(platform))
+			link = strings.TrimSpace(link)
+			if !socialPlatforms[platform] {
+				return nil, errs.NewError(http.StatusBadRequest, "不支持的社交平台："+platform, nil)
+			}
+			if link == "" {
+				continue
+			}
+			if !checkLink(link) {
+				return nil, errs.NewError(http.StatusBadRequest, platform+"的链接需要是http或https链接", nil)
+			}
+			links[platform] = link
+		}
+		// 按map更新时不会经过字段的序列化，需要自己编码
+		data, err := json.Marshal(links)
+		if err != nil {
+			return nil, errs.NewError(http.StatusInternalServerError, "", err)
+		}
+		fields["social_links"] = string(data)
+	}
+
+	if len(fields) > 0 {
+		if err := s.r.UpdateProfile(userID, fields); err != nil {
+			log.Printf("个人资料更新出错：%s\n", err.Error())
+			return nil, errs.NewError(http.StatusInternalServerError, "", err)
+		}
+	}
+
+	return s.Profile(userID, userID)
+}
+
+// 公开的作者主页，被封禁的用户不展示；viewerID为空表示未登录
+func (s *Service) AuthorPage(username, viewerID string) (*dtos.AuthorPageResp, *errs.ErrorResp) {
+	user, errResp := s.followTarget(username)
+	if errResp != nil {
+		return nil, errResp
+	}
+
+	posts, err := s.r.GetUserPosts(user.ID)
+	if err != nil {
+		log.Printf("用户已发表文章数查询报错：%s\n", err.Error())
+		return nil, errs.NewError(http.StatusInternalServerError, "", err)
+	}
+
+	categories, err := s.r.GetSeries(user.ID)
+	if err != nil {
+		log.Printf("作者系列查询出错：%s\n", err.Error())
+		return nil, errs.NewError(http.StatusInternalServerError, "", err)
+	}
+	series := make([]dtos.SeriesItem, len(categories))
+	for i := range categories {
+		series[i] = dtos.ToSeriesItem(&categories[i])
+	}
+
+	followers, following, err := s.r.CountFollows(user.ID)
+	if err != nil {
+		log.Printf("关注数查询出错：%s\n", err.Error())
+		return nil, errs.NewError(http.StatusInternalServerError, "", err)
+	}
+
+	followed := false
+	if viewerID != "" && viewerID != user.ID {
+		if followed, err = s.r.IsFollowing(viewerID, user.ID); err != nil {
+			log.Printf("关注状态查询出错：%s\n", err.Error())
+		}
+	}
+
+	recent, err := s.r.GetRecentUserPosts(user.ID, authorRecentPosts)
+	if err != nil {
+		log.Printf("作者最近文章查询出错：%s\n", err.Error())
+		return nil, errs.NewError(http.StatusInternalServerError, "", err)
+	}
+
+	recentPosts := dtos.ToPostList(recent)
+	s.markBookmarked(viewerID, recentPosts)
+
+	profile := toProfileResp(user, posts)
+	return &dtos.AuthorPageResp{
+		ID:          profile.ID,
+		Username:    profile.Username,
+		DisplayName: profile.DisplayName,
+		Avatar:      profile.Avatar,
+		Bio:         profile.Bio,
+		Website:     profile.Website,
+		Links:       profile.Links,
+		JoinedAt:    profile.JoinedAt,
+		Articles:    posts,
+		Followers:   followers,
+		Following:   following,
+		Followed:    followed,
+		Series:      series,
+		RecentPosts: recentPosts,
+	}, nil
+}
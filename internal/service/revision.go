@@ -0,0 +1,121 @@
+package service
+
+import (
+	"errors"
+	"log"
+	"net/http"
+
+	"github.com/Jack-samu/the-blog-backend-gin.git/internal/dtos"
+	"github.com/Jack-samu/the-blog-backend-gin.git/internal/errs"
+	"github.com/Jack-samu/the-blog-backend-gin.git/internal/models"
+	"gorm.io/gorm"
+)
+
+// 重新打开已发布的文章进行编辑，已有编辑稿时直接返回
+func (s *Service) EditPost(postID uint, userID string) (*dtos.DraftDetailResp, *errs.ErrorResp) {
+	var draftID uint
+
+	err := s.r.Transaction(func(tx *gorm.DB) error {
+		post, errResp := s.ownPost(postID, userID, tx)
+		if errResp != nil {
+			return deny(errResp)
+		}
+
+		draft, err := s.r.GetDraftOfPost(post.ID, tx)
+		if err == nil {
+			draftID = draft.ID
+			return nil
+		} else if !errors.Is(err, gorm.ErrRecordNotFound) {
+			return err
+		}
+
+		draft = &models.Draft{
+			Title:      post.Title,
+			Excerpt:    post.Excerpt,
+			Content:    post.Content,
+			Cover:      post.Cover,
+			UserID:     post.UserID,
+			CategoryID: post.CategoryID,
+			Tags:       post.Tags,
+			PostID:     &post.ID,
+		}
+		if err = tx.Create(draft).Error; err != nil {
+			return err
+		}
+
+		draftID = draft.ID
+		return nil
+	})
+	if err != nil {
+		if errResp, ok := asDenied(err); ok {
+			return nil, errResp
+		}
+		log.Printf("打开文章编辑稿出错：%s\n", err.Error())
+		return nil, errs.NewError(http.StatusInternalServerError, "", err)
+	}
+
+	return s.GetDraft(draftID, userID)
+}
+
+// 版本记录只对作者开放
+func (s *Service) checkRevisionAccess(postID uint, userID string) *errs.ErrorResp {
+	_, errResp := s.ownPost(postID, userID, nil)
+	return errResp
+}
+
+func (s *Service) getRevision(postID uint, version int) (*models.PostRevision, *errs.ErrorResp) {
+	revision, err := s.r.GetRevision(postID, version)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errs.NewError(http.StatusNotFound, "版本不存在", nil)
+		}
+		log.Printf("版本查询出错：%s\n", err.Error())
+		return nil, errs.NewError(http.StatusInternalServerError, "", err)
+	}
+
+	return revision, nil
+}
+
+func (s *Service) GetRevisions(postID uint, userID string) (*dtos.RevisionsResp, *errs.ErrorResp) {
+	if errResp := s.checkRevisionAccess(postID, userID); errResp != nil {
+		return nil, errResp
+	}
+
+	revisions, err := s.r.GetRevisions(postID)
+	if err != nil {
+		log.Printf("版本列表查询出错：%s\n", err.Error())
+		return nil, errs.NewError(http.StatusInternalServerError, "", err)
+	}
+
+	return dtos.ToRevisionsResp(postID, revisions), nil
+}
+
+func (s *Service) GetRevision(postID uint, version int, userID string) (*dtos.RevisionDetailResp, *errs.ErrorResp) {
+	if errResp := s.checkRevisionAccess(postID, userID); errResp != nil {
+		return nil, errResp
+	}
+
+	revision, errResp := s.getRevision(postID, version)
+	if errResp != nil {
+		return nil, errResp
+	}
+
+	return dtos.ToRevisionDetailResp(revision), nil
+}
+
+func (s *Service) DiffRevisions(postID uint, from, to int, userID string) (*dtos.RevisionDiffResp, *errs.ErrorResp) {
+	if errResp := s.checkRevisionAccess(postID, userID); errResp != nil {
+		return nil, errResp
+	}
+
+	fromRevision, errResp := s.getRevision(postID, from)
+	if errResp != nil {
+		return nil, errResp
+	}
+	toRevision, errResp := s.getRevision(postID, to)
+	if errResp != nil {
+		return nil, errResp
+	}
+
+	return dtos.ToRevisionDiffResp(fromRevision, toRevision), nil
+}
@@ -0,0 +1,181 @@
+package service
+
+import (
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/Jack-samu/the-blog-backend-gin.git/internal/dtos"
+	"github.com/Jack-samu/the-blog-backend-gin.git/internal/errs"
+	"github.com/Jack-samu/the-blog-backend-gin.git/internal/models"
+	"gorm.io/gorm"
+)
+
+// 单轮最多发布的草稿数，剩下的留到下一轮
+const scheduleBatchSize = 100
+
+func checkPublishAt(publishAt *time.Time) *errs.ErrorResp {
+	if publishAt == nil || !publishAt.After(time.Now()) {
+		return errs.NewError(http.StatusBadRequest, "发布时间需晚于当前时间", nil)
+	}
+	return nil
+}
+
+// 定时发布，内容按草稿保存并记下发布时间
+func (s *Service) ScheduleArticle(req *dtos.ArticleReq, userID string) (*dtos.DraftDetailResp, *errs.ErrorResp) {
+	if errResp := checkPublishAt(req.PublishAt); errResp != nil {
+		return nil, errResp
+	}
+
+	if errResp := s.checkVerified(userID, ActionPublish); errResp != nil {
+		return nil, errResp
+	}
+
+	var draftID uint
+	err := s.r.Transaction(func(tx *gorm.DB) error {
+		draft, err := s.saveDraft(tx, req, userID)
+		if err != nil {
+			return err
+		}
+
+		draftID = draft.ID
+		return s.r.SetDraftSchedule(tx, draft.ID, req.PublishAt)
+	})
+	if err != nil {
+		if errResp, ok := asDenied(err); ok {
+			return nil, errResp
+		}
+		log.Printf("定时发布保存出错：%s\n", err.Error())
+		return nil, errs.NewError(http.StatusInternalServerError, "", err)
+	}
+
+	return s.GetDraft(draftID, userID)
+}
+
+func (s *Service) GetScheduled(userID string) (*dtos.ScheduledResp, *errs.ErrorResp) {
+	drafts, err := s.r.GetScheduledDrafts(userID)
+	if err != nil {
+		log.Printf("定时发布列表查询出错：%s\n", err.Error())
+		return nil, errs.NewError(http.StatusInternalServerError, "", err)
+	}
+
+	return &dtos.ScheduledResp{Drafts: dtos.ToDraftList(drafts)}, nil
+}
+
+// 修改发布时间，也可用于给已有草稿设置定时
+func (s *Service) Reschedule(id uint, publishAt time.Time, userID string) (*dtos.DraftDetailResp, *errs.ErrorResp) {
+	if errResp := checkPublishAt(&publishAt); errResp != nil {
+		return nil, errResp
+	}
+
+	draft, errResp := s.ownDraft(id, userID, nil)
+	if errResp != nil {
+		return nil, errResp
+	}
+
+	if errResp = s.checkVerified(userID, ActionPublish); errResp != nil {
+		return nil, errResp
+	}
+
+	if err := s.r.SetDraftSchedule(nil, draft.ID, &publishAt); err != nil {
+		log.Printf("修改定时发布出错：%s\n", err.Error())
+		return nil, errs.NewError(http.StatusInternalServerError, "", err)
+	}
+
+	return s.GetDraft(draft.ID, userID)
+}
+
+// 取消定时，草稿保留
+func (s *Service) CancelSchedule(id uint, userID string) *errs.ErrorResp {
+	draft, errResp := s.ownDraft(id, userID, nil)
+	if errResp != nil {
+		return errResp
+	}
+	if draft.PublishAt == nil {
+		return errs.NewError(http.StatusBadRequest, "该草稿没有定时发布", nil)
+	}
+
+	if err := s.r.SetDraftSchedule(nil, draft.ID, nil); err != nil {
+		log.Printf("取消定时发布出错：%s\n", err.Error())
+		return errs.NewError(http.StatusInternalServerError, "", err)
+	}
+
+	return nil
+}
+
+// 发布所有到点的草稿，返回成功发布的数量
+func (s *Service) PublishDue(now time.Time) int {
+	ids, err := s.r.GetDueDraftIDs(now, scheduleBatchSize)
+	if err != nil {
+		log.Printf("查询待发布草稿出错：%s\n", err.Error())
+		return 0
+	}
+
+	published := 0
+	for _, id := range ids {
+		claimed := false
+		err = s.r.Transaction(func(tx *gorm.DB) error {
+			ok, err := s.r.ClaimDueDraft(tx, id, now)
+			if err != nil || !ok {
+				return err
+			}
+
+			draft, err := s.r.GetDraft(id, tx)
+			if err != nil {
+				return err
+			}
+			// 定时之后作者被降级或封禁的，取消定时，草稿保留
+			if !models.CanPublish(draft.Author.Role) || draft.Author.SuspendedAt != nil {
+				log.Printf("草稿%d的作者已无发布权限，取消定时发布\n", id)
+				return nil
+			}
+			claimed = true
+
+			post, err := s.publishDraft(tx, draft)
+			if err != nil {
+				return err
+			}
+			if _, err = s.r.CreateRevision(tx, post, draft.UserID); err != nil {
+				return err
+			}
+
+			return nil
+		})
+		if err != nil {
+			log.Printf("定时发布草稿%d出错：%s\n", id, err.Error())
+		} else if claimed {
+			published++
+		}
+	}
+
+	return published
+}
+
+// 启动定时发布的轮询，计划都存在库里，重启后第一轮就会补发错过的草稿；
+// 每轮顺带清理登录限流和第三方登录请求里过期的记录
+func (s *Service) StartScheduler() func() {
+	ticker := time.NewTicker(envDuration("SCHEDULE_POLL_INTERVAL", 30*time.Second))
+	done := make(chan struct{})
+	stopped := make(chan struct{})
+
+	go func() {
+		defer close(stopped)
+		s.PublishDue(time.Now())
+		for {
+			select {
+			case <-ticker.C:
+				s.PublishDue(time.Now())
+				s.sweepLimiters()
+				s.oidcStates.sweep()
+			case <-done:
+				ticker.Stop()
+				return
+			}
+		}
+	}()
+
+	return func() {
+		close(done)
+		<-stopped
+	}
+}
@@ -0,0 +1,72 @@
+package service
+
+import (
+	"log"
+	"net/http"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/Jack-samu/the-blog-backend-gin.git/internal/dtos"
+	"github.com/Jack-samu/the-blog-backend-gin.git/internal/errs"
+	"github.com/Jack-samu/the-blog-backend-gin.git/internal/utils"
+)
+
+const (
+	maxSearchQueryLen = 100
+	maxSearchTerms    = 10
+	snippetRadius     = 40
+)
+
+func (s *Service) SearchPosts(query string, req *dtos.PageReq, viewerID string) (*dtos.SearchResp, *errs.ErrorResp) {
+	query = strings.TrimSpace(query)
+	if query == "" {
+		return nil, errs.NewError(http.StatusBadRequest, "搜索内容不能为空", nil)
+	}
+	if utf8.RuneCountInString(query) > maxSearchQueryLen {
+		return nil, errs.NewError(http.StatusBadRequest, "搜索内容过长", nil)
+	}
+
+	// 按空白切分搜索词并去重
+	terms := make([]string, 0, maxSearchTerms)
+	seen := make(map[string]bool)
+	for _, term := range strings.Fields(strings.ToLower(query)) {
+		if seen[term] || len(terms) == maxSearchTerms {
+			continue
+		}
+		seen[term] = true
+		terms = append(terms, term)
+	}
+
+	page, perPage := normalizePage(req.Page, req.PerPage)
+	posts, hits, total, err := s.r.SearchPosts(query, terms, page, perPage)
+	if err != nil {
+		log.Printf("文章搜索出错：%s\n", err.Error())
+		return nil, errs.NewError(http.StatusInternalServerError, "", err)
+	}
+
+	postList := make([]dtos.PostListItem, len(posts))
+	for i := range posts {
+		postList[i] = dtos.ToPostListItem(&posts[i])
+	}
+	s.markBookmarked(viewerID, postList)
+
+	items := make([]dtos.SearchItem, len(posts))
+	for i := range posts {
+		items[i] = dtos.SearchItem{
+			PostListItem: postList[i],
+			Score:        hits[i].Score,
+			Highlight: dtos.SearchHighlight{
+				Title:   utils.Highlight(posts[i].Title, terms, 0),
+				Excerpt: utils.Highlight(posts[i].Excerpt, terms, snippetRadius),
+				Content: utils.Highlight(posts[i].Content, terms, snippetRadius),
+			},
+		}
+	}
+
+	return &dtos.SearchResp{
+		Query:       query,
+		Posts:       items,
+		Cnt:         uint(total),
+		CurrentPage: uint(page),
+	}, nil
+}
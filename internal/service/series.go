@@ -0,0 +1,165 @@
+package service
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/Jack-samu/the-blog-backend-gin.git/internal/dtos"
+	"github.com/Jack-samu/the-blog-backend-gin.git/internal/errs"
+	"github.com/Jack-samu/the-blog-backend-gin.git/internal/models"
+	"gorm.io/gorm"
+)
+
+// 系列删除时对其下文章的处理方式
+const (
+	SeriesDetachPosts = "detach"
+	SeriesDeletePosts = "delete"
+)
+
+// 系列名与文章发布时的分类名保持一致，统一小写
+func normalizeSeriesName(name string) string {
+	return strings.ToLower(strings.TrimSpace(name))
+}
+
+func (s *Service) checkSeriesName(userID, name string, excludeID uint) *errs.ErrorResp {
+	if name == "" {
+		return errs.NewError(http.StatusBadRequest, "系列名不能为空", nil)
+	}
+
+	exist, err := s.r.ExistCategoryName(userID, name, excludeID)
+	if err != nil {
+		log.Printf("系列名查重出错：%s\n", err.Error())
+		return errs.NewError(http.StatusInternalServerError, "", err)
+	}
+	if exist {
+		return errs.NewError(http.StatusConflict, "系列名已存在", nil)
+	}
+
+	return nil
+}
+
+func (s *Service) GetSeriesDetail(id uint) (*dtos.SeriesDetailResp, *errs.ErrorResp) {
+	category, err := s.r.GetCategory(id, nil)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errs.NewError(http.StatusNotFound, "系列不存在", nil)
+		}
+		log.Printf("系列查询出错：%s\n", err.Error())
+		return nil, errs.NewError(http.StatusInternalServerError, "", err)
+	}
+
+	return dtos.ToSeriesDetailResp(category), nil
+}
+
+func (s *Service) CreateSeries(req *dtos.SeriesReq, userID string) (*dtos.SeriesDetailResp, *errs.ErrorResp) {
+	name := normalizeSeriesName(req.Name)
+	if errResp := s.checkSeriesName(userID, name, 0); errResp != nil {
+		return nil, errResp
+	}
+
+	category := &models.Category{
+		Name:        name,
+		Description: strings.TrimSpace(req.Description),
+		Cover:       req.Cover,
+		UserID:      userID,
+	}
+	if err := s.r.CreateCategory(category); err != nil {
+		log.Printf("系列创建出错：%s\n", err.Error())
+		return nil, errs.NewError(http.StatusInternalServerError, "", err)
+	}
+
+	return s.GetSeriesDetail(category.ID)
+}
+
+func (s *Service) UpdateSeries(id uint, req *dtos.SeriesUpdateReq, userID string) (*dtos.SeriesDetailResp, *errs.ErrorResp) {
+	category, errResp := s.ownSeries(id, userID, nil)
+	if errResp != nil {
+		return nil, errResp
+	}
+
+	fields := make(map[string]interface{})
+	if req.Name != nil {
+		name := normalizeSeriesName(*req.Name)
+		if name != category.Name {
+			if errResp = s.checkSeriesName(userID, name, category.ID); errResp != nil {
+				return nil, errResp
+			}
+			fields["name"] = name
+		}
+	}
+	if req.Description != nil {
+		fields["description"] = strings.TrimSpace(*req.Description)
+	}
+	if req.Cover != nil {
+		fields["cover"] = *req.Cover
+	}
+
+	if len(fields) > 0 {
+		if err := s.r.UpdateCategoryInfo(category, fields); err != nil {
+			log.Printf("系列更新出错：%s\n", err.Error())
+			return nil, errs.NewError(http.StatusInternalServerError, "", err)
+		}
+	}
+
+	return s.GetSeriesDetail(category.ID)
+}
+
+// 删除系列，mode决定其下文章是移出系列还是一并删除
+func (s *Service) DeleteSeries(id uint, mode, userID string) *errs.ErrorResp {
+	if mode == "" {
+		mode = SeriesDetachPosts
+	}
+	if mode != SeriesDetachPosts && mode != SeriesDeletePosts {
+		return errs.NewError(http.StatusBadRequest, "不支持的文章处理方式", nil)
+	}
+
+	category, errResp := s.ownSeries(id, userID, nil)
+	if errResp != nil {
+		return errResp
+	}
+
+	err := s.r.Transaction(func(tx *gorm.DB) error {
+		return s.r.DeleteCategory(tx, category, mode == SeriesDeletePosts)
+	})
+	if err != nil {
+		log.Printf("系列删除出错：%s\n", err.Error())
+		return errs.NewError(http.StatusInternalServerError, "", err)
+	}
+
+	return nil
+}
+
+// 调整系列内文章顺序，只接受属于该系列的文章
+func (s *Service) ReorderSeries(id uint, req *dtos.SeriesOrderReq, userID string) (*dtos.SeriesDetailResp, *errs.ErrorResp) {
+	category, errResp := s.ownSeries(id, userID, nil)
+	if errResp != nil {
+		return nil, errResp
+	}
+
+	inSeries := make(map[uint]bool, len(category.Posts))
+	for _, post := range category.Posts {
+		inSeries[post.ID] = true
+	}
+	seen := make(map[uint]bool, len(req.ArticleIDs))
+	for _, postID := range req.ArticleIDs {
+		if !inSeries[postID] {
+			return nil, errs.NewError(http.StatusBadRequest, "文章不在该系列中", nil)
+		}
+		if seen[postID] {
+			return nil, errs.NewError(http.StatusBadRequest, "文章id重复", nil)
+		}
+		seen[postID] = true
+	}
+
+	err := s.r.Transaction(func(tx *gorm.DB) error {
+		return s.r.ReorderSeries(tx, category, req.ArticleIDs)
+	})
+	if err != nil {
+		log.Printf("系列排序出错：%s\n", err.Error())
+		return nil, errs.NewError(http.StatusInternalServerError, "", err)
+	}
+
+	return s.GetSeriesDetail(category.ID)
+}
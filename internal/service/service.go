@@ -1,15 +1,77 @@
 package service
 
 import (
+	"log"
+	"os"
+	"strconv"
+	"time"
+
 	dao "github.com/Jack-samu/the-blog-backend-gin.git/internal/DAO"
 	"github.com/Jack-samu/the-blog-backend-gin.git/internal/utils"
 )
 
 type Service struct {
-	r *dao.DAO
-	u *utils.UserReset
+	r    *dao.DAO
+	u    *utils.UserReset
+	v    *viewCounter
+	lock lockoutPolicy
+	ip   *ipLimiter
+	// 同一来源对同一账号的失败次数
+	source *ipLimiter
+
+	// 未验证邮箱的用户受限的操作
+	restrict map[string]bool
+
+	// 第三方登录的提供方，以及进行中的授权请求
+	oidc       map[string]*utils.OIDCProvider
+	oidcStates *oidcStateStore
 }
 
 func NewService(r *dao.DAO) *Service {
-	return &Service{r: r}
+	lock := loadLockoutPolicy()
+
+	return &Service{
+		r:      r,
+		u:      &utils.UserReset{},
+		v:      newViewCounter(envDuration("VIEW_DEDUP_WINDOW", 30*time.Minute)),
+		lock:   lock,
+		ip:     newIPLimiter(envInt("LOGIN_IP_LIMIT", 20), envDuration("LOGIN_IP_WINDOW", 15*time.Minute)),
+		source: newSourceLimiter(lock),
+
+		restrict:   loadRestrictions(),
+		oidc:       loadOIDCProviders(),
+		oidcStates: newOIDCStateStore(envInt("OIDC_MAX_PENDING", 10000)),
+	}
+}
+
+// 读取时长类型的配置，未配置或者格式错误时使用默认值
+func envDuration(key string, def time.Duration) time.Duration {
+	val := os.Getenv(key)
+	if val == "" {
+		return def
+	}
+
+	d, err := time.ParseDuration(val)
+	if err != nil || d <= 0 {
+		log.Printf("配置项%s格式错误：%s，使用默认值%s\n", key, val, def)
+		return def
+	}
+
+	return d
+}
+
+// 读取正整数类型的配置，规则同envDuration
+func envInt(key string, def int) int {
+	val := os.Getenv(key)
+	if val == "" {
+		return def
+	}
+
+	n, err := strconv.Atoi(val)
+	if err != nil || n <= 0 {
+		log.Printf("配置项%s格式错误：%s，使用默认值%d\n", key, val, def)
+		return def
+	}
+
+	return n
 }
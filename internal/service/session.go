@@ -0,0 +1,47 @@
+package service
+
+import (
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/Jack-samu/the-blog-backend-gin.git/internal/dtos"
+	"github.com/Jack-samu/the-blog-backend-gin.git/internal/errs"
+)
+
+// 会话活跃时间的最小更新间隔
+const sessionTouchInterval = time.Minute
+
+func (s *Service) GetSessions(userID, currentID string) (*dtos.SessionsResp, *errs.ErrorResp) {
+	sessions, err := s.r.GetActiveSessions(userID)
+	if err != nil {
+		log.Printf("会话列表查询出错：%s\n", err.Error())
+		return nil, errs.NewError(http.StatusInternalServerError, "", err)
+	}
+
+	return dtos.ToSessionsResp(sessions, currentID), nil
+}
+
+func (s *Service) RevokeSession(userID, sessionID string) *errs.ErrorResp {
+	n, err := s.r.RevokeSession(nil, userID, sessionID)
+	if err != nil {
+		log.Printf("会话吊销出错：%s\n", err.Error())
+		return errs.NewError(http.StatusInternalServerError, "", err)
+	}
+	if n == 0 {
+		return errs.NewError(http.StatusNotFound, "会话不存在", nil)
+	}
+
+	return nil
+}
+
+// 退出其他设备上的登录，保留当前会话
+func (s *Service) RevokeOtherSessions(userID, currentID string) (int64, *errs.ErrorResp) {
+	n, err := s.r.RevokeOtherSessions(nil, userID, currentID)
+	if err != nil {
+		log.Printf("会话吊销出错：%s\n", err.Error())
+		return 0, errs.NewError(http.StatusInternalServerError, "", err)
+	}
+
+	return n, nil
+}
@@ -0,0 +1,76 @@
+package service
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/Jack-samu/the-blog-backend-gin.git/internal/dtos"
+	"github.com/Jack-samu/the-blog-backend-gin.git/internal/errs"
+	"gorm.io/gorm"
+)
+
+// 限制单次返回的标签数量
+func normalizeTagLimit(limit, def int) int {
+	if limit < 1 {
+		return def
+	}
+	if limit > 100 {
+		return 100
+	}
+	return limit
+}
+
+func (s *Service) GetTags(req *dtos.TagListReq) (*dtos.TagsResp, *errs.ErrorResp) {
+	tags, err := s.r.GetTagsWithCount(req.Sort, normalizeTagLimit(req.Limit, 50))
+	if err != nil {
+		log.Printf("标签列表查询出错：%s\n", err.Error())
+		return nil, errs.NewError(http.StatusInternalServerError, "", err)
+	}
+
+	return dtos.ToTagsResp(tags), nil
+}
+
+func (s *Service) SuggestTags(req *dtos.TagSuggestReq) (*dtos.TagsResp, *errs.ErrorResp) {
+	// 标签入库时统一小写
+	prefix := strings.ToLower(strings.TrimSpace(req.Prefix))
+	if prefix == "" {
+		return &dtos.TagsResp{Tags: []dtos.TagItem{}}, nil
+	}
+
+	tags, err := s.r.SuggestTags(prefix, normalizeTagLimit(req.Limit, 10))
+	if err != nil {
+		log.Printf("标签联想查询出错：%s\n", err.Error())
+		return nil, errs.NewError(http.StatusInternalServerError, "", err)
+	}
+
+	return dtos.ToTagsResp(tags), nil
+}
+
+// 标签页，复用公共文章列表的筛选和分页
+func (s *Service) GetTagPosts(name string, req *dtos.PageReq, viewerID string) (*dtos.TagDetailResp, *errs.ErrorResp) {
+	name = strings.ToLower(strings.TrimSpace(name))
+
+	tag, err := s.r.GetTagByName(name)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errs.NewError(http.StatusNotFound, "标签不存在", nil)
+		}
+		log.Printf("标签查询出错：%s\n", err.Error())
+		return nil, errs.NewError(http.StatusInternalServerError, "", err)
+	}
+
+	postsResp, errResp := s.GetPosts(&dtos.ArticleListReq{
+		PageReq: *req,
+		Tag:     tag.Name,
+	}, viewerID)
+	if errResp != nil {
+		return nil, errResp
+	}
+
+	return &dtos.TagDetailResp{
+		Tag:          dtos.ToTagItem(tag),
+		PostListResp: *postsResp,
+	}, nil
+}
@@ -0,0 +1,155 @@
+package service
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/Jack-samu/the-blog-backend-gin.git/internal/dtos"
+	"github.com/Jack-samu/the-blog-backend-gin.git/internal/errs"
+	"github.com/Jack-samu/the-blog-backend-gin.git/internal/models"
+	"github.com/Jack-samu/the-blog-backend-gin.git/internal/utils"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+const (
+	accessTokenTTL  = time.Hour
+	refreshTokenTTL = 24 * time.Hour
+)
+
+// 登录时开启新会话并签发token
+func (s *Service) startSession(tx *gorm.DB, user *models.User, ip, userAgent string) (string, string, error) {
+	now := time.Now()
+	if len(userAgent) > 255 {
+		userAgent = userAgent[:255]
+	}
+
+	session := &models.Session{
+		ID:         uuid.NewString(),
+		UserID:     user.ID,
+		UserAgent:  userAgent,
+		IP:         ip,
+		CreatedAt:  now,
+		LastSeenAt: now,
+		ExpiresAt:  now.Add(refreshTokenTTL),
+	}
+	if err := s.r.CreateSession(tx, session); err != nil {
+		return "", "", err
+	}
+
+	return s.issueTokens(tx, user, session.ID)
+}
+
+// 签发一对access和refresh token，refresh token的哈希落库
+func (s *Service) issueTokens(tx *gorm.DB, user *models.User, familyID string) (string, string, error) {
+	token, err := utils.GenerateToken(user.ID, accessTokenTTL,
+		utils.WithRole(user.Role), utils.WithSession(familyID))
+	if err != nil {
+		return "", "", err
+	}
+
+	refreshToken, err := utils.GenerateToken(user.ID, refreshTokenTTL,
+		utils.WithRole(user.Role), utils.WithSession(familyID), utils.WithType(utils.TokenRefresh))
+	if err != nil {
+		return "", "", err
+	}
+
+	err = s.r.CreateRefreshToken(tx, &models.RefreshToken{
+		UserID:    user.ID,
+		FamilyID:  familyID,
+		TokenHash: utils.HashToken(refreshToken),
+		ExpiresAt: time.Now().Add(refreshTokenTTL),
+	})
+	if err != nil {
+		return "", "", err
+	}
+
+	return token, refreshToken, nil
+}
+
+var errTokenReused = errors.New("refreshToken重复使用")
+
+// 用refresh token换一对新token，旧的refresh token随即失效；
+// 已失效的token再次出现说明可能被盗用，吊销整个会话
+func (s *Service) RefreshTheToken(refreshToken string) (*dtos.RefreshResp, *errs.ErrorResp) {
+	payload, err := utils.ParseToken(refreshToken)
+	if err != nil || payload.Type != utils.TokenRefresh {
+		return nil, errs.NewError(http.StatusUnauthorized, "refreshToken无效或者已过期", nil)
+	}
+
+	var user *models.User
+	var token, newRefreshToken, familyID string
+
+	err = s.r.Transaction(func(tx *gorm.DB) error {
+		stored, err := s.r.GetRefreshToken(tx, utils.HashToken(refreshToken))
+		if err != nil {
+			return err
+		}
+
+		familyID = stored.FamilyID
+		ok, err := s.r.UseRefreshToken(tx, stored.ID)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			return errTokenReused
+		}
+
+		if err = tx.Where("id = ?", stored.UserID).First(&user).Error; err != nil {
+			return err
+		}
+		if user.SuspendedAt != nil {
+			return deny(errs.NewError(http.StatusForbidden, "账号已被封禁", nil))
+		}
+
+		// 会话有效期随refresh token顺延
+		expiresAt := time.Now().Add(refreshTokenTTL)
+		if err = s.r.TouchSession(tx, stored.FamilyID, &expiresAt); err != nil {
+			return err
+		}
+
+		token, newRefreshToken, err = s.issueTokens(tx, user, stored.FamilyID)
+		return err
+	})
+
+	if errors.Is(err, errTokenReused) {
+		// 吊销放在事务外，不能跟着事务回滚
+		log.Printf("用户'%s'的refreshToken被重复使用，吊销会话%s\n", payload.ID, familyID)
+		if _, err = s.r.RevokeSession(nil, payload.ID, familyID); err != nil {
+			log.Printf("会话吊销失败：%s\n", err.Error())
+		}
+		return nil, errs.NewError(http.StatusUnauthorized, "refreshToken已失效，请重新登录", nil)
+	}
+	if err != nil {
+		if errResp, ok := asDenied(err); ok {
+			return nil, errResp
+		}
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errs.NewError(http.StatusUnauthorized, "refreshToken无效或者已过期", nil)
+		}
+		log.Printf("token刷新出错：%s\n", err.Error())
+		return nil, errs.NewError(http.StatusInternalServerError, "token刷新失败，请重试", err)
+	}
+
+	posts, err := s.r.GetUserPosts(user.ID)
+	if err != nil {
+		log.Printf("获取用户文章数量失败：%s\n", err.Error())
+		posts = 0
+	}
+
+	return &dtos.RefreshResp{
+		Token:        token,
+		RefreshToken: newRefreshToken,
+		UserInfo: &dtos.UserInfo{
+			ID:       user.ID,
+			Username: user.Username,
+			Email:    user.Email,
+			Avatar:   user.Avatar,
+			Role:     user.Role,
+			Verified: user.EmailVerifiedAt != nil,
+			Posts:    posts,
+		},
+	}, nil
+}
@@ -0,0 +1,223 @@
+package service
+
+import (
+	"crypto/rand"
+	"errors"
+	"log"
+	"math/big"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/Jack-samu/the-blog-backend-gin.git/internal/dtos"
+	"github.com/Jack-samu/the-blog-backend-gin.git/internal/errs"
+	"github.com/Jack-samu/the-blog-backend-gin.git/internal/models"
+	"github.com/Jack-samu/the-blog-backend-gin.git/internal/utils"
+	"gorm.io/gorm"
+)
+
+const (
+	recoveryCodeCount   = 10
+	recoveryCodeLetters = "abcdefghjkmnpqrstuvwxyz23456789"
+)
+
+func totpIssuer() string {
+	if issuer := os.Getenv("TOTP_ISSUER"); issuer != "" {
+		return issuer
+	}
+	return "the-blog"
+}
+
+// 恢复码格式为xxxxx-xxxxx，去掉了容易混淆的字符
+func generateRecoveryCodes() ([]string, []string, error) {
+	codes := make([]string, 0, recoveryCodeCount)
+	hashes := make([]string, 0, recoveryCodeCount)
+
+	for range recoveryCodeCount {
+		buf := make([]byte, 10)
+		for i := range buf {
+			n, err := rand.Int(rand.Reader, big.NewInt(int64(len(recoveryCodeLetters))))
+			if err != nil {
+				return nil, nil, err
+			}
+			buf[i] = recoveryCodeLetters[n.Int64()]
+		}
+		code := string(buf[:5]) + "-" + string(buf[5:])
+		codes = append(codes, code)
+		hashes = append(hashes, utils.HashToken(code))
+	}
+
+	return codes, hashes, nil
+}
+
+func normalizeRecoveryCode(code string) string {
+	return strings.ToLower(strings.TrimSpace(code))
+}
+
+// 校验第二步的验证码，可以是验证器应用上的验证码，也可以是未使用过的恢复码
+func (s *Service) checkSecondFactor(tx *gorm.DB, user *models.User, code string) (bool, error) {
+	if step, ok := utils.ValidateTOTP(user.TOTPSecret, code, time.Now()); ok {
+		return s.r.UseTOTPStep(tx, user.ID, step)
+	}
+
+	return s.r.UseRecoveryCode(tx, user.ID, utils.HashToken(normalizeRecoveryCode(code)))
+}
+
+// 生成待确认的密钥，返回给验证器应用扫码的链接
+func (s *Service) SetupTwoFactor(userID string) (*dtos.TwoFactorSetupResp, *errs.ErrorResp) {
+	user, errResp := s.accountUser(userID)
+	if errResp != nil {
+		return nil, errResp
+	}
+
+	if user.TOTPEnabledAt != nil {
+		return nil, errs.NewError(http.StatusBadRequest, "已开启两步验证", nil)
+	}
+
+	secret, err := utils.GenerateTOTPSecret()
+	if err != nil {
+		log.Printf("两步验证密钥生成出错：%s\n", err.Error())
+		return nil, errs.NewError(http.StatusInternalServerError, "", err)
+	}
+
+	if err = s.r.SetTOTPSecret(user.ID, secret); err != nil {
+		log.Printf("两步验证密钥保存出错：%s\n", err.Error())
+		return nil, errs.NewError(http.StatusInternalServerError, "", err)
+	}
+
+	return &dtos.TwoFactorSetupResp{
+		Secret: secret,
+		URI:    utils.TOTPURI(totpIssuer(), user.Username, secret),
+	}, nil
+}
+
+// 用第一个验证码确认后启用两步验证，恢复码只在这里返回一次
+func (s *Service) EnableTwoFactor(userID, code string) (*dtos.RecoveryCodesResp, *errs.ErrorResp) {
+	user, errResp := s.accountUser(userID)
+	if errResp != nil {
+		return nil, errResp
+	}
+
+	if user.TOTPEnabledAt != nil {
+		return nil, errs.NewError(http.StatusBadRequest, "已开启两步验证", nil)
+	}
+	if user.TOTPSecret == "" {
+		return nil, errs.NewError(http.StatusBadRequest, "请先获取两步验证密钥", nil)
+	}
+
+	step, ok := utils.ValidateTOTP(user.TOTPSecret, code, time.Now())
+	if !ok {
+		return nil, errs.NewError(http.StatusBadRequest, "验证码错误", nil)
+	}
+
+	codes, hashes, err := generateRecoveryCodes()
+	if err != nil {
+		log.Printf("恢复码生成出错：%s\n", err.Error())
+		return nil, errs.NewError(http.StatusInternalServerError, "", err)
+	}
+
+	err = s.r.Transaction(func(tx *gorm.DB) error {
+		if err := s.r.EnableTOTP(tx, user.ID, step); err != nil {
+			return err
+		}
+		return s.r.ReplaceRecoveryCodes(tx, user.ID, hashes)
+	})
+	if err != nil {
+		log.Printf("两步验证开启出错：%s\n", err.Error())
+		return nil, errs.NewError(http.StatusInternalServerError, "", err)
+	}
+
+	return &dtos.RecoveryCodesResp{Codes: codes}, nil
+}
+
+// 关闭两步验证需要有效的验证码或恢复码，验证码错误和登录失败一起计数
+func (s *Service) DisableTwoFactor(userID, code, ip string) *errs.ErrorResp {
+	user, errResp := s.accountUser(userID)
+	if errResp != nil {
+		return errResp
+	}
+
+	if user.TOTPEnabledAt == nil {
+		return errs.NewError(http.StatusBadRequest, "未开启两步验证", nil)
+	}
+
+	now := time.Now()
+	if errResp := s.checkAttempt(user, ip, now); errResp != nil {
+		return errResp
+	}
+
+	err := s.r.Transaction(func(tx *gorm.DB) error {
+		ok, err := s.checkSecondFactor(tx, user, code)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			return deny(errs.NewError(http.StatusBadRequest, "验证码错误", nil))
+		}
+		return s.r.DisableTOTP(tx, user.ID)
+	})
+	if err != nil {
+		// 事务里只有验证码错误会被拒绝
+		if errResp, ok := asDenied(err); ok {
+			if lockResp := s.attemptFailed(user, ip, now); lockResp != nil {
+				return lockResp
+			}
+			return errResp
+		}
+		log.Printf("两步验证关闭出错：%s\n", err.Error())
+		return errs.NewError(http.StatusInternalServerError, "", err)
+	}
+	s.attemptPassed(user, ip)
+
+	return nil
+}
+
+// 登录第二步，挑战token由密码校验通过后签发；验证码错误和密码错误一样计入登录失败次数
+func (s *Service) LoginTwoFactor(challenge, code, ip, userAgent string) (*dtos.LoginResp, *errs.ErrorResp) {
+	payload, err := utils.ParseToken(challenge)
+	if err != nil || payload.Type != utils.TokenChallenge {
+		return nil, errs.NewError(http.StatusUnauthorized, "登录已过期，请重新登录", nil)
+	}
+
+	if !s.ip.allow(ip) {
+		log.Printf("来源%s登录失败次数过多\n", ip)
+		return nil, errs.NewError(http.StatusTooManyRequests, "登录尝试过于频繁，请稍后再试", nil)
+	}
+
+	user, err := s.r.GetUserById(nil, payload.ID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errs.NewError(http.StatusUnauthorized, "用户不存在", nil)
+		}
+		log.Printf("用户查询出错：%s\n", err.Error())
+		return nil, errs.NewError(http.StatusInternalServerError, "用户查询出错", err)
+	}
+
+	if user.SuspendedAt != nil {
+		return nil, errs.NewError(http.StatusForbidden, "账号已被封禁", nil)
+	}
+	if user.TOTPEnabledAt == nil {
+		return nil, errs.NewError(http.StatusUnauthorized, "登录已过期，请重新登录", nil)
+	}
+
+	now := time.Now()
+	if errResp := s.checkAttempt(user, ip, now); errResp != nil {
+		return nil, errResp
+	}
+
+	ok, err := s.checkSecondFactor(nil, user, code)
+	if err != nil {
+		log.Printf("两步验证校验出错：%s\n", err.Error())
+		return nil, errs.NewError(http.StatusInternalServerError, "", err)
+	}
+	if !ok {
+		if errResp := s.attemptFailed(user, ip, now); errResp != nil {
+			return nil, errResp
+		}
+		return nil, errs.NewError(http.StatusBadRequest, "验证码错误", nil)
+	}
+	s.attemptPassed(user, ip)
+
+	return s.completeLogin(user, ip, userAgent)
+}
@@ -2,15 +2,18 @@ package service
 
 import (
 	"errors"
+	"fmt"
 	"io"
 	"log"
 	"mime/multipart"
 	"net/http"
+	"net/url"
 	"os"
 	"path/filepath"
 	"strings"
 	"time"
 
+	dao "github.com/Jack-samu/the-blog-backend-gin.git/internal/DAO"
 	"github.com/Jack-samu/the-blog-backend-gin.git/internal/dtos"
 	"github.com/Jack-samu/the-blog-backend-gin.git/internal/errs"
 	"github.com/Jack-samu/the-blog-backend-gin.git/internal/models"
@@ -20,7 +23,7 @@ import (
 )
 
 func (s *Service) Register(username, email, password, bio, avatar string) *errs.ErrorResp {
-	exists, err := s.r.ExistByEmail(email)
+	exists, err := s.r.ExistByEmail(nil, email)
 	if err != nil {
 		log.Printf("用户查询出错：%s\n", err.Error())
 		return errs.NewError(http.StatusInternalServerError, "用户查询出错", err)
@@ -50,6 +53,7 @@ func (s *Service) Register(username, email, password, bio, avatar string) *errs.
 		Avatar:        avatar,
 		FailedLogin:   0,
 		CaptchaReqCnt: 0,
+		Role:          models.RoleAuthor,
 		CreatedAt:     time.Now(),
 		LastActivity:  time.Now(),
 	}
@@ -60,7 +64,7 @@ func (s *Service) Register(username, email, password, bio, avatar string) *errs.
 		return errs.NewError(http.StatusInternalServerError, "用户注册中哈希加密出错", err)
 	}
 
-	if err = s.r.CreateUser(user); err != nil {
+	if err = s.r.CreateUser(nil, user); err != nil {
 		log.Printf("user创建出错：%s\n", err.Error())
 		return errs.NewError(http.StatusInternalServerError, "用户注册失败", err)
 	}
@@ -73,34 +77,98 @@ func (s *Service) Register(username, email, password, bio, avatar string) *errs.
 		}
 	}
 
+	// 验证邮件发送失败不影响注册，用户可以登录后重发
+	if err = s.sendVerification(user); err != nil {
+		log.Printf("验证邮件发送失败：%s\n", err.Error())
+	}
+
 	return nil
 
 }
 
-func (s *Service) Login(username, password string) (*dtos.LoginResp, *errs.ErrorResp) {
+// ip用于按来源限制登录失败次数，为空时不做限制；ip和userAgent同时记录到会话中
+func (s *Service) Login(username, password, ip, userAgent string) (*dtos.LoginResp, *errs.ErrorResp) {
+
+	if !s.ip.allow(ip) {
+		log.Printf("来源%s登录失败次数过多\n", ip)
+		return nil, errs.NewError(http.StatusTooManyRequests, "登录尝试过于频繁，请稍后再试", nil)
+	}
 
 	// 用户查询
 	user, err := s.r.GetUserByName(username)
 	if err != nil && user == nil {
 		log.Printf("用户查询出错：%s\n", err.Error())
 		if errors.Is(err, gorm.ErrRecordNotFound) {
+			s.ip.fail(ip)
 			return nil, errs.NewError(http.StatusBadRequest, "用户不存在", nil)
 		}
 		return nil, errs.NewError(http.StatusInternalServerError, "用户查询出错", err)
 	}
 
-	// 登录锁定检查
-	if user.FailedLogin >= 5 {
-		log.Printf("用户'%s'的登录已锁定\n", username)
-		return nil, errs.NewError(http.StatusBadRequest, "该用户登录失败次数过多，已锁定，请明天再试", nil)
+	if user.SuspendedAt != nil {
+		log.Printf("用户'%s'已被封禁\n", username)
+		return nil, errs.NewError(http.StatusForbidden, "账号已被封禁", nil)
+	}
+
+	// 限流和锁定检查，锁定到期后自动解除
+	now := time.Now()
+	if errResp := s.checkAttempt(user, ip, now); errResp != nil {
+		return nil, errResp
 	}
 
 	if !user.CheckPassword(password) {
-		if err = s.r.IncreaseFailedLogin(user); err != nil {
-			log.Printf("用户登陆失败计数位重设失败：%s\n", err.Error())
+		if errResp := s.attemptFailed(user, ip, now); errResp != nil {
+			return nil, errResp
 		}
 		return nil, errs.NewError(http.StatusBadRequest, "密码错误", nil)
 	}
+	s.attemptPassed(user, ip)
+
+	return s.firstFactorPassed(user, ip, userAgent)
+}
+
+// 密码或第三方登录通过后，开启了两步验证的用户只拿到挑战token，验证码通过后才登录
+func (s *Service) firstFactorPassed(user *models.User, ip, userAgent string) (*dtos.LoginResp, *errs.ErrorResp) {
+	if user.TOTPEnabledAt != nil {
+		challenge, err := utils.GenerateToken(user.ID, envDuration("TWO_FACTOR_CHALLENGE_TTL", 5*time.Minute),
+			utils.WithType(utils.TokenChallenge))
+		if err != nil {
+			log.Printf("token生成报错：%s\n", err.Error())
+			return nil, errs.NewError(http.StatusInternalServerError, "token生成失败，请重试", err)
+		}
+		return &dtos.LoginResp{TwoFactorRequired: true, ChallengeToken: challenge}, nil
+	}
+
+	return s.completeLogin(user, ip, userAgent)
+}
+
+// 记录一次登录失败，达到阈值时锁定并返回锁定的错误
+func (s *Service) loginFailed(user *models.User, now time.Time) *errs.ErrorResp {
+	failed, err := s.r.IncreaseFailedLogin(user)
+	if err != nil {
+		log.Printf("用户登陆失败计数位重设失败：%s\n", err.Error())
+		return nil
+	}
+
+	d := s.lock.lockFor(failed)
+	if d == 0 {
+		return nil
+	}
+	lockedUntil := now.Add(d)
+	if err = s.r.LockLogin(user, lockedUntil); err != nil {
+		log.Printf("用户锁定失败：%s\n", err.Error())
+	}
+	log.Printf("用户'%s'连续登录失败%d次，锁定至%s\n", user.Username, failed, lockedUntil.String())
+	return lockedError(lockedUntil)
+}
+
+// 认证全部通过后清除失败计数并开启新会话
+func (s *Service) completeLogin(user *models.User, ip, userAgent string) (*dtos.LoginResp, *errs.ErrorResp) {
+	if user.FailedLogin > 0 || user.LockedUntil != nil {
+		if err := s.r.ClearLoginLock(user); err != nil {
+			log.Printf("用户登录失败计数清除失败：%s\n", err.Error())
+		}
+	}
 
 	posts, err := s.r.GetUserPosts(user.ID)
 	if err != nil {
@@ -108,66 +176,76 @@ func (s *Service) Login(username, password string) (*dtos.LoginResp, *errs.Error
 		posts = 0
 	}
 
-	// token生成
-	token, err := utils.GenerateToken(user.ID, time.Hour)
+	// token生成，每次登录开启一个新会话
+	var token, refreshToken string
+	err = s.r.Transaction(func(tx *gorm.DB) error {
+		token, refreshToken, err = s.startSession(tx, user, ip, userAgent)
+		return err
+	})
 	if err != nil {
 		log.Printf("token生成报错：%s\n", err.Error())
 		return nil, errs.NewError(http.StatusInternalServerError, "token生成失败，请重试", err)
 	}
 
-	// refreshtoken生成
-	refreshToken, err := utils.GenerateToken(user.ID, 24*time.Hour)
-	if err != nil {
-		log.Printf("refreshtoken生成报错：%s\n", err.Error())
-		return nil, errs.NewError(http.StatusInternalServerError, "refreshToken生成失败，请重试", err)
-	}
-
 	return &dtos.LoginResp{
 		Token:        token,
 		RefreshToken: refreshToken,
 		UserInfo: &dtos.UserInfo{
-			ID:       user.ID,
-			Username: user.Username,
-			Email:    user.Email,
-			Avatar:   user.Avatar,
-			Posts:    posts,
+			ID:        user.ID,
+			Username:  user.Username,
+			Email:     user.Email,
+			Avatar:    user.Avatar,
+			Role:      user.Role,
+			Verified:  user.EmailVerifiedAt != nil,
+			TwoFactor: user.TOTPEnabledAt != nil,
+			Posts:     posts,
 		},
 	}, nil
 }
 
-func (s *Service) RefreshTheToken(id string) (*dtos.RefreshResp, *errs.ErrorResp) {
-	// 查询用户是否存在
-	user, err := s.r.GetUserById(id)
-	if err != nil && user == nil {
-		log.Printf("用户查询出错：%s\n", err.Error())
+func lockedError(until time.Time) *errs.ErrorResp {
+	return errs.NewError(http.StatusLocked,
+		fmt.Sprintf("该用户登录失败次数过多，已锁定至%s", until.Format(time.DateTime)), nil)
+}
+
+// 鉴权中间件在token解析通过后调用，拒绝已删除或被封禁用户的token；
+// 返回数据库中的当前角色，不信任token里的角色声明
+func (s *Service) ValidateToken(payload *utils.Payload) (string, *errs.ErrorResp) {
+	user, err := s.r.GetUserById(nil, payload.ID)
+	if err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
-			return nil, errs.NewError(http.StatusBadRequest, "用户不存在", nil)
+			return "", errs.NewError(http.StatusUnauthorized, "用户不存在", nil)
 		}
-		return nil, errs.NewError(http.StatusInternalServerError, "用户查询出错", err)
+		log.Printf("用户查询出错：%s\n", err.Error())
+		return "", errs.NewError(http.StatusInternalServerError, "用户查询出错", err)
 	}
 
-	posts, err := s.r.GetUserPosts(user.ID)
-	if err != nil {
-		log.Printf("获取用户文章数量失败：%s\n", err.Error())
-		posts = 0
+	if user.SuspendedAt != nil {
+		return "", errs.NewError(http.StatusForbidden, "账号已被封禁", nil)
 	}
 
-	token, err := utils.GenerateToken(id, time.Hour)
-	if err != nil {
-		log.Printf("token生成报错：%s\n", err.Error())
-		return nil, errs.NewError(http.StatusInternalServerError, "token生成失败，请重试", err)
+	// 会话被吊销或过期后，该会话签发的access token一并失效
+	if payload.SessionID == "" {
+		return "", errs.NewError(http.StatusUnauthorized, "登录已失效，请重新登录", nil)
+	}
+	session, err := s.r.GetSession(payload.SessionID)
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		log.Printf("会话状态查询出错：%s\n", err.Error())
+		return "", errs.NewError(http.StatusInternalServerError, "会话状态查询出错", err)
+	}
+	now := time.Now()
+	if session == nil || session.UserID != user.ID || !session.Active(now) {
+		return "", errs.NewError(http.StatusUnauthorized, "登录已失效，请重新登录", nil)
 	}
 
-	return &dtos.RefreshResp{
-		Token: token,
-		UserInfo: &dtos.UserInfo{
-			ID:       user.ID,
-			Username: user.Username,
-			Email:    user.Email,
-			Avatar:   user.Avatar,
-			Posts:    posts,
-		},
-	}, nil
+	// 活跃时间不需要太精确，避免每个请求都写库
+	if now.Sub(session.LastSeenAt) > sessionTouchInterval {
+		if err = s.r.TouchSession(nil, session.ID, nil); err != nil {
+			log.Printf("会话活跃时间更新失败：%s\n", err.Error())
+		}
+	}
+
+	return user.Role, nil
 }
 
 func (s *Service) SendCaptcha(username string) *errs.ErrorResp {
@@ -180,20 +258,12 @@ func (s *Service) SendCaptcha(username string) *errs.ErrorResp {
 		return errs.NewError(http.StatusInternalServerError, "", err)
 	}
 
-	// 检查是否发送过
-	val, ok := s.u.Load(user.ID)
-	if ok {
-		log.Println("加载存储的验证码结构体出错")
-		captcha, ok := val.(utils.Captcha)
-		if !ok {
-			log.Println("验证码结构体类型判断出错")
-			return errs.NewError(http.StatusInternalServerError, "加载存储的验证码报错", err)
-		}
-
-		// 检查是否还没过请求冷却
-		if captcha.Since.Before(time.Now()) {
+	// 检查是否还没过请求冷却
+	if val, ok := s.u.Load(user.ID); ok {
+		captcha, ok := val.(*utils.Captcha)
+		if ok && time.Since(captcha.Since) < envDuration("CAPTCHA_COOLDOWN", time.Minute) {
 			log.Println("还在冷却")
-			return errs.NewError(http.StatusBadRequest, "让我歇会", nil)
+			return errs.NewError(http.StatusTooManyRequests, "让我歇会", nil)
 		}
 	}
 
@@ -203,7 +273,6 @@ func (s *Service) SendCaptcha(username string) *errs.ErrorResp {
 		return errs.NewError(http.StatusInternalServerError, "token生成出错", err)
 	}
 
-	log.Println("生成的6位验证码：", captcha.Code)
 	s.u.Store(user.ID, captcha)
 
 	// 验证码邮件发送
@@ -223,6 +292,7 @@ func (s *Service) SendCaptcha(username string) *errs.ErrorResp {
 	return nil
 }
 
+// 校验验证码，通过后发送一次性的密码重设链接，之前发出的链接随之失效
 func (s *Service) Verify(username, code string) *errs.ErrorResp {
 	user, err := s.r.GetUserByName(username)
 	if err != nil {
@@ -235,14 +305,13 @@ func (s *Service) Verify(username, code string) *errs.ErrorResp {
 
 	val, ok := s.u.Load(user.ID)
 	if !ok {
-		log.Println("验证码结构体类型判断出错")
-		return errs.NewError(http.StatusInternalServerError, "加载存储的验证码报错", err)
+		return errs.NewError(http.StatusBadRequest, "请先获取验证码", nil)
 	}
 
-	captcha, ok := val.(utils.Captcha)
+	captcha, ok := val.(*utils.Captcha)
 	if !ok {
 		log.Println("验证码结构体类型判断出错")
-		return errs.NewError(http.StatusInternalServerError, "加载存储的验证码报错", err)
+		return errs.NewError(http.StatusInternalServerError, "加载存储的验证码报错", nil)
 	}
 
 	if captcha.Since.Add(10 * time.Minute).Before(time.Now()) {
@@ -255,13 +324,26 @@ func (s *Service) Verify(username, code string) *errs.ErrorResp {
 		return errs.NewError(http.StatusBadRequest, "验证码错误", nil)
 	}
 
-	token, err := utils.GenerateToken(user.ID, 20*time.Minute)
+	// 验证码只能用一次
+	s.u.Delete(user.ID)
+
+	var token string
+	ttl := envDuration("PASSWORD_RESET_TTL", 20*time.Minute)
+	err = s.r.Transaction(func(tx *gorm.DB) error {
+		if err := s.r.InvalidateUserTokens(tx, user.ID, models.PurposeResetPassword); err != nil {
+			return err
+		}
+
+		var err error
+		token, err = s.issueLink(tx, user.ID, models.PurposeResetPassword, "", ttl)
+		return err
+	})
 	if err != nil {
-		log.Printf("token生成报错：%s\n", err.Error())
+		log.Printf("密码重设链接生成出错：%s\n", err.Error())
 		return errs.NewError(http.StatusInternalServerError, "token生成失败，请重试", err)
 	}
 
-	err = utils.SendResetLink(user.Email, token)
+	err = utils.SendResetLink(user.Email, publicBaseURL()+"/auth/reset/"+url.PathEscape(token), ttl)
 	if err != nil {
 		log.Printf("密码重置邮件发送失败：%s\n", err.Error())
 		return errs.NewError(http.StatusInternalServerError, "密码重置邮件发送失败", err)
@@ -270,39 +352,68 @@ func (s *Service) Verify(username, code string) *errs.ErrorResp {
 	return nil
 }
 
-func (s *Service) Reset(userID, pwd string) *errs.ErrorResp {
-	user, err := s.r.GetUserById(userID)
-	if err != nil {
-		log.Printf("用户查询出错：%v\n", err)
-		return errs.NewError(http.StatusInternalServerError, "用户查询出错，请重试", err)
-	}
-
+// 凭重设链接中的token修改密码，token只能使用一次；修改后吊销该用户的所有会话
+func (s *Service) Reset(token, pwd string) *errs.ErrorResp {
 	if len(pwd) < 8 {
 		log.Println("密码长度不该小于8")
 		return errs.NewError(http.StatusBadRequest, "密码长度不该小于8", nil)
 	}
 
-	if user.CheckPassword(pwd) {
-		log.Println("完美，密码找回来了")
-		return errs.NewError(http.StatusBadRequest, "好啊，原密码", nil)
-	}
+	err := s.r.Transaction(func(tx *gorm.DB) error {
+		record, err := s.consumeLink(tx, token, models.PurposeResetPassword)
+		if err != nil {
+			return err
+		}
+
+		user, err := s.r.GetUserById(tx, record.UserID)
+		if err != nil {
+			return err
+		}
+		if user.CheckPassword(pwd) {
+			log.Println("完美，密码找回来了")
+			return deny(errs.NewError(http.StatusBadRequest, "好啊，原密码", nil))
+		}
+
+		if err = user.SetPassword(pwd); err != nil {
+			return err
+		}
+		if err = s.r.UpdatePassword(tx, user.ID, user.Pwd); err != nil {
+			return err
+		}
+
+		_, err = s.r.RevokeOtherSessions(tx, user.ID, "")
+		return err
+	})
 
-	err = user.SetPassword(pwd)
 	if err != nil {
-		log.Printf("新密码设置出错：%s\n", err.Error())
+		if errResp, ok := asDenied(err); ok {
+			return errResp
+		}
+		if errors.Is(err, dao.ErrTokenUsed) {
+			return errs.NewError(http.StatusBadRequest, "token无效或者已过期", nil)
+		}
+		log.Printf("密码重设出错：%s\n", err.Error())
 		return errs.NewError(http.StatusInternalServerError, "", err)
 	}
 
 	return nil
 }
 
-func (s *Service) Logout(id string) (string, *errs.ErrorResp) {
-	user, err := s.r.GetUserById(id)
+// 退出登录时吊销当前会话的refresh token
+func (s *Service) Logout(id, sessionID string) (string, *errs.ErrorResp) {
+	user, err := s.r.GetUserById(nil, id)
 	if err != nil {
 		log.Printf("用户查询出错：%v\n", err)
 		return "", errs.NewError(http.StatusInternalServerError, "用户查询出错，请重试", err)
 	}
 
+	if sessionID != "" {
+		if _, err = s.r.RevokeSession(nil, id, sessionID); err != nil {
+			log.Printf("会话吊销出错：%s\n", err.Error())
+			return "", errs.NewError(http.StatusInternalServerError, "会话吊销出错", err)
+		}
+	}
+
 	last_activity, err := s.r.SetLastActivity(user)
 	if err != nil {
 		log.Printf("用户最后活动时间设置出错：%s\n", err.Error())
@@ -311,38 +422,6 @@ func (s *Service) Logout(id string) (string, *errs.ErrorResp) {
 	return last_activity, nil
 }
 
-func (s *Service) Profile(id string) (*dtos.ProfileResp, *errs.ErrorResp) {
-	user, err := s.r.GetUserById(id)
-	if err != nil && user == nil {
-		log.Printf("用户查询出错：%s\n", err.Error())
-		if errors.Is(err, gorm.ErrRecordNotFound) {
-			return nil, errs.NewError(http.StatusBadRequest, "用户不存在", nil)
-		}
-		return nil, errs.NewError(http.StatusInternalServerError, "用户查询出错", err)
-	}
-
-	posts, err := s.r.GetUserPosts(id)
-	if err != nil {
-		log.Printf("用户已发表文章数查询报错：%s\n", err.Error())
-		posts = 0
-	}
-
-	drafts, err := s.r.GetUserDrafts(id)
-	if err != nil {
-		log.Printf("用户草稿数查询报错：%s\n", err.Error())
-		drafts = 0
-	}
-
-	return &dtos.ProfileResp{
-		ID:       user.ID,
-		Username: user.Username,
-		Email:    user.Email,
-		Articles: posts,
-		Drafts:   drafts,
-		Avatar:   user.Avatar,
-	}, nil
-}
-
 func (s *Service) GetPhotos(id string) (*dtos.PhotosResp, *errs.ErrorResp) {
 	imgs, err := s.r.GetUserPhotos(id)
 	if err != nil {
@@ -414,7 +493,7 @@ func (s *Service) SetAvatar(file *multipart.FileHeader, imageType, uploadPath, u
 		return "", err
 	}
 
-	if user, err := s.r.GetUserById(userID); err != nil {
+	if user, err := s.r.GetUserById(nil, userID); err != nil {
 		log.Printf("用户查询不能：%s\n", err.Error())
 		return "", errs.NewError(http.StatusBadRequest, "用户无法查询", nil)
 	} else {
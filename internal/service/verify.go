@@ -0,0 +1,196 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	dao "github.com/Jack-samu/the-blog-backend-gin.git/internal/DAO"
+	"github.com/Jack-samu/the-blog-backend-gin.git/internal/errs"
+	"github.com/Jack-samu/the-blog-backend-gin.git/internal/models"
+	"github.com/Jack-samu/the-blog-backend-gin.git/internal/utils"
+	"gorm.io/gorm"
+)
+
+// 未验证邮箱的用户可能被限制的操作，通过UNVERIFIED_RESTRICTIONS配置，
+// 多个用逗号分隔，配置为none表示不做限制
+const (
+	ActionPublish = "publish"
+	ActionComment = "comment"
+)
+
+var actionNames = map[string]string{
+	ActionPublish: "发布文章",
+	ActionComment: "发表评论",
+}
+
+func loadRestrictions() map[string]bool {
+	val, ok := os.LookupEnv("UNVERIFIED_RESTRICTIONS")
+	if !ok {
+		val = ActionPublish + "," + ActionComment
+	}
+
+	restrict := make(map[string]bool)
+	for _, action := range strings.Split(val, ",") {
+		action = strings.TrimSpace(action)
+		if _, ok := actionNames[action]; ok {
+			restrict[action] = true
+		} else if action != "" && action != "none" {
+			log.Printf("未知的受限操作：%s\n", action)
+		}
+	}
+	return restrict
+}
+
+// 邮件中链接指向的站点地址
+func publicBaseURL() string {
+	base := os.Getenv("PUBLIC_BASE_URL")
+	if base == "" {
+		base = "http://localhost:8080"
+	}
+	return strings.TrimRight(base, "/")
+}
+
+// 签发一次性链接的随机token，只落库哈希；data随记录保存，核销时取回
+func (s *Service) issueLink(tx *gorm.DB, userID, purpose, data string, ttl time.Duration) (string, error) {
+	token, err := utils.RandomString(32)
+	if err != nil {
+		return "", err
+	}
+
+	err = s.r.CreateUserToken(tx, &models.UserToken{
+		UserID:    userID,
+		Purpose:   purpose,
+		TokenHash: utils.HashToken(token),
+		Data:      data,
+		ExpiresAt: time.Now().Add(ttl),
+	})
+	if err != nil {
+		return "", err
+	}
+
+	return token, nil
+}
+
+// 核销一次性链接的token，返回对应的记录
+func (s *Service) consumeLink(tx *gorm.DB, token, purpose string) (*models.UserToken, error) {
+	if token == "" {
+		return nil, dao.ErrTokenUsed
+	}
+
+	record, err := s.r.ConsumeUserToken(tx, utils.HashToken(token), purpose)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, dao.ErrTokenUsed
+		}
+		return nil, err
+	}
+
+	return record, nil
+}
+
+func (s *Service) sendVerification(user *models.User) error {
+	token, err := s.issueLink(nil, user.ID, models.PurposeVerifyEmail, "",
+		envDuration("EMAIL_VERIFY_TTL", 24*time.Hour))
+	if err != nil {
+		return err
+	}
+
+	return utils.SendVerifyLink(user.Email, publicBaseURL()+"/auth/verify-email?token="+url.QueryEscape(token))
+}
+
+func (s *Service) VerifyEmail(token string) *errs.ErrorResp {
+	err := s.r.Transaction(func(tx *gorm.DB) error {
+		record, err := s.consumeLink(tx, token, models.PurposeVerifyEmail)
+		if err != nil {
+			return err
+		}
+		return s.r.SetEmailVerified(tx, record.UserID)
+	})
+
+	if err != nil {
+		if errors.Is(err, dao.ErrTokenUsed) {
+			return errs.NewError(http.StatusBadRequest, "验证链接无效或已过期", nil)
+		}
+		log.Printf("邮箱验证出错：%s\n", err.Error())
+		return errs.NewError(http.StatusInternalServerError, "", err)
+	}
+
+	return nil
+}
+
+// 重发验证邮件，之前发出的链接随之失效
+func (s *Service) ResendVerification(userID string) *errs.ErrorResp {
+	user, err := s.r.GetUserById(nil, userID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return errs.NewError(http.StatusNotFound, "用户不存在", nil)
+		}
+		log.Printf("用户查询出错：%s\n", err.Error())
+		return errs.NewError(http.StatusInternalServerError, "用户查询出错", err)
+	}
+
+	if user.EmailVerifiedAt != nil {
+		return errs.NewError(http.StatusBadRequest, "邮箱已验证", nil)
+	}
+
+	if errResp := s.checkResendCooldown(user.ID, models.PurposeVerifyEmail); errResp != nil {
+		return errResp
+	}
+
+	if err = s.r.InvalidateUserTokens(nil, user.ID, models.PurposeVerifyEmail); err != nil {
+		log.Printf("旧验证链接作废出错：%s\n", err.Error())
+		return errs.NewError(http.StatusInternalServerError, "", err)
+	}
+
+	if err = s.sendVerification(user); err != nil {
+		log.Printf("验证邮件发送失败：%s\n", err.Error())
+		return errs.NewError(http.StatusInternalServerError, "验证邮件发送失败", err)
+	}
+
+	return nil
+}
+
+// 同一用途的邮件在冷却时间内不能重复发送
+func (s *Service) checkResendCooldown(userID, purpose string) *errs.ErrorResp {
+	cooldown := envDuration("EMAIL_RESEND_COOLDOWN", time.Minute)
+	last, err := s.r.GetLatestUserToken(userID, purpose)
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		log.Printf("链接记录查询出错：%s\n", err.Error())
+		return errs.NewError(http.StatusInternalServerError, "", err)
+	}
+	if last != nil {
+		if wait := cooldown - time.Since(last.CreatedAt); wait > 0 {
+			return errs.NewError(http.StatusTooManyRequests,
+				fmt.Sprintf("请求过于频繁，请%d秒后再试", int(wait.Seconds())+1), nil)
+		}
+	}
+	return nil
+}
+
+// 按配置限制未验证邮箱的用户
+func (s *Service) checkVerified(userID, action string) *errs.ErrorResp {
+	if !s.restrict[action] {
+		return nil
+	}
+
+	user, err := s.r.GetUserById(nil, userID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return errs.NewError(http.StatusUnauthorized, "用户不存在", nil)
+		}
+		log.Printf("用户查询出错：%s\n", err.Error())
+		return errs.NewError(http.StatusInternalServerError, "用户查询出错", err)
+	}
+
+	if user.EmailVerifiedAt == nil {
+		return errs.NewError(http.StatusForbidden, "邮箱未验证，暂时无法"+actionNames[action], nil)
+	}
+
+	return nil
+}
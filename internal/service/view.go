@@ -0,0 +1,132 @@
+package service
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+)
+
+// 浏览量的内存缓冲，同一访问者在去重窗口内对同一文章只计一次，
+// 累积的增量由后台定时批量写入数据库，避免热门文章每次访问都去抢行锁
+type viewCounter struct {
+	mu      sync.Mutex
+	window  time.Duration
+	seen    map[string]time.Time
+	pending map[uint]int
+}
+
+func newViewCounter(window time.Duration) *viewCounter {
+	return &viewCounter{
+		window:  window,
+		seen:    make(map[string]time.Time),
+		pending: make(map[uint]int),
+	}
+}
+
+func (v *viewCounter) record(postID uint, visitor string) bool {
+	key := fmt.Sprintf("%d:%s", postID, visitor)
+	now := time.Now()
+
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	if last, ok := v.seen[key]; ok && now.Sub(last) < v.window {
+		return false
+	}
+	v.seen[key] = now
+	v.pending[postID]++
+	return true
+}
+
+func (v *viewCounter) pendingOf(postID uint) int {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	return v.pending[postID]
+}
+
+// 取出待写入的增量，同时清理过期的去重记录
+func (v *viewCounter) drain() map[uint]int {
+	now := time.Now()
+
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	for key, last := range v.seen {
+		if now.Sub(last) >= v.window {
+			delete(v.seen, key)
+		}
+	}
+
+	if len(v.pending) == 0 {
+		return nil
+	}
+	counts := v.pending
+	v.pending = make(map[uint]int)
+	return counts
+}
+
+// 写库失败时把增量放回缓冲，等下一轮再试
+func (v *viewCounter) restore(counts map[uint]int) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	for id, n := range counts {
+		v.pending[id] += n
+	}
+}
+
+// 记录一次文章浏览，登录用户按用户id去重，游客按ip和user-agent的指纹去重
+func (s *Service) RecordView(postID uint, userID, ip, userAgent string) {
+	visitor := "u:" + userID
+	if userID == "" {
+		sum := sha256.Sum256([]byte(ip + "|" + userAgent))
+		visitor = "a:" + hex.EncodeToString(sum[:])
+	}
+
+	s.v.record(postID, visitor)
+}
+
+// 将缓冲中的浏览量批量写入数据库
+func (s *Service) FlushViews() error {
+	counts := s.v.drain()
+	if counts == nil {
+		return nil
+	}
+
+	if err := s.r.IncreaseViews(counts); err != nil {
+		log.Printf("浏览量写入出错，等待下次重试：%s\n", err.Error())
+		s.v.restore(counts)
+		return err
+	}
+
+	return nil
+}
+
+// 启动后台定时写入，返回的函数用于停止并写入剩余的浏览量
+func (s *Service) StartViewFlusher() func() {
+	ticker := time.NewTicker(envDuration("VIEW_FLUSH_INTERVAL", 10*time.Second))
+	done := make(chan struct{})
+	stopped := make(chan struct{})
+
+	go func() {
+		defer close(stopped)
+		for {
+			select {
+			case <-ticker.C:
+				s.FlushViews()
+			case <-done:
+				ticker.Stop()
+				s.FlushViews()
+				return
+			}
+		}
+	}()
+
+	return func() {
+		close(done)
+		<-stopped
+	}
+}
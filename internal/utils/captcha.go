@@ -19,6 +19,7 @@ type Captcha struct {
 	Since time.Time
 }
 
+// 按用户ID存放*Captcha
 type UserReset struct {
 	sync.Map
 }
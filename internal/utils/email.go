@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"os"
 	"strconv"
+	"time"
 
 	"gopkg.in/gomail.v2"
 )
@@ -18,6 +19,9 @@ type EmailConfig struct {
 
 var e EmailConfig
 
+// 实际发送邮件的函数，测试中可以替换掉，避免真的连接邮件服务器
+var SendMail = sendEmail
+
 func InitEmailConfig() {
 	port, _ := strconv.Atoi(os.Getenv("MAIL_PORT"))
 	e = EmailConfig{
@@ -41,9 +45,20 @@ func sendEmail(to, body, subject string) error {
 	return d.DialAndSend(m)
 }
 
-func SendResetLink(to, resetToken string) error {
+// 链接有效期按整小时、分钟或秒显示
+func formatTTL(ttl time.Duration) string {
+	switch {
+	case ttl >= time.Hour && ttl%time.Hour == 0:
+		return fmt.Sprintf("%d小时", ttl/time.Hour)
+	case ttl >= time.Minute:
+		return fmt.Sprintf("%d分钟", (ttl+time.Minute-1)/time.Minute)
+	default:
+		return fmt.Sprintf("%d秒", (ttl+time.Second-1)/time.Second)
+	}
+}
+
+func SendResetLink(to, resetLink string, ttl time.Duration) error {
 
-	resetLink := fmt.Sprintf("http://192.168.1.10:8080/auth/reset/%s", resetToken)
 	body := fmt.Sprintf(`
 		<html>
 			<body>
@@ -51,12 +66,12 @@ func SendResetLink(to, resetToken string) error {
 				<p>请复制下面的链接到浏览器满足你重置的愿望：</p>
 				<p>%s</p>
 				<p>如果这不是你发起的请求，请忽略此邮件</p>
-				<p>此链接将在15分钟后失效。</p>
+				<p>此链接只能使用一次，将在%s后失效。</p>
 			</body>
 		</html>
-	`, resetLink)
+	`, resetLink, formatTTL(ttl))
 
-	return sendEmail(to, body, "密码重置邮件")
+	return SendMail(to, body, "密码重置邮件")
 }
 
 func SendCaptcha(to, captcha string) error {
@@ -70,5 +85,52 @@ func SendCaptcha(to, captcha string) error {
 		</html>
 	`, captcha, captcha)
 
-	return sendEmail(to, body, "身份校验邮件")
+	return SendMail(to, body, "身份校验邮件")
+}
+
+func SendVerifyLink(to, link string) error {
+
+	body := fmt.Sprintf(`
+		<html>
+			<body>
+				<h2>邮箱验证</h2>
+				<p>请点击或复制下面的链接到浏览器完成邮箱验证：</p>
+				<p><a href="%s">%s</a></p>
+				<p>如果这不是你注册的账号，请忽略此邮件</p>
+			</body>
+		</html>
+	`, link, link)
+
+	return SendMail(to, body, "邮箱验证邮件")
+}
+
+func SendChangeEmailLink(to, link string) error {
+
+	body := fmt.Sprintf(`
+		<html>
+			<body>
+				<h2>确认新邮箱</h2>
+				<p>你正在把账号邮箱修改为此地址，请点击或复制下面的链接到浏览器完成确认：</p>
+				<p><a href="%s">%s</a></p>
+				<p>如非本人操作请忽略此邮件</p>
+			</body>
+		</html>
+	`, link, link)
+
+	return SendMail(to, body, "邮箱修改确认邮件")
+}
+
+func SendEmailChangeNotice(to, newEmail string) error {
+
+	body := fmt.Sprintf(`
+		<html>
+			<body>
+				<h2>邮箱修改提醒</h2>
+				<p>你的账号申请把邮箱修改为<strong>%s</strong>，新邮箱确认后此地址将不再接收账号邮件。</p>
+				<p>如非本人操作，请尽快修改密码</p>
+			</body>
+		</html>
+	`, newEmail)
+
+	return SendMail(to, body, "邮箱修改提醒")
 }
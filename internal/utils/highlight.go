@@ -0,0 +1,77 @@
+package utils
+
+import (
+	"html"
+	"strings"
+	"unicode"
+)
+
+// 逐个rune转小写，文本和搜索词用同一种方式处理，保证命中位置和原文的rune一一对应
+func lowerRunes(s string) []rune {
+	runes := []rune(s)
+	for i, r := range runes {
+		runes[i] = unicode.ToLower(r)
+	}
+	return runes
+}
+
+// 在文本中用<mark>标记搜索词，radius>0时只截取首个命中附近的片段，
+// 返回内容已做html转义，没有命中时返回空字符串
+func Highlight(text string, terms []string, radius int) string {
+	runes := []rune(text)
+	lower := lowerRunes(text)
+
+	// 标记每个位置是否属于命中词
+	marked := make([]bool, len(runes))
+	first := -1
+	for _, term := range terms {
+		t := lowerRunes(term)
+		if len(t) == 0 {
+			continue
+		}
+		for i := 0; i+len(t) <= len(lower); i++ {
+			if string(lower[i:i+len(t)]) != string(t) {
+				continue
+			}
+			for j := i; j < i+len(t); j++ {
+				marked[j] = true
+			}
+			if first == -1 || i < first {
+				first = i
+			}
+		}
+	}
+
+	if first == -1 {
+		return ""
+	}
+
+	start, end := 0, len(runes)
+	if radius > 0 {
+		start = max(first-radius, 0)
+		end = min(first+radius, len(runes))
+	}
+
+	var b strings.Builder
+	if start > 0 {
+		b.WriteString("...")
+	}
+	for i := start; i < end; {
+		j := i
+		for j < end && marked[j] == marked[i] {
+			j++
+		}
+		segment := html.EscapeString(string(runes[i:j]))
+		if marked[i] {
+			b.WriteString("<mark>" + segment + "</mark>")
+		} else {
+			b.WriteString(segment)
+		}
+		i = j
+	}
+	if end < len(runes) {
+		b.WriteString("...")
+	}
+
+	return b.String()
+}
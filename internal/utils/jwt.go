@@ -1,6 +1,8 @@
 package utils
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
 	"log"
 	"time"
 
@@ -8,30 +10,66 @@ import (
 	"github.com/google/uuid"
 )
 
-var secretKey = []byte("blog-back")
+// token类型，鉴权中间件只接受access类型
+const (
+	TokenAccess  = "access"
+	TokenRefresh = "refresh"
+	// 两步验证登录中间步骤的挑战token
+	TokenChallenge = "2fa_challenge"
+)
 
 type Payload struct {
-	ID string
+	ID        string
+	Role      string `json:"role,omitempty"`
+	Type      string `json:"typ"`
+	SessionID string `json:"sid,omitempty"`
 	jwt.RegisteredClaims
 }
 
-func GenerateToken(userID string, t time.Duration) (string, error) {
+// 生成token时附加的可选信息
+type TokenOption func(*Payload)
+
+func WithRole(role string) TokenOption {
+	return func(p *Payload) {
+		p.Role = role
+	}
+}
+
+func WithType(typ string) TokenOption {
+	return func(p *Payload) {
+		p.Type = typ
+	}
+}
+
+// 同一次登录签发的token共用一个会话标识，退出登录时按会话吊销
+func WithSession(sessionID string) TokenOption {
+	return func(p *Payload) {
+		p.SessionID = sessionID
+	}
+}
+
+// 默认签发access类型的token
+func GenerateToken(userID string, t time.Duration, opts ...TokenOption) (string, error) {
+	ks := currentKeys()
 	payload := &Payload{
-		ID: userID,
+		ID:   userID,
+		Type: TokenAccess,
 		RegisteredClaims: jwt.RegisteredClaims{
 			ID:        uuid.NewString(), // 加个唯一标识
 			ExpiresAt: jwt.NewNumericDate(time.Now().Add(t)),
 			IssuedAt:  jwt.NewNumericDate(time.Now()),
 			NotBefore: jwt.NewNumericDate(time.Now()),
-			Issuer:    "babalababa",
+			Issuer:    ks.issuer,
 		},
 	}
+	for _, opt := range opts {
+		opt(payload)
+	}
 
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, payload)
-	tokenString, err := token.SignedString(secretKey)
-	log.Printf("用户'%s'token生成：%s\n", userID, tokenString)
-
-	return tokenString, err
+	// 头部带上kid，校验时据此找到对应的密钥
+	token := jwt.NewWithClaims(ks.active.method, payload)
+	token.Header["kid"] = ks.active.kid
+	return token.SignedString(ks.active.sign)
 }
 
 func ParseToken(tokenString string) (*Payload, error) {
@@ -39,13 +77,10 @@ func ParseToken(tokenString string) (*Payload, error) {
 		return nil, jwt.ErrInvalidKey
 	}
 
-	token, err := jwt.ParseWithClaims(tokenString, &Payload{}, func(token *jwt.Token) (interface{}, error) {
-		// 签名验证
-		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-			return nil, jwt.ErrSignatureInvalid
-		}
-		return secretKey, nil
-	})
+	ks := currentKeys()
+	token, err := jwt.ParseWithClaims(tokenString, &Payload{}, ks.keyFunc,
+		jwt.WithIssuer(ks.issuer),
+		jwt.WithValidMethods([]string{AlgHS256, AlgRS256, AlgEdDSA}))
 
 	if err != nil {
 		return nil, err
@@ -64,3 +99,9 @@ func ParseToken(tokenString string) (*Payload, error) {
 
 	return payload, nil
 }
+
+// 服务端只保存token的哈希，数据库泄露时无法直接拿来使用
+func HashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
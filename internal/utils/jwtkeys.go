@@ -0,0 +1,311 @@
+package utils
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const (
+	AlgHS256 = "HS256"
+	AlgRS256 = "RS256"
+	AlgEdDSA = "EdDSA"
+
+	defaultKID    = "default"
+	defaultIssuer = "babalababa"
+)
+
+// 单个密钥的配置。HS256使用Secret，RS256和EdDSA使用PEM格式的密钥文件；
+// 只配置公钥的密钥只能用来校验，适合轮换时保留旧密钥
+type KeyConfig struct {
+	KID            string `json:"kid"`
+	Alg            string `json:"alg"`
+	Secret         string `json:"secret,omitempty"`
+	PrivateKeyFile string `json:"private_key_file,omitempty"`
+	PublicKeyFile  string `json:"public_key_file,omitempty"`
+}
+
+// 密钥集合的配置，Active指定签发新token使用的密钥，其余密钥只用于校验
+type KeysConfig struct {
+	Issuer string      `json:"issuer,omitempty"`
+	Active string      `json:"active"`
+	Keys   []KeyConfig `json:"keys"`
+}
+
+type signingKey struct {
+	kid    string
+	method jwt.SigningMethod
+	sign   interface{}
+	verify interface{}
+}
+
+type keySet struct {
+	issuer string
+	active *signingKey
+	keys   map[string]*signingKey
+}
+
+var (
+	keysMu sync.RWMutex
+	keys   = defaultKeySet()
+)
+
+// 进程内随机生成的HS256密钥，只在调用InitJWTKeys之前（如测试中）使用，
+// 重启后失效，不能用于生产环境
+func defaultKeySet() *keySet {
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		panic("随机密钥生成失败：" + err.Error())
+	}
+
+	key := &signingKey{
+		kid:    defaultKID,
+		method: jwt.SigningMethodHS256,
+		sign:   secret,
+		verify: secret,
+	}
+	return &keySet{
+		issuer: defaultIssuer,
+		active: key,
+		keys:   map[string]*signingKey{defaultKID: key},
+	}
+}
+
+// 从环境变量加载密钥：JWT_KEYS_FILE指向json格式的KeysConfig，
+// 否则使用JWT_SECRET作为HS256密钥；都没有配置时返回错误，拒绝启动
+func InitJWTKeys() error {
+	if path := os.Getenv("JWT_KEYS_FILE"); path != "" {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return fmt.Errorf("读取密钥配置失败：%w", err)
+		}
+
+		var cfg KeysConfig
+		if err = json.Unmarshal(data, &cfg); err != nil {
+			return fmt.Errorf("解析密钥配置失败：%w", err)
+		}
+
+		// 密钥文件的相对路径以配置文件所在目录为准
+		dir := filepath.Dir(path)
+		for i := range cfg.Keys {
+			cfg.Keys[i].PrivateKeyFile = resolvePath(dir, cfg.Keys[i].PrivateKeyFile)
+			cfg.Keys[i].PublicKeyFile = resolvePath(dir, cfg.Keys[i].PublicKeyFile)
+		}
+		if cfg.Issuer == "" {
+			cfg.Issuer = os.Getenv("JWT_ISSUER")
+		}
+
+		return LoadKeys(&cfg)
+	}
+
+	if secret := os.Getenv("JWT_SECRET"); secret != "" {
+		return LoadKeys(&KeysConfig{
+			Issuer: os.Getenv("JWT_ISSUER"),
+			Active: defaultKID,
+			Keys:   []KeyConfig{{KID: defaultKID, Alg: AlgHS256, Secret: secret}},
+		})
+	}
+
+	return errors.New("未配置JWT密钥，请设置JWT_SECRET或JWT_KEYS_FILE")
+}
+
+func resolvePath(dir, path string) string {
+	if path == "" || filepath.IsAbs(path) {
+		return path
+	}
+	return filepath.Join(dir, path)
+}
+
+// 按配置替换当前的密钥集合，配置有误时保留原有密钥
+func LoadKeys(cfg *KeysConfig) error {
+	ks := &keySet{
+		issuer: cfg.Issuer,
+		keys:   make(map[string]*signingKey, len(cfg.Keys)),
+	}
+	if ks.issuer == "" {
+		ks.issuer = defaultIssuer
+	}
+
+	for _, kc := range cfg.Keys {
+		if kc.KID == "" {
+			return errors.New("密钥缺少kid")
+		}
+		if _, ok := ks.keys[kc.KID]; ok {
+			return fmt.Errorf("密钥kid重复：%s", kc.KID)
+		}
+
+		key, err := loadKey(kc)
+		if err != nil {
+			return fmt.Errorf("密钥%s加载失败：%w", kc.KID, err)
+		}
+		ks.keys[kc.KID] = key
+	}
+
+	active, ok := ks.keys[cfg.Active]
+	if !ok {
+		return fmt.Errorf("签发密钥%s不存在", cfg.Active)
+	}
+	if active.sign == nil {
+		return fmt.Errorf("签发密钥%s缺少私钥", cfg.Active)
+	}
+	ks.active = active
+
+	setKeySet(ks)
+	return nil
+}
+
+// 恢复为随机的默认密钥，测试使用
+func ResetKeys() {
+	setKeySet(defaultKeySet())
+}
+
+func setKeySet(ks *keySet) {
+	keysMu.Lock()
+	defer keysMu.Unlock()
+	keys = ks
+}
+
+func currentKeys() *keySet {
+	keysMu.RLock()
+	defer keysMu.RUnlock()
+	return keys
+}
+
+func loadKey(kc KeyConfig) (*signingKey, error) {
+	key := &signingKey{kid: kc.KID}
+
+	switch kc.Alg {
+	case AlgHS256:
+		if kc.Secret == "" {
+			return nil, errors.New("HS256密钥缺少secret")
+		}
+		key.method = jwt.SigningMethodHS256
+		key.sign = []byte(kc.Secret)
+		key.verify = key.sign
+		return key, nil
+	case AlgRS256:
+		key.method = jwt.SigningMethodRS256
+	case AlgEdDSA:
+		key.method = jwt.SigningMethodEdDSA
+	default:
+		return nil, fmt.Errorf("不支持的算法：%s", kc.Alg)
+	}
+
+	if kc.PrivateKeyFile != "" {
+		data, err := os.ReadFile(kc.PrivateKeyFile)
+		if err != nil {
+			return nil, err
+		}
+		if kc.Alg == AlgRS256 {
+			priv, err := jwt.ParseRSAPrivateKeyFromPEM(data)
+			if err != nil {
+				return nil, err
+			}
+			key.sign, key.verify = priv, &priv.PublicKey
+		} else {
+			priv, err := jwt.ParseEdPrivateKeyFromPEM(data)
+			if err != nil {
+				return nil, err
+			}
+			edKey, ok := priv.(ed25519.PrivateKey)
+			if !ok {
+				return nil, errors.New("不是Ed25519私钥")
+			}
+			key.sign, key.verify = edKey, edKey.Public()
+		}
+		return key, nil
+	}
+
+	if kc.PublicKeyFile != "" {
+		data, err := os.ReadFile(kc.PublicKeyFile)
+		if err != nil {
+			return nil, err
+		}
+		if kc.Alg == AlgRS256 {
+			key.verify, err = jwt.ParseRSAPublicKeyFromPEM(data)
+		} else {
+			key.verify, err = jwt.ParseEdPublicKeyFromPEM(data)
+		}
+		if err != nil {
+			return nil, err
+		}
+		return key, nil
+	}
+
+	return nil, errors.New("缺少密钥文件")
+}
+
+// 按token头部的kid查找校验密钥，没有kid的旧token使用默认密钥
+func (ks *keySet) keyFunc(token *jwt.Token) (interface{}, error) {
+	kid, _ := token.Header["kid"].(string)
+	if kid == "" {
+		kid = defaultKID
+	}
+
+	key, ok := ks.keys[kid]
+	if !ok {
+		return nil, jwt.ErrTokenUnverifiable
+	}
+	// 算法必须和密钥一致，防止用公钥当HMAC密钥伪造签名
+	if token.Method.Alg() != key.method.Alg() {
+		return nil, jwt.ErrSignatureInvalid
+	}
+
+	return key.verify, nil
+}
+
+// JWKS中的单个公钥，字段含义见RFC 7517
+type JWK struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	Alg string `json:"alg"`
+	N   string `json:"n,omitempty"`
+	E   string `json:"e,omitempty"`
+	Crv string `json:"crv,omitempty"`
+	X   string `json:"x,omitempty"`
+}
+
+type JWKSet struct {
+	Keys []JWK `json:"keys"`
+}
+
+// 导出所有非对称密钥的公钥，HS256密钥不对外公开
+func JWKS() JWKSet {
+	ks := currentKeys()
+	set := JWKSet{Keys: []JWK{}}
+
+	for kid, key := range ks.keys {
+		jwk := JWK{Kid: kid, Use: "sig", Alg: key.method.Alg()}
+		switch pub := key.verify.(type) {
+		case *rsa.PublicKey:
+			jwk.Kty = "RSA"
+			jwk.N = base64.RawURLEncoding.EncodeToString(pub.N.Bytes())
+			jwk.E = base64.RawURLEncoding.EncodeToString(big.NewInt(int64(pub.E)).Bytes())
+		case ed25519.PublicKey:
+			jwk.Kty = "OKP"
+			jwk.Crv = "Ed25519"
+			jwk.X = base64.RawURLEncoding.EncodeToString(pub)
+		default:
+			continue
+		}
+		set.Keys = append(set.Keys, jwk)
+	}
+
+	slices.SortFunc(set.Keys, func(a, b JWK) int {
+		return strings.Compare(a.Kid, b.Kid)
+	})
+	return set
+}
@@ -1,8 +1,15 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"log"
 	"net/http"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
 
 	dao "github.com/Jack-samu/the-blog-backend-gin.git/internal/DAO"
 	"github.com/Jack-samu/the-blog-backend-gin.git/internal/handler"
@@ -48,51 +55,129 @@ func main() {
 	// 异常机制
 	r.Use(customRecovery())
 
+	// 只信任配置的反向代理转发的X-Forwarded-For，未配置时直接取连接的来源地址，
+	// 避免伪造请求头绕过登录限流
+	var proxies []string
+	for _, p := range strings.Split(os.Getenv("TRUSTED_PROXIES"), ",") {
+		if p = strings.TrimSpace(p); p != "" {
+			proxies = append(proxies, p)
+		}
+	}
+	if err = r.SetTrustedProxies(proxies); err != nil {
+		log.Fatalf("可信代理配置有误：%s\n", err.Error())
+	}
+
 	// 邮件配置
 	utils.InitEmailConfig()
+	// jwt密钥配置
+	if err = utils.InitJWTKeys(); err != nil {
+		log.Fatalf("jwt密钥加载失败：%s\n", err.Error())
+	}
 	db := models.InitDB()
 
 	repository := dao.NewRepository(db)
 	service := service.NewService(repository)
 	handler := handler.NewHandler(service)
 
+	// 鉴权时拒绝已删除或被封禁用户的token
+	middleware.AddValidator(service.ValidateToken)
+
+	// 浏览量定时写库
+	stopViewFlusher := service.StartViewFlusher()
+
+	// 定时发布轮询
+	stopScheduler := service.StartScheduler()
+
 	// 路由注册
 	r.POST("/upload-img", handler.UploadImage)
-	r.GET("/articles/:id/comments", handler.GetComments)
-	r.GET("/articles/:id/replies", handler.GetReplies)
+	r.GET("/.well-known/jwks.json", handler.JWKS)
+	r.GET("/articles/:id/comments", middleware.OptionalAuth(), handler.GetComments)
+	r.GET("/articles/:id/replies", middleware.OptionalAuth(), handler.GetReplies)
 	auth := r.Group("/auth")
 	{
 		auth.POST("/register", handler.Register)
 		auth.POST("/login", handler.Login)
 		auth.GET("/getcode", handler.GetCaptcha)
+		auth.POST("/getcode", handler.GetCaptcha)
 		auth.POST("/verify", handler.VerifyCaptcha)
-		auth.Any("/reset/:token", handler.ResetPassword)
+		auth.Match([]string{http.MethodGet, http.MethodPost}, "/reset/:token", handler.ResetPassword)
+		auth.POST("/reset", handler.ResetPasswordJSON)
 		auth.POST("/refresh", handler.RefreshTheToken)
+		auth.POST("/login/2fa", handler.LoginTwoFactor)
+		auth.GET("/oidc/:provider/login", handler.OIDCLogin)
+		auth.GET("/oidc/:provider/callback", handler.OIDCCallback)
+		auth.GET("/verify-email", handler.VerifyEmail)
+		auth.GET("/confirm-email", handler.ConfirmEmail)
 	}
 
 	article := r.Group("/articles")
 	{
-		article.GET("", handler.GetArticles)
-		article.GET("/:id", handler.GetArticle)
+		article.GET("", middleware.OptionalAuth(), handler.GetArticles)
+		article.GET("/search", middleware.OptionalAuth(), handler.SearchArticles)
+		article.GET("/series/:id", handler.GetSeries)
+		article.GET("/:id", middleware.OptionalAuth(), handler.GetArticle)
+	}
+
+	tag := r.Group("/tags")
+	{
+		tag.GET("", handler.GetTags)
+		tag.GET("/suggest", handler.SuggestTags)
+		tag.GET("/:name", middleware.OptionalAuth(), handler.GetTagPosts)
+	}
+
+	r.GET("/series/:id", handler.GetSeriesDetail)
+	r.GET("/shared/reading-lists/:token", middleware.OptionalAuth(), handler.GetSharedReadingList)
+	user := r.Group("/users")
+	{
+		user.GET("/:username", middleware.OptionalAuth(), handler.GetAuthor)
+		user.GET("/:username/followers", handler.GetFollowers)
+		user.GET("/:username/following", handler.GetFollowing)
 	}
 
 	protected := r.Group("")
 	protected.Use(middleware.Auth())
 	{
 		protected.GET("/auth/:id/profile", handler.Profile)
+		protected.PUT("/auth/profile", handler.UpdateProfile)
+		protected.POST("/users/:username/follow", handler.Follow)
+		protected.DELETE("/users/:username/follow", handler.Unfollow)
+		protected.GET("/feed", handler.GetFeed)
 		protected.GET("/auth/id/photos", handler.GetPhotos)
 		protected.POST("/auth/set-avatar", handler.SetAvatar)
 		protected.POST("/auth/upload-img", handler.UploadImg)
 		protected.POST("logout", handler.Logout)
+		protected.POST("/auth/verify-email/resend", handler.ResendVerification)
+		protected.PUT("/auth/password", handler.ChangePassword)
+		protected.PUT("/auth/email", handler.ChangeEmail)
+		protected.POST("/auth/2fa/setup", handler.SetupTwoFactor)
+		protected.POST("/auth/2fa/enable", handler.EnableTwoFactor)
+		protected.POST("/auth/2fa/disable", handler.DisableTwoFactor)
+		protected.GET("/auth/sessions", handler.GetSessions)
+		protected.DELETE("/auth/sessions", handler.RevokeOtherSessions)
+		protected.DELETE("/auth/sessions/:id", handler.RevokeSession)
 
 		// article部分
 		protected.GET("/articles/draft/:id", handler.GetDraftEditable)
 		protected.GET("/articles/publish", handler.GetPostsOfUser)
 		protected.GET("/articles/drafts", handler.GetDraftOfUser)
-		protected.POST("/articles/publish", handler.PublishArticle)
-		protected.POST("/articles/save", handler.SaveDraft)
+		protected.POST("/articles/publish", middleware.RequireRole(models.RoleAuthor, models.RoleModerator), handler.PublishArticle)
+		protected.POST("/articles/save", middleware.RequireRole(models.RoleAuthor, models.RoleModerator), handler.SaveDraft)
+		protected.PATCH("/articles/draft/:id/autosave", middleware.RequireRole(models.RoleAuthor, models.RoleModerator), handler.AutosaveDraft)
+		protected.GET("/articles/scheduled", handler.GetScheduled)
+		protected.PUT("/articles/scheduled/:id", middleware.RequireRole(models.RoleAuthor, models.RoleModerator), handler.Reschedule)
+		protected.DELETE("/articles/scheduled/:id", middleware.RequireRole(models.RoleAuthor, models.RoleModerator), handler.CancelSchedule)
 		protected.DELETE("/articles/post/:id", handler.DeletePost)
-		protected.DELETE("/articles/draft/:id", handler.DeletePost)
+		protected.DELETE("/articles/draft/:id", handler.DeleteDraft)
+		protected.POST("/articles/post/:id/edit", middleware.RequireRole(models.RoleAuthor, models.RoleModerator), handler.EditPost)
+		protected.GET("/articles/:id/revisions", handler.GetRevisions)
+		protected.GET("/articles/:id/revisions/diff", handler.DiffRevisions)
+		protected.GET("/articles/:id/revisions/:version", handler.GetRevision)
+
+		// 系列部分
+		protected.POST("/series", handler.CreateSeries)
+		protected.PUT("/series/:id", handler.UpdateSeries)
+		protected.DELETE("/series/:id", handler.DeleteSeries)
+		protected.PUT("/series/:id/order", handler.ReorderSeries)
 
 		// comment部分
 		protected.POST("/articles/comments", handler.CreateComment)
@@ -101,10 +186,71 @@ func main() {
 		protected.POST("/replies/modify", handler.ModifyReply)
 		protected.DELETE("/comments/:id", handler.DeleteComment)
 		protected.DELETE("/replies/:id", handler.DeleteReply)
+
+		// 点赞部分
+		protected.POST("/articles/:id/like", handler.LikePost)
+		protected.DELETE("/articles/:id/like", handler.UnlikePost)
+		protected.POST("/comments/:id/like", handler.LikeComment)
+		protected.DELETE("/comments/:id/like", handler.UnlikeComment)
+		protected.POST("/replies/:id/like", handler.LikeReply)
+		protected.DELETE("/replies/:id/like", handler.UnlikeReply)
+
+		// 收藏和阅读列表
+		protected.POST("/articles/:id/bookmark", handler.BookmarkPost)
+		protected.DELETE("/articles/:id/bookmark", handler.UnbookmarkPost)
+		protected.GET("/bookmarks", handler.GetBookmarks)
+		protected.GET("/reading-lists", handler.GetReadingLists)
+		protected.POST("/reading-lists", handler.CreateReadingList)
+		protected.GET("/reading-lists/:id", handler.GetReadingList)
+		protected.PUT("/reading-lists/:id", handler.UpdateReadingList)
+		protected.DELETE("/reading-lists/:id", handler.DeleteReadingList)
+		protected.POST("/reading-lists/:id/items", handler.AddReadingListItem)
+		protected.DELETE("/reading-lists/:id/items/:postId", handler.RemoveReadingListItem)
+		protected.PUT("/reading-lists/:id/order", handler.ReorderReadingList)
+	}
+
+	// 管理后台，仅admin可访问
+	admin := r.Group("/admin")
+	admin.Use(middleware.Auth(), middleware.RequireRole(models.RoleAdmin))
+	{
+		admin.GET("/users", handler.GetUsers)
+		admin.PUT("/users/:id/role", handler.SetUserRole)
+		admin.PUT("/users/:id/suspend", handler.SuspendUser)
+		admin.DELETE("/users/:id/suspend", handler.UnsuspendUser)
+		admin.DELETE("/users/:id/failed-login", handler.ResetFailedLogin)
+		admin.DELETE("/users/:id", handler.DeleteUser)
+		admin.DELETE("/posts/:id", handler.AdminDeletePost)
+		admin.PUT("/tags/:id", handler.RenameTag)
+		admin.DELETE("/tags/:id", handler.DeleteTag)
 	}
 
 	// http://localhost:8080/img1.png
 	r.Static("", "./static/images")
 
-	r.Run()
+	addr := ":8080"
+	if port := os.Getenv("PORT"); port != "" {
+		addr = ":" + port
+	}
+	srv := &http.Server{Addr: addr, Handler: r}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	go func() {
+		if err := srv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			log.Fatalf("服务启动出错：%s\n", err.Error())
+		}
+	}()
+
+	<-ctx.Done()
+	log.Println("正在关闭服务")
+
+	// 先停止接收请求，再停掉定时发布，最后把内存里的浏览量写库
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if err := srv.Shutdown(shutdownCtx); err != nil {
+		log.Printf("服务关闭出错：%s\n", err.Error())
+	}
+	stopScheduler()
+	stopViewFlusher()
 }
@@ -6,6 +6,7 @@ import (
 	"net/http"
 	"net/http/httptest"
 	"testing"
+	"time"
 
 	dao "github.com/Jack-samu/the-blog-backend-gin.git/internal/DAO"
 	"github.com/Jack-samu/the-blog-backend-gin.git/internal/dtos"
@@ -14,12 +15,14 @@ import (
 	"github.com/Jack-samu/the-blog-backend-gin.git/internal/service"
 	"github.com/gin-gonic/gin"
 	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
 )
 
-func preparation(s *service.Service, t *testing.T) (string, int) {
+func preparation(db *gorm.DB, s *service.Service, t *testing.T) (string, int) {
 	err := s.Register("test-user", "test@test.com", "test1234", "guest what", "")
 	assert.Empty(t, err)
-	resp, err := s.Login("test-user", "test1234")
+	db.Model(&models.User{}).Where("username = ?", "test-user").Update("email_verified_at", time.Now())
+	resp, err := s.Login("test-user", "test1234", "", "")
 	assert.Empty(t, err)
 
 	req := &dtos.ArticleReq{
@@ -82,7 +85,7 @@ func TestComment(t *testing.T) {
 	//准备文章主体和用户主体
 	var token string
 	var commentID int64
-	_, post_id := preparation(serv, t)
+	_, post_id := preparation(db, serv, t)
 
 	t.Run("创建comment而后修改comment", func(t *testing.T) {
 		// 登录
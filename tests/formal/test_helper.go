@@ -40,7 +40,7 @@ func setupRoutes(h *handler.Handler, r *gin.Engine) {
 		// 删除post
 		protected.DELETE("/articles/post/:id", h.DeletePost)
 		// 删除draft
-		protected.DELETE("/articles/draft/:id", h.DeletePost)
+		protected.DELETE("/articles/draft/:id", h.DeleteDraft)
 
 		// comment部分
 		protected.POST("/articles/comments", h.CreateComment)
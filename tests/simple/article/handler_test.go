@@ -30,6 +30,7 @@ func setupFixture(t *testing.T) *hFixture {
 	// 准备测试用户
 	err := service.Register("test-user", "test@test.com", "test1234", "guest what", "")
 	assert.Nil(t, err)
+	verifyUser(db, "test-user")
 
 	return &hFixture{
 		db: db,
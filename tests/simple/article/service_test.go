@@ -1,12 +1,18 @@
 package article
 
 import (
+	"encoding/json"
+	"fmt"
 	"net/http"
+	"strings"
 	"testing"
+	"time"
 
 	dao "github.com/Jack-samu/the-blog-backend-gin.git/internal/DAO"
 	"github.com/Jack-samu/the-blog-backend-gin.git/internal/dtos"
+	"github.com/Jack-samu/the-blog-backend-gin.git/internal/models"
 	"github.com/Jack-samu/the-blog-backend-gin.git/internal/service"
+	"github.com/Jack-samu/the-blog-backend-gin.git/internal/utils"
 	"github.com/stretchr/testify/assert"
 	"gorm.io/gorm"
 )
@@ -27,7 +33,7 @@ func setupTestFixture(t *testing.T) *tFixture {
 		db:     db,
 		repo:   repo,
 		serv:   serv,
-		userID: createTestUser(serv, t),
+		userID: createTestUser(db, serv, t),
 	}
 }
 
@@ -35,7 +41,7 @@ func TestGetPosts(t *testing.T) {
 	fixture := setupTestFixture(t)
 	defer teardownTestDB(fixture.db)
 
-	resp, err := fixture.serv.GetPosts(1, 10)
+	resp, err := fixture.serv.GetPosts(&dtos.ArticleListReq{PageReq: dtos.PageReq{Page: 1, PerPage: 10}}, "")
 	assert.Empty(t, err)
 	assert.Empty(t, resp.Posts)
 }
@@ -44,7 +50,7 @@ func TestGetPost(t *testing.T) {
 	fixture := setupTestFixture(t)
 	defer teardownTestDB(fixture.db)
 
-	resp, err := fixture.serv.GetPost(1)
+	resp, err := fixture.serv.GetPost(1, "")
 	assert.NotEmpty(t, err)
 	assert.Empty(t, resp)
 }
@@ -53,7 +59,7 @@ func TestGetDraft(t *testing.T) {
 	fixture := setupTestFixture(t)
 	defer teardownTestDB(fixture.db)
 
-	resp, err := fixture.serv.GetDraft(1)
+	resp, err := fixture.serv.GetDraft(1, fixture.userID)
 	assert.NotEmpty(t, err)
 	assert.Empty(t, resp)
 }
@@ -63,11 +69,11 @@ func TestGetPerson(t *testing.T) {
 	defer teardownTestDB(fixture.db)
 
 	// 空的列表且查询中并没有系统错误
-	postsResp, err := fixture.serv.GetPostsOfUser(1, 10, fixture.userID)
+	postsResp, err := fixture.serv.GetPostsOfUser(&dtos.PageReq{Page: 1, PerPage: 10}, fixture.userID)
 	assert.Nil(t, err)
 	assert.Empty(t, postsResp.Posts)
 
-	draftsResp, err := fixture.serv.GetDraftsOfUser(1, 10, fixture.userID)
+	draftsResp, err := fixture.serv.GetDraftsOfUser(&dtos.PageReq{Page: 1, PerPage: 10}, fixture.userID)
 	assert.Nil(t, err)
 	assert.Empty(t, draftsResp.Drafts)
 }
@@ -110,16 +116,16 @@ func TestPublish(t *testing.T) {
 	assert.Equal(t, int(1), postId)
 
 	// 针对id获取post
-	postResp, err := fixture.serv.GetPost(uint(postId))
+	postResp, err := fixture.serv.GetPost(uint(postId), "")
 	assert.Nil(t, err)
 	assert.Equal(t, req.Title, postResp.Post.Title)
 
 	// 删除post
-	err = fixture.serv.DeletePost(uint(postId))
+	err = fixture.serv.DeletePost(uint(postId), fixture.userID)
 	assert.Nil(t, err)
 
 	// 删除文章后，查询个人发布文章
-	postsResp, err := fixture.serv.GetPostsOfUser(1, 10, fixture.userID)
+	postsResp, err := fixture.serv.GetPostsOfUser(&dtos.PageReq{Page: 1, PerPage: 10}, fixture.userID)
 	assert.Nil(t, err)
 	assert.Equal(t, uint(0), postsResp.Cnt)
 	assert.Empty(t, postsResp.Posts)
@@ -147,17 +153,815 @@ func TestSaveDraft(t *testing.T) {
 	assert.Empty(t, err)
 
 	// 针对id查询draft
-	draftResp, err := fixture.serv.GetDraft(uint(draftId))
+	draftResp, err := fixture.serv.GetDraft(uint(draftId), fixture.userID)
 	assert.Nil(t, err)
 	assert.Equal(t, req.Title, draftResp.Draft.Title)
 
 	// 删除draft
-	err = fixture.serv.DeleteDraft(uint(draftId))
+	err = fixture.serv.DeleteDraft(uint(draftId), fixture.userID)
 	assert.Nil(t, err)
 
 	// 删除草稿后查询个人草稿
-	draftsResp, err := fixture.serv.GetDraftsOfUser(1, 10, fixture.userID)
+	draftsResp, err := fixture.serv.GetDraftsOfUser(&dtos.PageReq{Page: 1, PerPage: 10}, fixture.userID)
 	assert.Nil(t, err)
 	assert.Equal(t, uint(0), draftsResp.Cnt)
 	assert.Empty(t, draftsResp.Drafts)
 }
+
+func TestViewCount(t *testing.T) {
+	fixture := setupTestFixture(t)
+	defer teardownTestDB(fixture.db)
+
+	postId, err := fixture.serv.PublishArticle(&dtos.ArticleReq{
+		Title:   "测试",
+		Excerpt: "摘要abstract",
+		Content: "正文",
+	}, fixture.userID)
+	assert.Nil(t, err)
+
+	// 同一用户重复访问只计一次，游客按指纹区分
+	fixture.serv.RecordView(uint(postId), fixture.userID, "127.0.0.1", "test-agent")
+	fixture.serv.RecordView(uint(postId), fixture.userID, "127.0.0.1", "test-agent")
+	fixture.serv.RecordView(uint(postId), "", "127.0.0.1", "test-agent")
+	fixture.serv.RecordView(uint(postId), "", "127.0.0.1", "test-agent")
+	fixture.serv.RecordView(uint(postId), "", "127.0.0.2", "test-agent")
+
+	// 未写库时详情中也能看到缓冲中的浏览量
+	postResp, err := fixture.serv.GetPost(uint(postId), "")
+	assert.Nil(t, err)
+	assert.Equal(t, uint(3), postResp.Post.Views)
+
+	// 写库后计数不变
+	assert.NoError(t, fixture.serv.FlushViews())
+	postResp, err = fixture.serv.GetPost(uint(postId), "")
+	assert.Nil(t, err)
+	assert.Equal(t, uint(3), postResp.Post.Views)
+}
+
+func TestSearchPosts(t *testing.T) {
+	fixture := setupTestFixture(t)
+	defer teardownTestDB(fixture.db)
+
+	reqs := []*dtos.ArticleReq{
+		{Title: "gin入门", Excerpt: "路由与中间件", Content: "gin是一个go语言的web框架"},
+		{Title: "gorm笔记", Excerpt: "关联查询", Content: "在gin项目里使用gorm"},
+		{Title: "随笔", Excerpt: "生活", Content: "100%_无关"},
+	}
+	for _, req := range reqs {
+		_, err := fixture.serv.PublishArticle(req, fixture.userID)
+		assert.Nil(t, err)
+	}
+
+	// 空搜索词
+	_, err := fixture.serv.SearchPosts("  ", &dtos.PageReq{Page: 1, PerPage: 10}, "")
+	assert.Equal(t, http.StatusBadRequest, err.Code)
+
+	// 标题命中的排在前面，并带有高亮片段
+	searchResp, err := fixture.serv.SearchPosts("GIN", &dtos.PageReq{Page: 1, PerPage: 10}, "")
+	assert.Nil(t, err)
+	assert.Equal(t, uint(2), searchResp.Cnt)
+	assert.Equal(t, "gin入门", searchResp.Posts[0].Title)
+	assert.Equal(t, "<mark>gin</mark>入门", searchResp.Posts[0].Highlight.Title)
+	assert.Contains(t, searchResp.Posts[1].Highlight.Content, "<mark>gin</mark>")
+	assert.False(t, searchResp.Posts[0].Bookmarked)
+
+	// 登录用户能看到自己的收藏状态
+	_, err = fixture.serv.Bookmark(fixture.userID, searchResp.Posts[0].Id, true)
+	assert.Nil(t, err)
+	searchResp, err = fixture.serv.SearchPosts("GIN", &dtos.PageReq{Page: 1, PerPage: 10}, fixture.userID)
+	assert.Nil(t, err)
+	assert.True(t, searchResp.Posts[0].Bookmarked)
+	assert.False(t, searchResp.Posts[1].Bookmarked)
+
+	// 通配符按普通字符匹配
+	searchResp, err = fixture.serv.SearchPosts("%_", &dtos.PageReq{Page: 1, PerPage: 10}, "")
+	assert.Nil(t, err)
+	assert.Equal(t, uint(1), searchResp.Cnt)
+
+	// 分页参数越界时按默认值处理
+	searchResp, err = fixture.serv.SearchPosts("gin", &dtos.PageReq{Page: 0, PerPage: -1}, "")
+	assert.Nil(t, err)
+	assert.Equal(t, uint(1), searchResp.CurrentPage)
+	assert.Equal(t, 2, len(searchResp.Posts))
+	searchResp, err = fixture.serv.SearchPosts("gin", &dtos.PageReq{Page: 1, PerPage: 1}, "")
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(searchResp.Posts))
+
+	searchResp, err = fixture.serv.SearchPosts("不存在", &dtos.PageReq{Page: 1, PerPage: 10}, "")
+	assert.Nil(t, err)
+	assert.Empty(t, searchResp.Posts)
+}
+
+func TestGetPostsFilter(t *testing.T) {
+	fixture := setupTestFixture(t)
+	defer teardownTestDB(fixture.db)
+
+	reqs := []*dtos.ArticleReq{
+		{Title: "a", Excerpt: "a", Content: "a", Category: "技术", Tags: []string{"Go"}},
+		{Title: "b", Excerpt: "b", Content: "b", Tags: []string{"life"}},
+		{Title: "c", Excerpt: "c", Content: "c"},
+	}
+	ids := make([]int, len(reqs))
+	for i, req := range reqs {
+		id, err := fixture.serv.PublishArticle(req, fixture.userID)
+		assert.Nil(t, err)
+		ids[i] = id
+	}
+
+	resp, err := fixture.serv.GetPosts(&dtos.ArticleListReq{Tag: "go"}, "")
+	assert.Nil(t, err)
+	assert.Equal(t, uint(1), resp.Cnt)
+	assert.Equal(t, "a", resp.Posts[0].Title)
+
+	resp, err = fixture.serv.GetPosts(&dtos.ArticleListReq{Category: "技术"}, "")
+	assert.Nil(t, err)
+	assert.Equal(t, uint(1), resp.Cnt)
+	assert.Equal(t, "技术", resp.Posts[0].Category)
+
+	resp, err = fixture.serv.GetPosts(&dtos.ArticleListReq{Author: "test-user", PageReq: dtos.PageReq{PerPage: 2}}, "")
+	assert.Nil(t, err)
+	assert.Equal(t, uint(3), resp.Cnt)
+	assert.Equal(t, 2, len(resp.Posts))
+
+	resp, err = fixture.serv.GetPosts(&dtos.ArticleListReq{Author: "nobody"}, "")
+	assert.Nil(t, err)
+	assert.Equal(t, uint(0), resp.Cnt)
+
+	// 日期范围
+	today := time.Now().Format(time.DateOnly)
+	resp, err = fixture.serv.GetPosts(&dtos.ArticleListReq{From: today, To: today}, "")
+	assert.Nil(t, err)
+	assert.Equal(t, uint(3), resp.Cnt)
+	resp, err = fixture.serv.GetPosts(&dtos.ArticleListReq{From: time.Now().AddDate(0, 0, 1).Format(time.DateOnly)}, "")
+	assert.Nil(t, err)
+	assert.Equal(t, uint(0), resp.Cnt)
+	_, err = fixture.serv.GetPosts(&dtos.ArticleListReq{To: "yesterday"}, "")
+	assert.Equal(t, http.StatusBadRequest, err.Code)
+
+	// 排序
+	_, err = fixture.serv.ToggleLike(fixture.userID, models.LikeTargetPost, uint(ids[1]), true)
+	assert.Nil(t, err)
+	resp, err = fixture.serv.GetPosts(&dtos.ArticleListReq{Sort: "likes"}, "")
+	assert.Nil(t, err)
+	assert.Equal(t, "b", resp.Posts[0].Title)
+
+	resp, err = fixture.serv.GetPosts(&dtos.ArticleListReq{Sort: "oldest"}, "")
+	assert.Nil(t, err)
+	assert.Equal(t, "a", resp.Posts[0].Title)
+}
+
+func TestGetPostsCursor(t *testing.T) {
+	fixture := setupTestFixture(t)
+	defer teardownTestDB(fixture.db)
+
+	for _, title := range []string{"1", "2", "3", "4", "5"} {
+		_, err := fixture.serv.PublishArticle(&dtos.ArticleReq{Title: title, Excerpt: title, Content: title}, fixture.userID)
+		assert.Nil(t, err)
+	}
+
+	titles := func(resp *dtos.PostListResp) []string {
+		list := make([]string, len(resp.Posts))
+		for i, p := range resp.Posts {
+			list[i] = p.Title
+		}
+		return list
+	}
+
+	// 第一页
+	resp, err := fixture.serv.GetPosts(&dtos.ArticleListReq{PageReq: dtos.PageReq{PerPage: 2}}, "")
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"5", "4"}, titles(resp))
+	assert.NotEmpty(t, resp.NextCursor)
+	assert.Empty(t, resp.PrevCursor)
+
+	// 翻页之间有新文章发布，不影响后续页
+	_, err = fixture.serv.PublishArticle(&dtos.ArticleReq{Title: "6", Excerpt: "6", Content: "6"}, fixture.userID)
+	assert.Nil(t, err)
+
+	resp, err = fixture.serv.GetPosts(&dtos.ArticleListReq{PageReq: dtos.PageReq{PerPage: 2, Cursor: resp.NextCursor}}, "")
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"3", "2"}, titles(resp))
+
+	resp, err = fixture.serv.GetPosts(&dtos.ArticleListReq{PageReq: dtos.PageReq{PerPage: 2, Cursor: resp.NextCursor}}, "")
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"1"}, titles(resp))
+	assert.Empty(t, resp.NextCursor)
+
+	// 往回翻
+	resp, err = fixture.serv.GetPosts(&dtos.ArticleListReq{PageReq: dtos.PageReq{PerPage: 2, Cursor: resp.PrevCursor}}, "")
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"3", "2"}, titles(resp))
+	resp, err = fixture.serv.GetPosts(&dtos.ArticleListReq{PageReq: dtos.PageReq{PerPage: 2, Cursor: resp.PrevCursor}}, "")
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"5", "4"}, titles(resp))
+	assert.NotEmpty(t, resp.PrevCursor)
+
+	// 无效游标以及不支持游标的排序
+	_, err = fixture.serv.GetPosts(&dtos.ArticleListReq{PageReq: dtos.PageReq{Cursor: "abc"}}, "")
+	assert.Equal(t, http.StatusBadRequest, err.Code)
+	_, err = fixture.serv.GetPosts(&dtos.ArticleListReq{PageReq: dtos.PageReq{Cursor: resp.NextCursor}, Sort: "views"}, "")
+	assert.Equal(t, http.StatusBadRequest, err.Code)
+}
+
+func TestTags(t *testing.T) {
+	fixture := setupTestFixture(t)
+	defer teardownTestDB(fixture.db)
+
+	reqs := []*dtos.ArticleReq{
+		{Title: "a", Excerpt: "a", Content: "a", Tags: []string{"go", "gin"}},
+		{Title: "b", Excerpt: "b", Content: "b", Tags: []string{"Go"}},
+	}
+	for _, req := range reqs {
+		_, err := fixture.serv.PublishArticle(req, fixture.userID)
+		assert.Nil(t, err)
+	}
+
+	// 已存在的标签会被复用
+	tagsResp, err := fixture.serv.GetTags(&dtos.TagListReq{})
+	assert.Nil(t, err)
+	assert.Equal(t, 2, len(tagsResp.Tags))
+	assert.Equal(t, "go", tagsResp.Tags[0].Name)
+	assert.Equal(t, int64(2), tagsResp.Tags[0].Posts)
+
+	tagsResp, err = fixture.serv.GetTags(&dtos.TagListReq{Sort: "name"})
+	assert.Nil(t, err)
+	assert.Equal(t, "gin", tagsResp.Tags[0].Name)
+
+	tagsResp, err = fixture.serv.SuggestTags(&dtos.TagSuggestReq{Prefix: "GI"})
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(tagsResp.Tags))
+	assert.Equal(t, "gin", tagsResp.Tags[0].Name)
+
+	tagResp, err := fixture.serv.GetTagPosts("Go", &dtos.PageReq{}, "")
+	assert.Nil(t, err)
+	assert.Equal(t, int64(2), tagResp.Tag.Posts)
+	assert.Equal(t, uint(2), tagResp.Cnt)
+
+	_, err = fixture.serv.GetTagPosts("rust", &dtos.PageReq{}, "")
+	assert.Equal(t, http.StatusNotFound, err.Code)
+}
+
+func TestSeriesManagement(t *testing.T) {
+	fixture := setupTestFixture(t)
+	defer teardownTestDB(fixture.db)
+
+	seriesResp, err := fixture.serv.CreateSeries(&dtos.SeriesReq{Name: "Gin教程", Description: "从零开始"}, fixture.userID)
+	assert.Nil(t, err)
+	assert.Equal(t, "gin教程", seriesResp.Name)
+
+	_, err = fixture.serv.CreateSeries(&dtos.SeriesReq{Name: "gin教程"}, fixture.userID)
+	assert.Equal(t, http.StatusConflict, err.Code)
+
+	// 发布时按分类名加入系列，依次排在末尾
+	var ids []uint
+	for _, title := range []string{"一", "二", "三"} {
+		id, err := fixture.serv.PublishArticle(&dtos.ArticleReq{Title: title, Excerpt: title, Content: title, Category: "GIN教程"}, fixture.userID)
+		assert.Nil(t, err)
+		ids = append(ids, uint(id))
+	}
+
+	detail, err := fixture.serv.GetSeriesDetail(seriesResp.ID)
+	assert.Nil(t, err)
+	assert.Equal(t, 3, detail.Count)
+	assert.Equal(t, ids[0], detail.Posts[0].Id)
+
+	// 重排，未列出的排在后面
+	detail, err = fixture.serv.ReorderSeries(seriesResp.ID, &dtos.SeriesOrderReq{ArticleIDs: []uint{ids[2], ids[0]}}, fixture.userID)
+	assert.Nil(t, err)
+	assert.Equal(t, []uint{ids[2], ids[0], ids[1]}, []uint{detail.Posts[0].Id, detail.Posts[1].Id, detail.Posts[2].Id})
+
+	_, err = fixture.serv.ReorderSeries(seriesResp.ID, &dtos.SeriesOrderReq{ArticleIDs: []uint{999}}, fixture.userID)
+	assert.Equal(t, http.StatusBadRequest, err.Code)
+
+	// 别人的系列不能改
+	name := "改名"
+	_, err = fixture.serv.UpdateSeries(seriesResp.ID, &dtos.SeriesUpdateReq{Name: &name}, "someone")
+	assert.Equal(t, http.StatusForbidden, err.Code)
+	detail, err = fixture.serv.UpdateSeries(seriesResp.ID, &dtos.SeriesUpdateReq{Name: &name}, fixture.userID)
+	assert.Nil(t, err)
+	assert.Equal(t, "改名", detail.Name)
+	assert.Equal(t, "从零开始", detail.Description)
+
+	list, err := fixture.serv.GetSeries(fixture.userID)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(list.Categories.([]dtos.SeriesItem)))
+
+	// 默认删除系列只把文章移出
+	err = fixture.serv.DeleteSeries(seriesResp.ID, "", fixture.userID)
+	assert.Nil(t, err)
+	_, err = fixture.serv.GetSeriesDetail(seriesResp.ID)
+	assert.Equal(t, http.StatusNotFound, err.Code)
+	postsResp, err := fixture.serv.GetPosts(&dtos.ArticleListReq{}, "")
+	assert.Nil(t, err)
+	assert.Equal(t, uint(3), postsResp.Cnt)
+
+	seriesResp, err = fixture.serv.CreateSeries(&dtos.SeriesReq{Name: "临时"}, fixture.userID)
+	assert.Nil(t, err)
+	fourth, err := fixture.serv.PublishArticle(&dtos.ArticleReq{Title: "四", Excerpt: "四", Content: "四", Category: "临时", Tags: []string{"x"}}, fixture.userID)
+	assert.Nil(t, err)
+	_, err = fixture.serv.Bookmark(fixture.userID, uint(fourth), true)
+	assert.Nil(t, err)
+	readingList, err := fixture.serv.CreateReadingList(&dtos.ReadingListReq{Name: "稍后读"}, fixture.userID)
+	assert.Nil(t, err)
+	_, err = fixture.serv.AddReadingListItem(readingList.ID, &dtos.ReadingListItemReq{ArticleID: uint(fourth)}, fixture.userID)
+	assert.Nil(t, err)
+	err = fixture.serv.DeleteSeries(seriesResp.ID, "delete", fixture.userID)
+	assert.Nil(t, err)
+	postsResp, err = fixture.serv.GetPosts(&dtos.ArticleListReq{}, "")
+	assert.Nil(t, err)
+	assert.Equal(t, uint(3), postsResp.Cnt)
+
+	// 连同文章删除时，收藏和阅读列表中的引用一并清理
+	var cnt int64
+	fixture.db.Model(&models.Bookmark{}).Where("post_id = ?", fourth).Count(&cnt)
+	assert.Zero(t, cnt)
+	fixture.db.Model(&models.ReadingListItem{}).Where("post_id = ?", fourth).Count(&cnt)
+	assert.Zero(t, cnt)
+}
+
+func TestPostRevisions(t *testing.T) {
+	fixture := setupTestFixture(t)
+	defer teardownTestDB(fixture.db)
+
+	postID, err := fixture.serv.PublishArticle(&dtos.ArticleReq{Title: "初版", Excerpt: "摘要", Content: "a\nb\nc", Tags: []string{"go"}}, fixture.userID)
+	assert.Nil(t, err)
+
+	// 别人不能编辑
+	_, err = fixture.serv.EditPost(uint(postID), "someone")
+	assert.Equal(t, http.StatusForbidden, err.Code)
+
+	draftResp, err := fixture.serv.EditPost(uint(postID), fixture.userID)
+	assert.Nil(t, err)
+	assert.Equal(t, uint(postID), *draftResp.Draft.PostID)
+	assert.Equal(t, []string{"go"}, draftResp.Draft.Tags)
+
+	// 重复打开拿到的是同一份编辑稿
+	again, err := fixture.serv.EditPost(uint(postID), fixture.userID)
+	assert.Nil(t, err)
+	assert.Equal(t, draftResp.Draft.Id, again.Draft.Id)
+
+	// 修改编辑稿后原地发布
+	fixture.db.Model(&models.Draft{}).Where("id = ?", draftResp.Draft.Id).
+		Updates(map[string]interface{}{"title": "二版", "content": "a\nc\nd"})
+	newID, err := fixture.serv.PublishArticle(&dtos.ArticleReq{Id: draftResp.Draft.Id}, fixture.userID)
+	assert.Nil(t, err)
+	assert.Equal(t, postID, newID)
+
+	postResp, err := fixture.serv.GetPost(uint(postID), "")
+	assert.Nil(t, err)
+	assert.Equal(t, "二版", postResp.Post.Title)
+	assert.Equal(t, []string{"go"}, postResp.Post.Tags)
+
+	revisionsResp, err := fixture.serv.GetRevisions(uint(postID), fixture.userID)
+	assert.Nil(t, err)
+	assert.Equal(t, 2, len(revisionsResp.Revisions))
+	assert.Equal(t, 2, revisionsResp.Revisions[0].Version)
+
+	revisionResp, err := fixture.serv.GetRevision(uint(postID), 1, fixture.userID)
+	assert.Nil(t, err)
+	assert.Equal(t, "a\nb\nc", revisionResp.Content)
+
+	diffResp, err := fixture.serv.DiffRevisions(uint(postID), 1, 2, fixture.userID)
+	assert.Nil(t, err)
+	assert.Equal(t, []utils.DiffLine{
+		{Op: utils.DiffEqual, Text: "a"},
+		{Op: utils.DiffDelete, Text: "b"},
+		{Op: utils.DiffEqual, Text: "c"},
+		{Op: utils.DiffInsert, Text: "d"},
+	}, diffResp.Content)
+
+	_, err = fixture.serv.DiffRevisions(uint(postID), 1, 3, fixture.userID)
+	assert.Equal(t, http.StatusNotFound, err.Code)
+	_, err = fixture.serv.GetRevisions(uint(postID), "someone")
+	assert.Equal(t, http.StatusForbidden, err.Code)
+}
+
+func TestAutosaveDraft(t *testing.T) {
+	fixture := setupTestFixture(t)
+	defer teardownTestDB(fixture.db)
+
+	draftID, err := fixture.serv.SaveDraft(&dtos.ArticleReq{Title: "草稿", Content: "正文"}, fixture.userID)
+	assert.Nil(t, err)
+
+	// 整篇保存会写库并递增版本
+	_, err = fixture.serv.SaveDraft(&dtos.ArticleReq{Id: uint(draftID), Title: "草稿1", Content: "正文1"}, fixture.userID)
+	assert.Nil(t, err)
+	draftResp, err := fixture.serv.GetDraft(uint(draftID), fixture.userID)
+	assert.Nil(t, err)
+	assert.Equal(t, "草稿1", draftResp.Draft.Title)
+	assert.Equal(t, 2, draftResp.Draft.Version)
+
+	// 部分字段更新
+	content := "自动保存"
+	category := "笔记"
+	tags := []string{"Go"}
+	saveResp, err := fixture.serv.AutosaveDraft(uint(draftID), &dtos.AutosaveReq{Version: 2, Content: &content, Category: &category, Tags: &tags}, fixture.userID)
+	assert.Nil(t, err)
+	assert.Equal(t, 3, saveResp.Version)
+
+	draftResp, err = fixture.serv.GetDraft(uint(draftID), fixture.userID)
+	assert.Nil(t, err)
+	assert.Equal(t, "草稿1", draftResp.Draft.Title)
+	assert.Equal(t, "自动保存", draftResp.Draft.Content)
+	assert.Equal(t, "笔记", draftResp.Draft.Category)
+	assert.Equal(t, []string{"go"}, draftResp.Draft.Tags)
+
+	// 另一个标签页拿着旧版本保存
+	title := "旧标签页"
+	_, err = fixture.serv.AutosaveDraft(uint(draftID), &dtos.AutosaveReq{Version: 2, Title: &title}, fixture.userID)
+	assert.Equal(t, http.StatusConflict, err.Code)
+
+	_, err = fixture.serv.AutosaveDraft(uint(draftID), &dtos.AutosaveReq{Version: 3, Title: &title}, "someone")
+	assert.Equal(t, http.StatusForbidden, err.Code)
+	_, err = fixture.serv.AutosaveDraft(999, &dtos.AutosaveReq{Version: 1}, fixture.userID)
+	assert.Equal(t, http.StatusNotFound, err.Code)
+}
+
+func TestScheduledPublish(t *testing.T) {
+	fixture := setupTestFixture(t)
+	defer teardownTestDB(fixture.db)
+
+	past := time.Now().Add(-time.Hour)
+	_, err := fixture.serv.ScheduleArticle(&dtos.ArticleReq{Title: "定时", PublishAt: &past}, fixture.userID)
+	assert.Equal(t, http.StatusBadRequest, err.Code)
+
+	publishAt := time.Now().Add(time.Hour)
+	draftResp, err := fixture.serv.ScheduleArticle(&dtos.ArticleReq{
+		Title: "定时", Excerpt: "定时", Content: "定时",
+		Category: "连载", Tags: []string{"go"}, PublishAt: &publishAt,
+	}, fixture.userID)
+	assert.Nil(t, err)
+	draftID := draftResp.Draft.Id
+
+	cancelID, err := fixture.serv.SaveDraft(&dtos.ArticleReq{Title: "取消"}, fixture.userID)
+	assert.Nil(t, err)
+	_, err = fixture.serv.Reschedule(uint(cancelID), publishAt, fixture.userID)
+	assert.Nil(t, err)
+
+	scheduledResp, err := fixture.serv.GetScheduled(fixture.userID)
+	assert.Nil(t, err)
+	assert.Equal(t, 2, len(scheduledResp.Drafts))
+
+	_, err = fixture.serv.Reschedule(draftID, publishAt, "someone")
+	assert.Equal(t, http.StatusForbidden, err.Code)
+	err = fixture.serv.CancelSchedule(uint(cancelID), fixture.userID)
+	assert.Nil(t, err)
+
+	// 没到点不发布
+	assert.Equal(t, 0, fixture.serv.PublishDue(time.Now()))
+	assert.Equal(t, 1, fixture.serv.PublishDue(time.Now().Add(2*time.Hour)))
+	assert.Equal(t, 0, fixture.serv.PublishDue(time.Now().Add(2*time.Hour)))
+
+	postsResp, err := fixture.serv.GetPosts(&dtos.ArticleListReq{}, "")
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(postsResp.Posts))
+	assert.Equal(t, "连载", postsResp.Posts[0].Category)
+	assert.Equal(t, []string{"go"}, postsResp.Posts[0].Tags)
+
+	// 取消定时的草稿仍在
+	_, err = fixture.serv.GetDraft(uint(cancelID), fixture.userID)
+	assert.Nil(t, err)
+	_, err = fixture.serv.GetDraft(draftID, fixture.userID)
+	assert.Equal(t, http.StatusNotFound, err.Code)
+
+	// 作者被降级后到点不再发布，定时取消，草稿保留
+	_, err = fixture.serv.Reschedule(uint(cancelID), publishAt, fixture.userID)
+	assert.Nil(t, err)
+	assert.Nil(t, fixture.serv.SetUserRole(fixture.userID, models.RoleReader))
+	assert.Equal(t, 0, fixture.serv.PublishDue(time.Now().Add(2*time.Hour)))
+	scheduledResp, err = fixture.serv.GetScheduled(fixture.userID)
+	assert.Nil(t, err)
+	assert.Empty(t, scheduledResp.Drafts)
+	_, err = fixture.serv.GetDraft(uint(cancelID), fixture.userID)
+	assert.Nil(t, err)
+}
+
+func TestOwnership(t *testing.T) {
+	fixture := setupTestFixture(t)
+	defer teardownTestDB(fixture.db)
+
+	errResp := fixture.serv.Register("other-user", "other@test.com", "test123", "", "")
+	assert.Nil(t, errResp)
+	verifyUser(fixture.db, "other-user")
+	loginResp, errResp := fixture.serv.Login("other-user", "test123", "", "")
+	assert.Nil(t, errResp)
+	otherID := loginResp.UserInfo.ID
+
+	postID, err := fixture.serv.PublishArticle(&dtos.ArticleReq{Title: "文章", Excerpt: "文章", Content: "文章"}, fixture.userID)
+	assert.Nil(t, err)
+	draftID, err := fixture.serv.SaveDraft(&dtos.ArticleReq{Title: "草稿"}, fixture.userID)
+	assert.Nil(t, err)
+
+	// 别人的草稿不能看、不能改、不能发布
+	_, err = fixture.serv.GetDraft(uint(draftID), otherID)
+	assert.Equal(t, http.StatusForbidden, err.Code)
+	_, err = fixture.serv.SaveDraft(&dtos.ArticleReq{Id: uint(draftID), Title: "篡改"}, otherID)
+	assert.Equal(t, http.StatusForbidden, err.Code)
+	_, err = fixture.serv.PublishArticle(&dtos.ArticleReq{Id: uint(draftID)}, otherID)
+	assert.Equal(t, http.StatusForbidden, err.Code)
+
+	// 别人的文章和草稿不能删
+	err = fixture.serv.DeletePost(uint(postID), otherID)
+	assert.Equal(t, http.StatusForbidden, err.Code)
+	err = fixture.serv.DeleteDraft(uint(draftID), otherID)
+	assert.Equal(t, http.StatusForbidden, err.Code)
+
+	err = fixture.serv.DeletePost(999, fixture.userID)
+	assert.Equal(t, http.StatusNotFound, err.Code)
+	err = fixture.serv.DeleteDraft(999, fixture.userID)
+	assert.Equal(t, http.StatusNotFound, err.Code)
+
+	draftResp, err := fixture.serv.GetDraft(uint(draftID), fixture.userID)
+	assert.Nil(t, err)
+	assert.Equal(t, "草稿", draftResp.Draft.Title)
+	assert.Nil(t, fixture.serv.DeleteDraft(uint(draftID), fixture.userID))
+	assert.Nil(t, fixture.serv.DeletePost(uint(postID), fixture.userID))
+}
+
+func TestAuthorPage(t *testing.T) {
+	fixture := setupTestFixture(t)
+	defer teardownTestDB(fixture.db)
+
+	_, err := fixture.serv.AuthorPage("nobody", "")
+	assert.Equal(t, http.StatusNotFound, err.Code)
+
+	// 资料校验
+	str := func(s string) *string { return &s }
+	_, err = fixture.serv.UpdateProfile(fixture.userID, &dtos.ProfileUpdateReq{Website: str("javascript:alert(1)")})
+	assert.Equal(t, http.StatusBadRequest, err.Code)
+	_, err = fixture.serv.UpdateProfile(fixture.userID, &dtos.ProfileUpdateReq{Links: map[string]string{"myspace": "https://myspace.com/a"}})
+	assert.Equal(t, http.StatusBadRequest, err.Code)
+	_, err = fixture.serv.UpdateProfile(fixture.userID, &dtos.ProfileUpdateReq{Links: map[string]string{"github": "github.com/a"}})
+	assert.Equal(t, http.StatusBadRequest, err.Code)
+	_, err = fixture.serv.UpdateProfile(fixture.userID, &dtos.ProfileUpdateReq{Bio: str(strings.Repeat("长", 501))})
+	assert.Equal(t, http.StatusBadRequest, err.Code)
+	assert.Contains(t, err.Msg, "500")
+	_, err = fixture.serv.UpdateProfile(fixture.userID, &dtos.ProfileUpdateReq{DisplayName: str("换\n行")})
+	assert.Equal(t, http.StatusBadRequest, err.Code)
+	assert.Contains(t, err.Msg, "控制字符")
+
+	profile, err := fixture.serv.UpdateProfile(fixture.userID, &dtos.ProfileUpdateReq{
+		DisplayName: str(" 测试作者 "),
+		Bio:         str("写点Go"),
+		Website:     str("https://blog.test"),
+		Links:       map[string]string{"GitHub": "https://github.com/test", "twitter": ""},
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, "测试作者", profile.DisplayName)
+	assert.Equal(t, map[string]string{"github": "https://github.com/test"}, profile.Links)
+	assert.Equal(t, "test@test.com", profile.Email)
+
+	// 只更新传了的字段
+	profile, err = fixture.serv.UpdateProfile(fixture.userID, &dtos.ProfileUpdateReq{Bio: str("")})
+	assert.Nil(t, err)
+	assert.Empty(t, profile.Bio)
+	assert.Equal(t, "https://blog.test", profile.Website)
+	assert.Equal(t, "https://github.com/test", profile.Links["github"])
+
+	// 其他人看不到邮箱
+	profile, err = fixture.serv.Profile(fixture.userID, "someone-else")
+	assert.Nil(t, err)
+	assert.Empty(t, profile.Email)
+	assert.Zero(t, profile.Drafts)
+
+	_, err = fixture.serv.CreateSeries(&dtos.SeriesReq{Name: "系列"}, fixture.userID)
+	assert.Nil(t, err)
+	for i := range 7 {
+		req := &dtos.ArticleReq{Title: fmt.Sprintf("文章%d", i), Excerpt: "摘要", Content: "内容"}
+		if i == 0 {
+			req.Category = "系列"
+		}
+		_, err = fixture.serv.PublishArticle(req, fixture.userID)
+		assert.Nil(t, err)
+	}
+
+	page, err := fixture.serv.AuthorPage("test-user", "")
+	assert.Nil(t, err)
+	assert.Equal(t, "测试作者", page.DisplayName)
+	assert.Equal(t, int64(7), page.Articles)
+	assert.Equal(t, 1, len(page.Series))
+	assert.Equal(t, 1, page.Series[0].Count)
+	assert.Equal(t, 5, len(page.RecentPosts))
+	assert.Equal(t, "文章6", page.RecentPosts[0].Title)
+	data, _ := json.Marshal(page)
+	assert.NotContains(t, string(data), "test@test.com")
+
+	// 被封禁的作者不展示
+	fixture.db.Model(&models.User{}).Where("id = ?", fixture.userID).Update("suspended_at", time.Now())
+	_, err = fixture.serv.AuthorPage("test-user", "")
+	assert.Equal(t, http.StatusNotFound, err.Code)
+}
+
+func TestFollowFeed(t *testing.T) {
+	fixture := setupTestFixture(t)
+	defer teardownTestDB(fixture.db)
+
+	// 再准备两个作者
+	register := func(name string) string {
+		assert.Empty(t, fixture.serv.Register(name, name+"@test.com", "test1234", "", ""))
+		verifyUser(fixture.db, name)
+		resp, err := fixture.serv.Login(name, "test1234", "", "")
+		assert.Empty(t, err)
+		return resp.UserInfo.ID
+	}
+	alice, bob := register("alice"), register("bob")
+
+	_, err := fixture.serv.Follow(fixture.userID, "test-user", true)
+	assert.Equal(t, http.StatusBadRequest, err.Code)
+	_, err = fixture.serv.Follow(fixture.userID, "nobody", true)
+	assert.Equal(t, http.StatusNotFound, err.Code)
+
+	// 重复关注不报错，也不重复计数
+	followResp, err := fixture.serv.Follow(fixture.userID, "alice", true)
+	assert.Nil(t, err)
+	assert.True(t, followResp.Followed)
+	followResp, err = fixture.serv.Follow(fixture.userID, "alice", true)
+	assert.Nil(t, err)
+	assert.Equal(t, int64(1), followResp.Followers)
+	_, err = fixture.serv.Follow(bob, "alice", true)
+	assert.Nil(t, err)
+	_, err = fixture.serv.Follow(fixture.userID, "bob", true)
+	assert.Nil(t, err)
+
+	page, err := fixture.serv.AuthorPage("alice", fixture.userID)
+	assert.Nil(t, err)
+	assert.Equal(t, int64(2), page.Followers)
+	assert.True(t, page.Followed)
+	page, err = fixture.serv.AuthorPage("alice", "")
+	assert.Nil(t, err)
+	assert.False(t, page.Followed)
+	profile, err := fixture.serv.Profile(fixture.userID, fixture.userID)
+	assert.Nil(t, err)
+	assert.Equal(t, int64(2), profile.Following)
+
+	followers, err := fixture.serv.GetFollows("alice", &dtos.PageReq{Page: 1, PerPage: 10}, true)
+	assert.Nil(t, err)
+	assert.Equal(t, uint(2), followers.Cnt)
+	following, err := fixture.serv.GetFollows("test-user", &dtos.PageReq{Page: 1, PerPage: 10}, false)
+	assert.Nil(t, err)
+	assert.ElementsMatch(t, []string{"alice", "bob"}, []string{following.Users[0].Username, following.Users[1].Username})
+
+	// 动态只包含关注的作者，按发布时间倒序
+	base := time.Now().Add(-time.Hour)
+	for i, author := range []string{alice, bob, fixture.userID, alice, bob} {
+		id, err := fixture.serv.PublishArticle(&dtos.ArticleReq{Title: fmt.Sprintf("动态%d", i), Excerpt: "摘要", Content: "内容"}, author)
+		assert.Nil(t, err)
+		fixture.db.Model(&models.Post{}).Where("id = ?", id).Update("created_at", base.Add(time.Duration(i)*time.Minute))
+	}
+
+	feed, err := fixture.serv.GetFeed(fixture.userID, &dtos.PageReq{Page: 1, PerPage: 3})
+	assert.Nil(t, err)
+	assert.Equal(t, uint(4), feed.Cnt)
+	assert.Equal(t, []string{"动态4", "动态3", "动态1"}, []string{feed.Posts[0].Title, feed.Posts[1].Title, feed.Posts[2].Title})
+	assert.NotEmpty(t, feed.NextCursor)
+
+	feed, err = fixture.serv.GetFeed(fixture.userID, &dtos.PageReq{PerPage: 3, Cursor: feed.NextCursor})
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(feed.Posts))
+	assert.Equal(t, "动态0", feed.Posts[0].Title)
+	assert.Empty(t, feed.NextCursor)
+	assert.NotEmpty(t, feed.PrevCursor)
+
+	// 取消关注后不再出现在动态里
+	followResp, err = fixture.serv.Follow(fixture.userID, "bob", false)
+	assert.Nil(t, err)
+	assert.False(t, followResp.Followed)
+	feed, err = fixture.serv.GetFeed(fixture.userID, &dtos.PageReq{Page: 1, PerPage: 10})
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"动态3", "动态0"}, []string{feed.Posts[0].Title, feed.Posts[1].Title})
+
+	feed, err = fixture.serv.GetFeed(bob, &dtos.PageReq{Page: 1, PerPage: 10})
+	assert.Nil(t, err)
+	assert.Equal(t, 2, len(feed.Posts))
+}
+
+func TestBookmarksAndReadingLists(t *testing.T) {
+	fixture := setupTestFixture(t)
+	defer teardownTestDB(fixture.db)
+
+	assert.Empty(t, fixture.serv.Register("reader", "reader@test.com", "test1234", "", ""))
+	verifyUser(fixture.db, "reader")
+	login, err := fixture.serv.Login("reader", "test1234", "", "")
+	assert.Empty(t, err)
+	reader := login.UserInfo.ID
+
+	base := time.Now().Add(-time.Hour)
+	ids := make([]uint, 3)
+	for i := range ids {
+		id, err := fixture.serv.PublishArticle(&dtos.ArticleReq{Title: fmt.Sprintf("收藏%d", i), Excerpt: "摘要", Content: "内容"}, fixture.userID)
+		assert.Nil(t, err)
+		fixture.db.Model(&models.Post{}).Where("id = ?", id).Update("created_at", base.Add(time.Duration(i)*time.Minute))
+		ids[i] = uint(id)
+	}
+
+	// 收藏，重复操作不报错
+	_, err = fixture.serv.Bookmark(reader, 9999, true)
+	assert.Equal(t, http.StatusNotFound, err.Code)
+	for _, id := range []uint{ids[0], ids[2], ids[2]} {
+		resp, err := fixture.serv.Bookmark(reader, id, true)
+		assert.Nil(t, err)
+		assert.True(t, resp.Bookmarked)
+	}
+
+	bookmarks, err := fixture.serv.GetBookmarks(reader, &dtos.PageReq{Page: 1, PerPage: 10})
+	assert.Nil(t, err)
+	assert.Equal(t, uint(2), bookmarks.Cnt)
+	assert.True(t, bookmarks.Posts[0].Bookmarked)
+
+	// 列表和详情按调用者标记收藏状态
+	posts, err := fixture.serv.GetPosts(&dtos.ArticleListReq{}, reader)
+	assert.Nil(t, err)
+	marked := make(map[uint]bool)
+	for _, p := range posts.Posts {
+		marked[p.Id] = p.Bookmarked
+	}
+	assert.Equal(t, map[uint]bool{ids[0]: true, ids[1]: false, ids[2]: true}, marked)
+	posts, err = fixture.serv.GetPosts(&dtos.ArticleListReq{}, "")
+	assert.Nil(t, err)
+	assert.False(t, posts.Posts[0].Bookmarked)
+	detail, err := fixture.serv.GetPost(ids[0], reader)
+	assert.Nil(t, err)
+	assert.True(t, detail.Post.Bookmarked)
+	detail, err = fixture.serv.GetPost(ids[0], fixture.userID)
+	assert.Nil(t, err)
+	assert.False(t, detail.Post.Bookmarked)
+
+	resp, err := fixture.serv.Bookmark(reader, ids[2], false)
+	assert.Nil(t, err)
+	assert.False(t, resp.Bookmarked)
+	bookmarks, err = fixture.serv.GetBookmarks(reader, &dtos.PageReq{Page: 1, PerPage: 10})
+	assert.Nil(t, err)
+	assert.Equal(t, "收藏0", bookmarks.Posts[0].Title)
+
+	// 阅读列表：按加入顺序排列，可以调整顺序
+	list, err := fixture.serv.CreateReadingList(&dtos.ReadingListReq{Name: " 周末读 "}, reader)
+	assert.Nil(t, err)
+	assert.Equal(t, "周末读", list.Name)
+	assert.Empty(t, list.ShareURL)
+	for _, id := range []uint{ids[1], ids[0], ids[2], ids[1]} {
+		list, err = fixture.serv.AddReadingListItem(list.ID, &dtos.ReadingListItemReq{ArticleID: id}, reader)
+		assert.Nil(t, err)
+	}
+	_, err = fixture.serv.AddReadingListItem(list.ID, &dtos.ReadingListItemReq{ArticleID: 9999}, reader)
+	assert.Equal(t, http.StatusNotFound, err.Code)
+	_, err = fixture.serv.AddReadingListItem(list.ID, &dtos.ReadingListItemReq{ArticleID: ids[0]}, fixture.userID)
+	assert.Equal(t, http.StatusForbidden, err.Code)
+	assert.Equal(t, 3, list.Count)
+	assert.Equal(t, []string{"收藏1", "收藏0", "收藏2"}, []string{list.Posts[0].Title, list.Posts[1].Title, list.Posts[2].Title})
+	assert.True(t, list.Posts[1].Bookmarked)
+
+	_, err = fixture.serv.ReorderReadingList(list.ID, &dtos.ReadingListOrderReq{ArticleIDs: []uint{ids[0], ids[0]}}, reader)
+	assert.Equal(t, http.StatusBadRequest, err.Code)
+	list, err = fixture.serv.ReorderReadingList(list.ID, &dtos.ReadingListOrderReq{ArticleIDs: []uint{ids[2]}}, reader)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"收藏2", "收藏1", "收藏0"}, []string{list.Posts[0].Title, list.Posts[1].Title, list.Posts[2].Title})
+
+	list, err = fixture.serv.RemoveReadingListItem(list.ID, ids[1], reader)
+	assert.Nil(t, err)
+	assert.Equal(t, 2, list.Count)
+	_, err = fixture.serv.RemoveReadingListItem(list.ID, ids[1], reader)
+	assert.Equal(t, http.StatusNotFound, err.Code)
+
+	// 公开后生成分享链接，其他人通过链接可以访问
+	public := true
+	list, err = fixture.serv.UpdateReadingList(list.ID, &dtos.ReadingListUpdateReq{Public: &public}, reader)
+	assert.Nil(t, err)
+	assert.True(t, strings.Contains(list.ShareURL, "/shared/reading-lists/"))
+	token := list.ShareURL[strings.LastIndex(list.ShareURL, "/")+1:]
+
+	_, err = fixture.serv.GetReadingList(list.ID, fixture.userID)
+	assert.Equal(t, http.StatusForbidden, err.Code)
+	shared, err := fixture.serv.GetSharedReadingList(token, fixture.userID)
+	assert.Nil(t, err)
+	assert.Equal(t, "reader", shared.Owner.Username)
+	assert.Equal(t, 2, len(shared.Posts))
+	assert.False(t, shared.Posts[1].Bookmarked)
+
+	// 再次保存不改变链接，取消公开后链接失效
+	name := "周末必读"
+	list, err = fixture.serv.UpdateReadingList(list.ID, &dtos.ReadingListUpdateReq{Name: &name, Public: &public}, reader)
+	assert.Nil(t, err)
+	assert.True(t, strings.HasSuffix(list.ShareURL, token))
+	public = false
+	list, err = fixture.serv.UpdateReadingList(list.ID, &dtos.ReadingListUpdateReq{Public: &public}, reader)
+	assert.Nil(t, err)
+	assert.Empty(t, list.ShareURL)
+	_, err = fixture.serv.GetSharedReadingList(token, "")
+	assert.Equal(t, http.StatusNotFound, err.Code)
+
+	// 删除文章时收藏和列表中的引用一并清理
+	assert.Nil(t, fixture.serv.DeletePost(ids[0], fixture.userID))
+	bookmarks, err = fixture.serv.GetBookmarks(reader, &dtos.PageReq{Page: 1, PerPage: 10})
+	assert.Nil(t, err)
+	assert.Equal(t, uint(0), bookmarks.Cnt)
+	lists, err := fixture.serv.GetReadingLists(reader)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(lists.Lists))
+	assert.Equal(t, 1, lists.Lists[0].Count)
+
+	assert.Equal(t, http.StatusForbidden, fixture.serv.DeleteReadingList(list.ID, fixture.userID).Code)
+	assert.Nil(t, fixture.serv.DeleteReadingList(list.ID, reader))
+	lists, err = fixture.serv.GetReadingLists(reader)
+	assert.Nil(t, err)
+	assert.Empty(t, lists.Lists)
+}
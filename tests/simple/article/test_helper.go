@@ -4,6 +4,7 @@ import (
 	"log"
 	"os"
 	"testing"
+	"time"
 
 	"github.com/Jack-samu/the-blog-backend-gin.git/internal/handler"
 	"github.com/Jack-samu/the-blog-backend-gin.git/internal/middleware"
@@ -40,7 +41,7 @@ func setupRoutes(h *handler.Handler, r *gin.Engine) {
 		// 删除post
 		protected.DELETE("/articles/post/:id", h.DeletePost)
 		// 删除draft
-		protected.DELETE("/articles/draft/:id", h.DeletePost)
+		protected.DELETE("/articles/draft/:id", h.DeleteDraft)
 	}
 }
 
@@ -56,8 +57,19 @@ func setupTestDB(t *testing.T) *gorm.DB {
 		&models.Post{},
 		&models.Draft{},
 		&models.Img{},
+		&models.RefreshToken{},
+		&models.Session{},
+		&models.UserToken{},
+		&models.RecoveryCode{},
+		&models.UserIdentity{},
+		&models.Follow{},
+		&models.Bookmark{},
+		&models.ReadingList{},
+		&models.ReadingListItem{},
 		&models.Comment{},
 		&models.Reply{},
+		&models.Like{},
+		&models.PostRevision{},
 	)
 	if err != nil {
 		t.Fatalf("数据库迁移失败：%s\n", err.Error())
@@ -77,10 +89,16 @@ func teardownTestDB(db *gorm.DB) {
 	}
 }
 
-func createTestUser(s *service.Service, t *testing.T) string {
+// 测试用户默认已验证邮箱
+func verifyUser(db *gorm.DB, username string) {
+	db.Model(&models.User{}).Where("username = ?", username).Update("email_verified_at", time.Now())
+}
+
+func createTestUser(db *gorm.DB, s *service.Service, t *testing.T) string {
 	err := s.Register("test-user", "test@test.com", "test123", "guest what", "")
 	assert.Empty(t, err)
-	resp, err := s.Login("test-user", "test123")
+	verifyUser(db, "test-user")
+	resp, err := s.Login("test-user", "test123", "", "")
 	assert.Empty(t, err)
 	return resp.UserInfo.ID
 }
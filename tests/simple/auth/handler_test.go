@@ -19,13 +19,13 @@ import (
 func setupRoutes(h *handler.Handler, r *gin.Engine) {
 	r.POST("/auth/register", h.Register)
 	r.POST("/auth/login", h.Login)
+	r.POST("/auth/refresh", h.RefreshTheToken)
 
 	protected := r.Group("/auth")
 	protected.Use(middleware.Auth())
 	{
 		protected.POST("/:id/profile", h.Profile)
 		protected.POST("/photos", h.GetPhotos)
-		protected.POST("/refresh", h.RefreshTheToken)
 		protected.POST("/logout", h.Logout)
 	}
 }
@@ -86,13 +86,23 @@ func TestUserFlowSimple(t *testing.T) {
 		user_id = resp.UserInfo.ID
 	})
 
-	t.Run("个人信息获取", func(t *testing.T) {
+	t.Run("refreshToken不能用于鉴权", func(t *testing.T) {
 		recorder := httptest.NewRecorder()
 		req, err := http.NewRequest(http.MethodPost, "/auth/"+user_id+"/profile", nil)
 		assert.NoError(t, err)
 
 		req.Header.Set("Authorization", "Bearer "+refreshToken)
 		r.ServeHTTP(recorder, req)
+		assert.Equal(t, http.StatusUnauthorized, recorder.Code)
+	})
+
+	t.Run("个人信息获取", func(t *testing.T) {
+		recorder := httptest.NewRecorder()
+		req, err := http.NewRequest(http.MethodPost, "/auth/"+user_id+"/profile", nil)
+		assert.NoError(t, err)
+
+		req.Header.Set("Authorization", "Bearer "+token)
+		r.ServeHTTP(recorder, req)
 		var resp dtos.ProfileResp
 
 		json.Unmarshal(recorder.Body.Bytes(), &resp)
@@ -112,6 +122,7 @@ func TestUserFlowSimple(t *testing.T) {
 		json.Unmarshal(recorder.Body.Bytes(), &refreshResp)
 		assert.Equal(t, http.StatusOK, recorder.Code)
 		assert.NotEqual(t, token, refreshResp.Token)
+		assert.NotEqual(t, refreshToken, refreshResp.RefreshToken)
 		token = refreshResp.Token
 	})
 
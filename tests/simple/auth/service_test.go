@@ -1,12 +1,22 @@
 package auth
 
 import (
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
 	"testing"
+	"time"
 
 	dao "github.com/Jack-samu/the-blog-backend-gin.git/internal/DAO"
+	"github.com/Jack-samu/the-blog-backend-gin.git/internal/dtos"
+	"github.com/Jack-samu/the-blog-backend-gin.git/internal/errs"
+	"github.com/Jack-samu/the-blog-backend-gin.git/internal/models"
 	"github.com/Jack-samu/the-blog-backend-gin.git/internal/service"
 	"github.com/Jack-samu/the-blog-backend-gin.git/internal/utils"
+	"github.com/golang-jwt/jwt/v5"
 	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
 )
 
 // 还缺了忘记密码部分和照片部分
@@ -30,7 +40,7 @@ func TestUserServiceWithoutAvatar(t *testing.T) {
 	assert.Contains(t, err.Msg, "昵称重复")
 
 	// 登录测试
-	userInfo, err := s.Login("test-user", "test123")
+	userInfo, err := s.Login("test-user", "test123", "", "")
 	assert.Empty(t, err)
 	assert.Equal(t, "test-user", userInfo.UserInfo.Username)
 
@@ -40,15 +50,19 @@ func TestUserServiceWithoutAvatar(t *testing.T) {
 	_, err1 = utils.ParseToken(userInfo.RefreshToken)
 	assert.NoError(t, err1)
 
-	// token刷新
-	refreshResp, err := s.RefreshTheToken(payload.ID)
+	assert.Equal(t, utils.TokenAccess, payload.Type)
+
+	// token刷新，access token不能用来刷新
+	_, err = s.RefreshTheToken(userInfo.Token)
+	assert.Equal(t, http.StatusUnauthorized, err.Code)
+	refreshResp, err := s.RefreshTheToken(userInfo.RefreshToken)
 	assert.Empty(t, err)
 	assert.Equal(t, userInfo.UserInfo.ID, refreshResp.UserInfo.ID)
 	_, err1 = utils.ParseToken(refreshResp.Token)
 	assert.NoError(t, err1)
 
 	// 用户信息获取
-	profileResp, err := s.Profile(userInfo.UserInfo.ID)
+	profileResp, err := s.Profile(userInfo.UserInfo.ID, userInfo.UserInfo.ID)
 	assert.Empty(t, err)
 	assert.Equal(t, userInfo.UserInfo.Username, profileResp.Username)
 	assert.Equal(t, userInfo.UserInfo.Email, profileResp.Email)
@@ -59,7 +73,689 @@ func TestUserServiceWithoutAvatar(t *testing.T) {
 	assert.Empty(t, err)
 	assert.Empty(t, photosResp.Photos)
 
-	last_activity, err := s.Logout(userInfo.UserInfo.ID)
+	last_activity, err := s.Logout(userInfo.UserInfo.ID, payload.SessionID)
 	assert.Empty(t, err)
 	t.Logf("最后活动时间：%s\n", last_activity)
 }
+
+func TestAdminUserManagement(t *testing.T) {
+	db := setupTestDB(t)
+	defer teardownTestDB(db, t)
+
+	repo := dao.NewRepository(db)
+	s := service.NewService(repo)
+
+	err := s.Register("admin-user", "admin@test.com", "test123", "", "")
+	assert.Empty(t, err)
+	err = s.Register("test-user", "test@test.com", "test123", "", "")
+	assert.Empty(t, err)
+	adminResp, err := s.Login("admin-user", "test123", "", "")
+	assert.Empty(t, err)
+	adminID := adminResp.UserInfo.ID
+	loginResp, err := s.Login("test-user", "test123", "", "")
+	assert.Empty(t, err)
+	userID := loginResp.UserInfo.ID
+	assert.Nil(t, s.SetUserRole(adminID, models.RoleAdmin))
+
+	// 列表筛选
+	usersResp, err := s.GetUsers(&dtos.AdminUserListReq{})
+	assert.Nil(t, err)
+	assert.Equal(t, uint(2), usersResp.Cnt)
+	usersResp, err = s.GetUsers(&dtos.AdminUserListReq{Role: models.RoleAdmin})
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(usersResp.Users))
+	assert.Equal(t, adminID, usersResp.Users[0].ID)
+	usersResp, err = s.GetUsers(&dtos.AdminUserListReq{Keyword: "test-user"})
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(usersResp.Users))
+	// 通配符按普通字符匹配
+	usersResp, err = s.GetUsers(&dtos.AdminUserListReq{Keyword: "%"})
+	assert.Nil(t, err)
+	assert.Empty(t, usersResp.Users)
+	usersResp, err = s.GetUsers(&dtos.AdminUserListReq{RegisteredFrom: "2000-01-01", ActiveTo: "2000-01-01"})
+	assert.Nil(t, err)
+	assert.Empty(t, usersResp.Users)
+	_, err = s.GetUsers(&dtos.AdminUserListReq{RegisteredFrom: "昨天"})
+	assert.Equal(t, http.StatusBadRequest, err.Code)
+
+	// 登录失败计数筛选和重置
+	_, err = s.Login("test-user", "wrong", "", "")
+	assert.NotNil(t, err)
+	usersResp, err = s.GetUsers(&dtos.AdminUserListReq{MinFailed: 1})
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(usersResp.Users))
+	assert.Nil(t, s.ResetFailedLogin(userID))
+	usersResp, err = s.GetUsers(&dtos.AdminUserListReq{MinFailed: 1})
+	assert.Nil(t, err)
+	assert.Empty(t, usersResp.Users)
+
+	// 角色以数据库为准，调整后已签发的token立即按新角色生效
+	payload, _ := utils.ParseToken(loginResp.Token)
+	assert.Nil(t, s.SetUserRole(userID, models.RoleReader))
+	role, err := s.ValidateToken(payload)
+	assert.Nil(t, err)
+	assert.Equal(t, models.RoleReader, role)
+	payload.Role = models.RoleAdmin
+	role, _ = s.ValidateToken(payload)
+	assert.Equal(t, models.RoleReader, role)
+	payload.Role = ""
+
+	// 封禁后无法登录，已签发的token也失效
+	assert.Equal(t, http.StatusBadRequest, s.SuspendUser(adminID, adminID, true).Code)
+	assert.Nil(t, s.SuspendUser(userID, adminID, true))
+	_, err = s.Login("test-user", "test123", "", "")
+	assert.Equal(t, http.StatusForbidden, err.Code)
+	assert.Equal(t, http.StatusForbidden, validateToken(s, payload).Code)
+	suspended := true
+	usersResp, err = s.GetUsers(&dtos.AdminUserListReq{Suspended: &suspended})
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(usersResp.Users))
+	assert.NotEmpty(t, usersResp.Users[0].SuspendedAt)
+
+	assert.Nil(t, s.SuspendUser(userID, adminID, false))
+	_, err = s.Login("test-user", "test123", "", "")
+	assert.Empty(t, err)
+	assert.Nil(t, validateToken(s, payload))
+
+	// 删除用户
+	assert.Equal(t, http.StatusNotFound, s.DeleteUser("not-exist", adminID).Code)
+	assert.Nil(t, s.DeleteUser(userID, adminID))
+	assert.Equal(t, http.StatusUnauthorized, validateToken(s, payload).Code)
+	_, err = s.Login("test-user", "test123", "", "")
+	assert.Equal(t, http.StatusBadRequest, err.Code)
+}
+
+func TestLoginLockout(t *testing.T) {
+	t.Setenv("LOGIN_LOCK_THRESHOLD", "3")
+	t.Setenv("LOGIN_LOCK_DURATION", "10m")
+	t.Setenv("LOGIN_IP_LIMIT", "8")
+
+	db := setupTestDB(t)
+	defer teardownTestDB(db, t)
+
+	repo := dao.NewRepository(db)
+	s := service.NewService(repo)
+
+	err := s.Register("test-user", "test@test.com", "test123", "", "")
+	assert.Empty(t, err)
+
+	lockedUntil := func() time.Duration {
+		var user models.User
+		db.Where("username = ?", "test-user").First(&user)
+		if user.LockedUntil == nil {
+			return 0
+		}
+		return time.Until(*user.LockedUntil)
+	}
+
+	// 单个来源对同一账号的失败次数限制在阈值以下，无法锁定别人的账号
+	for i := 0; i < 2; i++ {
+		_, err = s.Login("test-user", "wrong", "10.0.0.1", "")
+		assert.Equal(t, http.StatusBadRequest, err.Code)
+	}
+	_, err = s.Login("test-user", "wrong", "10.0.0.1", "")
+	assert.Equal(t, http.StatusTooManyRequests, err.Code)
+	assert.Zero(t, lockedUntil())
+
+	// 多个来源累计失败达到阈值后锁定，锁定期间密码正确也无法登录
+	_, err = s.Login("test-user", "wrong", "10.0.0.2", "")
+	assert.Equal(t, http.StatusLocked, err.Code)
+	assert.InDelta(t, float64(10*time.Minute), float64(lockedUntil()), float64(time.Minute))
+	_, err = s.Login("test-user", "test123", "10.0.0.2", "")
+	assert.Equal(t, http.StatusLocked, err.Code)
+
+	// 锁定到期后自动解除，计数没有清零，再失败一次就重新锁定，锁定时长翻倍
+	db.Model(&models.User{}).Where("username = ?", "test-user").Update("locked_until", time.Now().Add(-time.Second))
+	_, err = s.Login("test-user", "wrong", "10.0.0.3", "")
+	assert.Equal(t, http.StatusLocked, err.Code)
+	assert.InDelta(t, float64(20*time.Minute), float64(lockedUntil()), float64(time.Minute))
+
+	// 并发失败让计数越过阈值时同样锁定
+	db.Model(&models.User{}).Where("username = ?", "test-user").
+		Updates(map[string]interface{}{"failed_login": 1, "locked_until": nil})
+	_, err = s.Login("test-user", "wrong", "10.0.0.3", "")
+	assert.Equal(t, http.StatusBadRequest, err.Code)
+	db.Model(&models.User{}).Where("username = ?", "test-user").Update("failed_login", gorm.Expr("failed_login + 2"))
+	_, err = s.Login("test-user", "wrong", "10.0.0.4", "")
+	assert.Equal(t, http.StatusLocked, err.Code)
+	assert.InDelta(t, float64(40*time.Minute), float64(lockedUntil()), float64(time.Minute))
+
+	// 登录成功后清除失败计数
+	db.Model(&models.User{}).Where("username = ?", "test-user").Update("locked_until", time.Now().Add(-time.Second))
+	_, err = s.Login("test-user", "test123", "10.0.0.2", "")
+	assert.Empty(t, err)
+	var user models.User
+	db.Where("username = ?", "test-user").First(&user)
+	assert.Equal(t, 0, user.FailedLogin)
+	assert.Nil(t, user.LockedUntil)
+
+	// 被限流的来源在管理员重置后恢复，之后密码正确时清除该来源的失败记录
+	_, err = s.Login("test-user", "test123", "10.0.0.1", "")
+	assert.Equal(t, http.StatusTooManyRequests, err.Code)
+	assert.Nil(t, s.ResetFailedLogin(user.ID))
+	_, err = s.Login("test-user", "wrong", "10.0.0.1", "")
+	assert.Equal(t, http.StatusBadRequest, err.Code)
+	_, err = s.Login("test-user", "test123", "10.0.0.1", "")
+	assert.Empty(t, err)
+	_, err = s.Login("test-user", "wrong", "10.0.0.1", "")
+	assert.Equal(t, http.StatusBadRequest, err.Code)
+	_, err = s.Login("test-user", "test123", "10.0.0.1", "")
+	assert.Empty(t, err)
+
+	// 同一来源总的失败次数过多后被限流，不再累加账号的失败计数
+	for i := 0; i < 4; i++ {
+		_, err = s.Login("not-exist", "wrong", "10.0.0.1", "")
+		assert.Equal(t, http.StatusBadRequest, err.Code)
+	}
+	_, err = s.Login("test-user", "wrong", "10.0.0.1", "")
+	assert.Equal(t, http.StatusTooManyRequests, err.Code)
+	db.Where("username = ?", "test-user").First(&user)
+	assert.Equal(t, 0, user.FailedLogin)
+	_, err = s.Login("test-user", "test123", "10.0.0.2", "")
+	assert.Empty(t, err)
+}
+
+func TestRefreshTokenRotation(t *testing.T) {
+	db := setupTestDB(t)
+	defer teardownTestDB(db, t)
+
+	repo := dao.NewRepository(db)
+	s := service.NewService(repo)
+
+	err := s.Register("test-user", "test@test.com", "test123", "", "")
+	assert.Empty(t, err)
+	loginResp, err := s.Login("test-user", "test123", "", "")
+	assert.Empty(t, err)
+
+	// refresh token只存哈希
+	var stored models.RefreshToken
+	assert.NoError(t, db.First(&stored).Error)
+	assert.Equal(t, utils.HashToken(loginResp.RefreshToken), stored.TokenHash)
+
+	// 每次刷新都换新的refresh token，同一会话
+	first, err := s.RefreshTheToken(loginResp.RefreshToken)
+	assert.Empty(t, err)
+	assert.NotEqual(t, loginResp.RefreshToken, first.RefreshToken)
+	oldPayload, _ := utils.ParseToken(loginResp.Token)
+	newPayload, _ := utils.ParseToken(first.Token)
+	assert.Equal(t, oldPayload.SessionID, newPayload.SessionID)
+	assert.Nil(t, validateToken(s, newPayload))
+
+	second, err := s.RefreshTheToken(first.RefreshToken)
+	assert.Empty(t, err)
+
+	// 旧的refresh token被重复使用，整个会话吊销
+	_, err = s.RefreshTheToken(first.RefreshToken)
+	assert.Equal(t, http.StatusUnauthorized, err.Code)
+	_, err = s.RefreshTheToken(second.RefreshToken)
+	assert.Equal(t, http.StatusUnauthorized, err.Code)
+	assert.Equal(t, http.StatusUnauthorized, validateToken(s, newPayload).Code)
+
+	// 其他会话不受影响，退出登录后该会话失效
+	loginResp, err = s.Login("test-user", "test123", "", "")
+	assert.Empty(t, err)
+	payload, _ := utils.ParseToken(loginResp.Token)
+	assert.Nil(t, validateToken(s, payload))
+	_, err = s.Logout(payload.ID, payload.SessionID)
+	assert.Empty(t, err)
+	assert.Equal(t, http.StatusUnauthorized, validateToken(s, payload).Code)
+	_, err = s.RefreshTheToken(loginResp.RefreshToken)
+	assert.Equal(t, http.StatusUnauthorized, err.Code)
+}
+
+func TestSessions(t *testing.T) {
+	db := setupTestDB(t)
+	defer teardownTestDB(db, t)
+
+	repo := dao.NewRepository(db)
+	s := service.NewService(repo)
+
+	err := s.Register("test-user", "test@test.com", "test123", "", "")
+	assert.Empty(t, err)
+
+	// 两台设备分别登录
+	phone, err := s.Login("test-user", "test123", "10.0.0.1", "phone")
+	assert.Empty(t, err)
+	laptop, err := s.Login("test-user", "test123", "10.0.0.2", "laptop")
+	assert.Empty(t, err)
+	tablet, err := s.Login("test-user", "test123", "10.0.0.3", "tablet")
+	assert.Empty(t, err)
+	phonePayload, _ := utils.ParseToken(phone.Token)
+	laptopPayload, _ := utils.ParseToken(laptop.Token)
+	tabletPayload, _ := utils.ParseToken(tablet.Token)
+
+	sessionsResp, err := s.GetSessions(phonePayload.ID, phonePayload.SessionID)
+	assert.Empty(t, err)
+	assert.Equal(t, 3, len(sessionsResp.Sessions))
+	for _, item := range sessionsResp.Sessions {
+		assert.Equal(t, item.ID == phonePayload.SessionID, item.Current)
+		if item.Current {
+			assert.Equal(t, "phone", item.UserAgent)
+			assert.Equal(t, "10.0.0.1", item.IP)
+		}
+	}
+
+	// 吊销单个会话，access token和refresh token都失效
+	assert.Equal(t, http.StatusNotFound, s.RevokeSession(phonePayload.ID, "not-exist").Code)
+	assert.Nil(t, s.RevokeSession(phonePayload.ID, laptopPayload.SessionID))
+	assert.Equal(t, http.StatusUnauthorized, validateToken(s, laptopPayload).Code)
+	_, err = s.RefreshTheToken(laptop.RefreshToken)
+	assert.Equal(t, http.StatusUnauthorized, err.Code)
+	assert.Nil(t, validateToken(s, phonePayload))
+
+	// 退出其他所有会话
+	n, err := s.RevokeOtherSessions(phonePayload.ID, phonePayload.SessionID)
+	assert.Empty(t, err)
+	assert.Equal(t, int64(1), n)
+	assert.Equal(t, http.StatusUnauthorized, validateToken(s, tabletPayload).Code)
+	assert.Nil(t, validateToken(s, phonePayload))
+
+	sessionsResp, err = s.GetSessions(phonePayload.ID, phonePayload.SessionID)
+	assert.Empty(t, err)
+	assert.Equal(t, 1, len(sessionsResp.Sessions))
+	assert.True(t, sessionsResp.Sessions[0].Current)
+}
+
+func TestEmailVerification(t *testing.T) {
+	t.Setenv("PUBLIC_BASE_URL", "https://blog.test/")
+	t.Setenv("EMAIL_RESEND_COOLDOWN", "1h")
+
+	var links []string
+	defer func(send func(to, body, subject string) error) { utils.SendMail = send }(utils.SendMail)
+	utils.SendMail = func(to, body, subject string) error {
+		links = append(links, regexp.MustCompile(`https://blog\.test/auth/verify-email\?token=[^"<\s]+`).FindString(body))
+		return nil
+	}
+
+	db := setupTestDB(t)
+	defer teardownTestDB(db, t)
+
+	repo := dao.NewRepository(db)
+	s := service.NewService(repo)
+
+	// 注册后未验证，发出验证邮件
+	err := s.Register("test-user", "test@test.com", "test123", "", "")
+	assert.Empty(t, err)
+	assert.Equal(t, 1, len(links))
+	assert.NotEmpty(t, links[0])
+	loginResp, err := s.Login("test-user", "test123", "", "")
+	assert.Empty(t, err)
+	assert.False(t, loginResp.UserInfo.Verified)
+	userID := loginResp.UserInfo.ID
+
+	// 未验证用户不能发布文章和评论
+	_, err = s.PublishArticle(&dtos.ArticleReq{Title: "文章", Excerpt: "文章", Content: "文章"}, userID)
+	assert.Equal(t, http.StatusForbidden, err.Code)
+	_, err = s.CreateComment(&dtos.CommentReq{ArticleID: 1, Content: "评论"}, userID)
+	assert.Equal(t, http.StatusForbidden, err.Code)
+
+	// 冷却时间内不能重发
+	err = s.ResendVerification(userID)
+	assert.Equal(t, http.StatusTooManyRequests, err.Code)
+	db.Model(&models.UserToken{}).Where("user_id = ?", userID).Update("created_at", time.Now().Add(-2*time.Hour))
+	assert.Nil(t, s.ResendVerification(userID))
+	assert.Equal(t, 2, len(links))
+
+	token := func(link string) string {
+		u, _ := url.Parse(link)
+		return u.Query().Get("token")
+	}
+
+	// 重发后旧链接失效，新链接只能用一次
+	assert.Equal(t, http.StatusBadRequest, s.VerifyEmail(token(links[0])).Code)
+	assert.Equal(t, http.StatusBadRequest, s.VerifyEmail("invalid").Code)
+	assert.Nil(t, s.VerifyEmail(token(links[1])))
+	assert.Equal(t, http.StatusBadRequest, s.VerifyEmail(token(links[1])).Code)
+	assert.Equal(t, http.StatusBadRequest, s.ResendVerification(userID).Code)
+
+	loginResp, err = s.Login("test-user", "test123", "", "")
+	assert.Empty(t, err)
+	assert.True(t, loginResp.UserInfo.Verified)
+	_, err = s.PublishArticle(&dtos.ArticleReq{Title: "文章", Excerpt: "文章", Content: "文章"}, userID)
+	assert.Nil(t, err)
+
+	// 可以配置为不限制
+	t.Setenv("UNVERIFIED_RESTRICTIONS", "none")
+	s = service.NewService(repo)
+	err = s.Register("other-user", "other@test.com", "test123", "", "")
+	assert.Empty(t, err)
+	loginResp, err = s.Login("other-user", "test123", "", "")
+	assert.Empty(t, err)
+	_, err = s.PublishArticle(&dtos.ArticleReq{Title: "文章", Excerpt: "文章", Content: "文章"}, loginResp.UserInfo.ID)
+	assert.Nil(t, err)
+}
+
+func TestPasswordReset(t *testing.T) {
+	t.Setenv("PUBLIC_BASE_URL", "https://blog.test")
+	t.Setenv("PASSWORD_RESET_TTL", "2h")
+
+	var codes, links, bodies []string
+	defer func(send func(to, body, subject string) error) { utils.SendMail = send }(utils.SendMail)
+	utils.SendMail = func(to, body, subject string) error {
+		if m := regexp.MustCompile(`<strong>(.{6})</strong>`).FindStringSubmatch(body); m != nil {
+			codes = append(codes, m[1])
+		}
+		if link := regexp.MustCompile(`https://blog\.test/auth/reset/[^"<\s]+`).FindString(body); link != "" {
+			links = append(links, link)
+			bodies = append(bodies, body)
+		}
+		return nil
+	}
+
+	db := setupTestDB(t)
+	defer teardownTestDB(db, t)
+
+	repo := dao.NewRepository(db)
+	s := service.NewService(repo)
+
+	err := s.Register("test-user", "test@test.com", "test1234", "", "")
+	assert.Empty(t, err)
+	loginResp, err := s.Login("test-user", "test1234", "", "")
+	assert.Empty(t, err)
+	payload, _ := utils.ParseToken(loginResp.Token)
+
+	// 验证码冷却期内不能重复获取，错误的验证码不发链接
+	assert.Nil(t, s.SendCaptcha("test-user"))
+	assert.Equal(t, http.StatusTooManyRequests, s.SendCaptcha("test-user").Code)
+	assert.Equal(t, 1, len(codes))
+	assert.Equal(t, http.StatusBadRequest, s.Verify("test-user", "wrong!").Code)
+	assert.Nil(t, s.Verify("test-user", codes[0]))
+	assert.Equal(t, 1, len(links))
+	assert.Contains(t, bodies[0], "2小时后失效")
+	// 验证码只能用一次
+	assert.Equal(t, http.StatusBadRequest, s.Verify("test-user", codes[0]).Code)
+
+	token := links[0][len("https://blog.test/auth/reset/"):]
+	var record models.UserToken
+	db.Where("purpose = ?", models.PurposeResetPassword).First(&record)
+	assert.Equal(t, utils.HashToken(token), record.TokenHash)
+
+	// 校验失败时token不被核销
+	assert.Equal(t, http.StatusBadRequest, s.Reset(token, "short").Code)
+	assert.Equal(t, http.StatusBadRequest, s.Reset(token, "test1234").Code)
+	assert.Equal(t, http.StatusBadRequest, s.Reset("invalid", "newpass123").Code)
+
+	assert.Nil(t, s.Reset(token, "newpass123"))
+	assert.Equal(t, http.StatusBadRequest, s.Reset(token, "another123").Code)
+
+	// 新密码生效，原有会话全部吊销
+	assert.Equal(t, http.StatusUnauthorized, validateToken(s, payload).Code)
+	_, err = s.RefreshTheToken(loginResp.RefreshToken)
+	assert.Equal(t, http.StatusUnauthorized, err.Code)
+	_, err = s.Login("test-user", "test1234", "", "")
+	assert.Equal(t, http.StatusBadRequest, err.Code)
+	_, err = s.Login("test-user", "newpass123", "", "")
+	assert.Empty(t, err)
+
+	// 过期的链接不可用
+	t.Setenv("CAPTCHA_COOLDOWN", "0s")
+	assert.Nil(t, s.SendCaptcha("test-user"))
+	assert.Nil(t, s.Verify("test-user", codes[len(codes)-1]))
+	db.Model(&models.UserToken{}).Where("purpose = ? AND used_at IS NULL", models.PurposeResetPassword).
+		Update("expires_at", time.Now().Add(-time.Minute))
+	assert.Equal(t, http.StatusBadRequest, s.Reset(links[len(links)-1][len("https://blog.test/auth/reset/"):], "another123").Code)
+}
+
+func TestChangeCredentials(t *testing.T) {
+	t.Setenv("PUBLIC_BASE_URL", "https://blog.test")
+
+	mails := map[string][]string{}
+	defer func(send func(to, body, subject string) error) { utils.SendMail = send }(utils.SendMail)
+	utils.SendMail = func(to, body, subject string) error {
+		mails[to] = append(mails[to], body)
+		return nil
+	}
+
+	db := setupTestDB(t)
+	defer teardownTestDB(db, t)
+
+	repo := dao.NewRepository(db)
+	s := service.NewService(repo)
+
+	assert.Empty(t, s.Register("test-user", "test@test.com", "test1234", "", ""))
+	assert.Empty(t, s.Register("other-user", "other@test.com", "test1234", "", ""))
+	current, err := s.Login("test-user", "test1234", "", "current")
+	assert.Empty(t, err)
+	other, err := s.Login("test-user", "test1234", "", "other")
+	assert.Empty(t, err)
+	currentPayload, _ := utils.ParseToken(current.Token)
+	otherPayload, _ := utils.ParseToken(other.Token)
+	userID := currentPayload.ID
+
+	// 修改密码需要原密码，成功后只保留当前会话
+	err = s.ChangePassword(userID, currentPayload.SessionID, "", &dtos.ChangePasswordReq{OldPassword: "wrong123", NewPassword: "newpass123"})
+	assert.Equal(t, http.StatusBadRequest, err.Code)
+	err = s.ChangePassword(userID, currentPayload.SessionID, "", &dtos.ChangePasswordReq{OldPassword: "test1234", NewPassword: "test1234"})
+	assert.Equal(t, http.StatusBadRequest, err.Code)
+	assert.Nil(t, s.ChangePassword(userID, currentPayload.SessionID, "", &dtos.ChangePasswordReq{OldPassword: "test1234", NewPassword: "newpass123"}))
+	assert.Nil(t, validateToken(s, currentPayload))
+	assert.Equal(t, http.StatusUnauthorized, validateToken(s, otherPayload).Code)
+	_, err = s.Login("test-user", "test1234", "", "")
+	assert.Equal(t, http.StatusBadRequest, err.Code)
+	_, err = s.Login("test-user", "newpass123", "", "")
+	assert.Empty(t, err)
+
+	// 新邮箱不能是已注册的邮箱，也需要校验密码
+	err = s.RequestEmailChange(userID, "", &dtos.ChangeEmailReq{Email: "other@test.com", Password: "newpass123"})
+	assert.Equal(t, http.StatusBadRequest, err.Code)
+	err = s.RequestEmailChange(userID, "", &dtos.ChangeEmailReq{Email: "new@test.com", Password: "test1234"})
+	assert.Equal(t, http.StatusBadRequest, err.Code)
+
+	link := regexp.MustCompile(`https://blog\.test/auth/confirm-email\?token=[^"<\s]+`)
+	token := func(to string) string {
+		u, _ := url.Parse(link.FindString(mails[to][len(mails[to])-1]))
+		return u.Query().Get("token")
+	}
+
+	// 确认前邮箱不变，原邮箱收到提醒
+	assert.Nil(t, s.RequestEmailChange(userID, "", &dtos.ChangeEmailReq{Email: "new@test.com", Password: "newpass123"}))
+	first := token("new@test.com")
+	assert.NotEmpty(t, first)
+	assert.Contains(t, mails["test@test.com"][len(mails["test@test.com"])-1], "new@test.com")
+	var user models.User
+	db.First(&user, "id = ?", userID)
+	assert.Equal(t, "test@test.com", user.Email)
+
+	// 冷却时间内不能重新申请，过后旧链接失效，新链接只能用一次
+	err = s.RequestEmailChange(userID, "", &dtos.ChangeEmailReq{Email: "new@test.com", Password: "newpass123"})
+	assert.Equal(t, http.StatusTooManyRequests, err.Code)
+	db.Model(&models.UserToken{}).Where("user_id = ?", userID).Update("created_at", time.Now().Add(-2*time.Hour))
+	assert.Nil(t, s.RequestEmailChange(userID, "", &dtos.ChangeEmailReq{Email: "new@test.com", Password: "newpass123"}))
+	assert.Equal(t, http.StatusBadRequest, s.ConfirmEmailChange(first).Code)
+	second := token("new@test.com")
+	assert.Nil(t, s.ConfirmEmailChange(second))
+	assert.Equal(t, http.StatusBadRequest, s.ConfirmEmailChange(second).Code)
+	db.First(&user, "id = ?", userID)
+	assert.Equal(t, "new@test.com", user.Email)
+	assert.NotNil(t, user.EmailVerifiedAt)
+
+	// 确认时邮箱已被占用
+	otherResp, err := s.Login("other-user", "test1234", "", "")
+	assert.Empty(t, err)
+	assert.Nil(t, s.RequestEmailChange(otherResp.UserInfo.ID, "", &dtos.ChangeEmailReq{Email: "taken@test.com", Password: "test1234"}))
+	db.Model(&models.User{}).Where("id = ?", userID).Update("email", "taken@test.com")
+	assert.Equal(t, http.StatusConflict, s.ConfirmEmailChange(token("taken@test.com")).Code)
+
+	// 原密码错误和登录失败一起计数，达到阈值后锁定
+	t.Setenv("LOGIN_LOCK_THRESHOLD", "2")
+	s = service.NewService(repo)
+	otherID := otherResp.UserInfo.ID
+	err = s.ChangePassword(otherID, "", "", &dtos.ChangePasswordReq{OldPassword: "wrong123", NewPassword: "newpass123"})
+	assert.Equal(t, http.StatusBadRequest, err.Code)
+	err = s.RequestEmailChange(otherID, "", &dtos.ChangeEmailReq{Email: "another@test.com", Password: "wrong123"})
+	assert.Equal(t, http.StatusLocked, err.Code)
+	err = s.ChangePassword(otherID, "", "", &dtos.ChangePasswordReq{OldPassword: "test1234", NewPassword: "newpass123"})
+	assert.Equal(t, http.StatusLocked, err.Code)
+	_, err = s.Login("other-user", "test1234", "", "")
+	assert.Equal(t, http.StatusLocked, err.Code)
+}
+
+func TestTwoFactor(t *testing.T) {
+	db := setupTestDB(t)
+	defer teardownTestDB(db, t)
+
+	repo := dao.NewRepository(db)
+	s := service.NewService(repo)
+
+	assert.Empty(t, s.Register("test-user", "test@test.com", "test1234", "", ""))
+	loginResp, err := s.Login("test-user", "test1234", "", "")
+	assert.Empty(t, err)
+	userID := loginResp.UserInfo.ID
+	assert.False(t, loginResp.UserInfo.TwoFactor)
+
+	code := func(secret string, offset int64) string {
+		c, _ := utils.TOTPCode(secret, utils.TOTPStep(time.Now())+offset)
+		return c
+	}
+
+	// 未确认前不生效
+	_, err = s.EnableTwoFactor(userID, "123456")
+	assert.Equal(t, http.StatusBadRequest, err.Code)
+	setup, err := s.SetupTwoFactor(userID)
+	assert.Empty(t, err)
+	assert.Contains(t, setup.URI, setup.Secret)
+	loginResp, err = s.Login("test-user", "test1234", "", "")
+	assert.Empty(t, err)
+	assert.False(t, loginResp.TwoFactorRequired)
+
+	_, err = s.EnableTwoFactor(userID, "000000")
+	assert.Equal(t, http.StatusBadRequest, err.Code)
+	codesResp, err := s.EnableTwoFactor(userID, code(setup.Secret, 0))
+	assert.Empty(t, err)
+	assert.Equal(t, 10, len(codesResp.Codes))
+	var stored models.RecoveryCode
+	db.Where("user_id = ?", userID).First(&stored)
+	assert.NotContains(t, codesResp.Codes, stored.CodeHash)
+
+	// 密码通过后只返回挑战token，不能当作access token使用
+	loginResp, err = s.Login("test-user", "test1234", "", "")
+	assert.Empty(t, err)
+	assert.True(t, loginResp.TwoFactorRequired)
+	assert.Empty(t, loginResp.Token)
+	assert.Empty(t, loginResp.RefreshToken)
+	challenge, _ := utils.ParseToken(loginResp.ChallengeToken)
+	assert.Equal(t, utils.TokenChallenge, challenge.Type)
+
+	// 确认时用过的验证码不能重放
+	_, err = s.LoginTwoFactor(loginResp.ChallengeToken, code(setup.Secret, 0), "", "")
+	assert.Equal(t, http.StatusBadRequest, err.Code)
+	_, err = s.LoginTwoFactor("invalid", code(setup.Secret, 1), "", "")
+	assert.Equal(t, http.StatusUnauthorized, err.Code)
+	full, err := s.LoginTwoFactor(loginResp.ChallengeToken, code(setup.Secret, 1), "", "")
+	assert.Empty(t, err)
+	assert.NotEmpty(t, full.Token)
+	assert.True(t, full.UserInfo.TwoFactor)
+
+	// 恢复码只能使用一次，大小写和空格不敏感
+	recovery := codesResp.Codes[0]
+	_, err = s.LoginTwoFactor(loginResp.ChallengeToken, " "+strings.ToUpper(recovery)+" ", "", "")
+	assert.Empty(t, err)
+	_, err = s.LoginTwoFactor(loginResp.ChallengeToken, recovery, "", "")
+	assert.Equal(t, http.StatusBadRequest, err.Code)
+
+	// 关闭需要有效的验证码，错误次数和登录失败一起计数，锁定后有效的验证码也不能用
+	for i := 0; i < 3; i++ {
+		assert.Equal(t, http.StatusBadRequest, s.DisableTwoFactor(userID, recovery, "").Code)
+	}
+	assert.Equal(t, http.StatusLocked, s.DisableTwoFactor(userID, recovery, "").Code)
+	assert.Equal(t, http.StatusLocked, s.DisableTwoFactor(userID, codesResp.Codes[1], "").Code)
+	db.Model(&models.User{}).Where("id = ?", userID).Updates(map[string]interface{}{"failed_login": 0, "locked_until": nil})
+	assert.Nil(t, s.DisableTwoFactor(userID, codesResp.Codes[1], ""))
+	var cnt int64
+	db.Model(&models.RecoveryCode{}).Where("user_id = ?", userID).Count(&cnt)
+	assert.Equal(t, int64(0), cnt)
+	loginResp, err = s.Login("test-user", "test1234", "", "")
+	assert.Empty(t, err)
+	assert.False(t, loginResp.TwoFactorRequired)
+	assert.NotEmpty(t, loginResp.Token)
+}
+
+func TestOIDCLogin(t *testing.T) {
+	provider := newMockOIDC(t, "blog-client")
+	defer provider.Close()
+	t.Setenv("OIDC_PROVIDERS", "mock")
+	t.Setenv("OIDC_MOCK_ISSUER", provider.URL)
+	t.Setenv("OIDC_MOCK_CLIENT_ID", "blog-client")
+	t.Setenv("OIDC_MOCK_CLIENT_SECRET", "secret")
+
+	db := setupTestDB(t)
+	defer teardownTestDB(db, t)
+
+	repo := dao.NewRepository(db)
+	s := service.NewService(repo)
+
+	assert.Empty(t, s.Register("test-user", "test@test.com", "test1234", "", ""))
+
+	_, err := s.OIDCStart("unknown")
+	assert.Equal(t, http.StatusNotFound, err.Code)
+
+	login := func(claims jwt.MapClaims) (*dtos.LoginResp, *errs.ErrorResp) {
+		authURL, err := s.OIDCStart("mock")
+		assert.Empty(t, err)
+		code, state := provider.authorize(t, authURL, claims)
+		return s.OIDCCallback("mock", code, state, "", "oidc")
+	}
+
+	// 首次登录创建账号，用户名重复时加后缀
+	resp, err := login(jwt.MapClaims{"sub": "u-1", "email": "oidc@test.com", "email_verified": true, "preferred_username": "Test User"})
+	assert.Empty(t, err)
+	assert.NotEmpty(t, resp.Token)
+	assert.NotEmpty(t, resp.RefreshToken)
+	assert.Equal(t, "test-user-2", resp.UserInfo.Username)
+	assert.True(t, resp.UserInfo.Verified)
+	payload, _ := utils.ParseToken(resp.Token)
+	assert.Nil(t, validateToken(s, payload))
+
+	// 再次登录关联到同一个账号
+	again, err := login(jwt.MapClaims{"sub": "u-1", "email": "oidc@test.com", "preferred_username": "renamed"})
+	assert.Empty(t, err)
+	assert.Equal(t, resp.UserInfo.ID, again.UserInfo.ID)
+	var cnt int64
+	db.Model(&models.User{}).Count(&cnt)
+	assert.Equal(t, int64(2), cnt)
+	db.Model(&models.UserIdentity{}).Count(&cnt)
+	assert.Equal(t, int64(1), cnt)
+
+	// state只能用一次，授权码和code_verifier必须对应
+	authURL, err := s.OIDCStart("mock")
+	assert.Empty(t, err)
+	code, state := provider.authorize(t, authURL, jwt.MapClaims{"sub": "u-2", "email": "u2@test.com"})
+	otherURL, err := s.OIDCStart("mock")
+	assert.Empty(t, err)
+	_, otherState := provider.authorize(t, otherURL, jwt.MapClaims{"sub": "u-2"})
+	_, err = s.OIDCCallback("mock", code, otherState, "", "")
+	assert.Equal(t, http.StatusUnauthorized, err.Code)
+	_, err = s.OIDCCallback("mock", code, otherState, "", "")
+	assert.Equal(t, http.StatusBadRequest, err.Code)
+	_, err = s.OIDCCallback("mock", code, state, "", "")
+	assert.Equal(t, http.StatusUnauthorized, err.Code)
+
+	// nonce不匹配时拒绝
+	_, err = login(jwt.MapClaims{"sub": "u-3", "email": "u3@test.com", "nonce": "forged"})
+	assert.Equal(t, http.StatusUnauthorized, err.Code)
+
+	// 不按邮箱自动关联已有账号
+	_, err = login(jwt.MapClaims{"sub": "u-4", "email": "test@test.com", "email_verified": true})
+	assert.Equal(t, http.StatusConflict, err.Code)
+
+	// 开启了两步验证的账号同样需要验证码
+	setup, err := s.SetupTwoFactor(resp.UserInfo.ID)
+	assert.Empty(t, err)
+	totp, _ := utils.TOTPCode(setup.Secret, utils.TOTPStep(time.Now()))
+	_, err = s.EnableTwoFactor(resp.UserInfo.ID, totp)
+	assert.Empty(t, err)
+	challenge, err := login(jwt.MapClaims{"sub": "u-1", "email": "oidc@test.com"})
+	assert.Empty(t, err)
+	assert.True(t, challenge.TwoFactorRequired)
+	assert.Empty(t, challenge.Token)
+
+	// 进行中的授权请求数量有上限
+	t.Setenv("OIDC_MAX_PENDING", "2")
+	s = service.NewService(repo)
+	for i := 0; i < 2; i++ {
+		_, err = s.OIDCStart("mock")
+		assert.Empty(t, err)
+	}
+	_, err = s.OIDCStart("mock")
+	assert.Equal(t, http.StatusServiceUnavailable, err.Code)
+}
@@ -1,11 +1,25 @@
 package auth
 
 import (
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
 	"os"
+	"sync"
 	"testing"
+	"time"
 
 	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/Jack-samu/the-blog-backend-gin.git/internal/errs"
 	"github.com/Jack-samu/the-blog-backend-gin.git/internal/models"
+	"github.com/Jack-samu/the-blog-backend-gin.git/internal/service"
+	"github.com/Jack-samu/the-blog-backend-gin.git/internal/utils"
+	"github.com/golang-jwt/jwt/v5"
 	"github.com/stretchr/testify/assert"
 	"gorm.io/driver/mysql"
 	"gorm.io/driver/sqlite"
@@ -24,6 +38,19 @@ func setupTestDB(t *testing.T) *gorm.DB {
 		&models.Post{},
 		&models.Draft{},
 		&models.Img{},
+		&models.RefreshToken{},
+		&models.Session{},
+		&models.UserToken{},
+		&models.RecoveryCode{},
+		&models.UserIdentity{},
+		&models.Follow{},
+		&models.Bookmark{},
+		&models.ReadingList{},
+		&models.ReadingListItem{},
+		&models.Comment{},
+		&models.Reply{},
+		&models.Like{},
+		&models.PostRevision{},
 	)
 	if err != nil {
 		t.Fatalf("数据库迁移失败：%s\n", err.Error())
@@ -65,8 +92,116 @@ func setupMockDB(t *testing.T) (*gorm.DB, sqlmock.Sqlmock, func()) {
 	return gormDB, mock, cleanup
 }
 
+// 只关心校验结果，不关心返回的角色
+func validateToken(s *service.Service, payload *utils.Payload) *errs.ErrorResp {
+	_, errResp := s.ValidateToken(payload)
+	return errResp
+}
+
 func verifyMockExpection(t *testing.T, mock sqlmock.Sqlmock) {
 	if err := mock.ExpectationsWereMet(); err != nil {
 		t.Errorf("期望的mock未满足：%s\n", err)
 	}
 }
+
+// 本地的OIDC提供方，只实现discovery、jwks和token端点；
+// 授权页面由authorize模拟用户同意，直接返回授权码
+type mockOIDC struct {
+	*httptest.Server
+	clientID string
+	key      *rsa.PrivateKey
+
+	mu    sync.Mutex
+	codes map[string]mockGrant
+}
+
+type mockGrant struct {
+	challenge   string
+	redirectURI string
+	claims      jwt.MapClaims
+}
+
+func newMockOIDC(t *testing.T, clientID string) *mockOIDC {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	assert.NoError(t, err)
+
+	m := &mockOIDC{clientID: clientID, key: key, codes: make(map[string]mockGrant)}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]string{
+			"issuer":                 m.URL,
+			"authorization_endpoint": m.URL + "/authorize",
+			"token_endpoint":         m.URL + "/token",
+			"jwks_uri":               m.URL + "/jwks",
+		})
+	})
+	mux.HandleFunc("/jwks", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"keys": []map[string]string{{
+				"kty": "RSA",
+				"kid": "mock-key",
+				"use": "sig",
+				"alg": "RS256",
+				"n":   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+				"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+			}},
+		})
+	})
+	mux.HandleFunc("/token", m.token)
+	m.Server = httptest.NewServer(mux)
+
+	return m
+}
+
+// 模拟用户在授权页面同意授权，返回回调中的code和state
+func (m *mockOIDC) authorize(t *testing.T, authURL string, claims jwt.MapClaims) (string, string) {
+	u, err := url.Parse(authURL)
+	assert.NoError(t, err)
+	q := u.Query()
+	assert.Equal(t, m.URL+"/authorize", u.Scheme+"://"+u.Host+u.Path)
+	assert.Equal(t, m.clientID, q.Get("client_id"))
+	assert.Equal(t, "S256", q.Get("code_challenge_method"))
+
+	if _, ok := claims["nonce"]; !ok {
+		claims["nonce"] = q.Get("nonce")
+	}
+	code, _ := utils.RandomString(16)
+
+	m.mu.Lock()
+	m.codes[code] = mockGrant{challenge: q.Get("code_challenge"), redirectURI: q.Get("redirect_uri"), claims: claims}
+	m.mu.Unlock()
+
+	return code, q.Get("state")
+}
+
+func (m *mockOIDC) token(w http.ResponseWriter, r *http.Request) {
+	r.ParseForm()
+
+	m.mu.Lock()
+	grant, ok := m.codes[r.PostForm.Get("code")]
+	delete(m.codes, r.PostForm.Get("code"))
+	m.mu.Unlock()
+
+	if !ok || r.PostForm.Get("client_id") != m.clientID ||
+		r.PostForm.Get("redirect_uri") != grant.redirectURI ||
+		utils.PKCEChallenge(r.PostForm.Get("code_verifier")) != grant.challenge {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": "invalid_grant"})
+		return
+	}
+
+	claims := jwt.MapClaims{
+		"iss": m.URL,
+		"aud": m.clientID,
+		"iat": time.Now().Unix(),
+		"exp": time.Now().Add(5 * time.Minute).Unix(),
+	}
+	for k, v := range grant.claims {
+		claims[k] = v
+	}
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+	token.Header["kid"] = "mock-key"
+	idToken, _ := token.SignedString(m.key)
+
+	json.NewEncoder(w).Encode(map[string]string{"access_token": "mock", "token_type": "Bearer", "id_token": idToken})
+}
@@ -54,7 +54,7 @@ func TestHandlerComment(t *testing.T) {
 	//准备文章主体和用户主体
 	var token string
 	var commentID int64
-	_, post_id := preparation(serv, t)
+	_, post_id := preparation(db, serv, t)
 
 	t.Run("创建comment而后修改comment", func(t *testing.T) {
 		// 登录
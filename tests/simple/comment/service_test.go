@@ -6,6 +6,7 @@ import (
 
 	dao "github.com/Jack-samu/the-blog-backend-gin.git/internal/DAO"
 	"github.com/Jack-samu/the-blog-backend-gin.git/internal/dtos"
+	"github.com/Jack-samu/the-blog-backend-gin.git/internal/models"
 	"github.com/Jack-samu/the-blog-backend-gin.git/internal/service"
 	"github.com/stretchr/testify/assert"
 )
@@ -17,22 +18,22 @@ func TestCommentServiceFlow(t *testing.T) {
 	defer teardownTestDB(db)
 
 	// 查询不存在的post评论
-	commentsResp, err := serv.GetComments(int64(1))
+	commentsResp, err := serv.GetComments(int64(1), &dtos.PageReq{}, "")
 	assert.Equal(t, http.StatusNotFound, err.Code)
 	assert.Nil(t, commentsResp)
 
 	// 查询post评论但传入错误参数
-	commentsResp, err = serv.GetComments(int64(-1))
+	commentsResp, err = serv.GetComments(int64(-1), &dtos.PageReq{}, "")
 	assert.Equal(t, http.StatusBadRequest, err.Code)
 	assert.Nil(t, commentsResp)
 
 	// 查询reply评论但传入错误参数
-	repliesResp, err := serv.GetReplies(int64(-1))
+	repliesResp, err := serv.GetReplies(int64(-1), &dtos.PageReq{}, "")
 	assert.Equal(t, http.StatusBadRequest, err.Code)
 	assert.Nil(t, repliesResp)
 
 	//准备文章主体和用户主体
-	userID, postID := preparation(serv, t)
+	userID, postID := preparation(db, serv, t)
 
 	// 创建评论
 	req := &dtos.CommentReq{
@@ -45,12 +46,12 @@ func TestCommentServiceFlow(t *testing.T) {
 	assert.Equal(t, uint(postID), commentResp.Comment.PostID)
 	assert.Equal(t, userID, commentResp.Comment.Commenter.ID)
 	// 查comments
-	commentsResp, err = serv.GetComments(int64(postID))
+	commentsResp, err = serv.GetComments(int64(postID), &dtos.PageReq{}, "")
 	assert.Nil(t, err)
 	assert.Equal(t, int(1), len(commentsResp.Comments))
 	assert.Equal(t, "comment测试", commentsResp.Comments[0].Content)
 	// 查replies
-	repliesResp, err = serv.GetReplies(int64(commentResp.Comment.ID))
+	repliesResp, err = serv.GetReplies(int64(commentResp.Comment.ID), &dtos.PageReq{}, "")
 	assert.Nil(t, err)
 	assert.Empty(t, repliesResp.Replies)
 
@@ -60,11 +61,11 @@ func TestCommentServiceFlow(t *testing.T) {
 		CommentID: int64(commentResp.Comment.ID),
 	}
 	t.Logf("comment请求参数：%v\n", req)
-	commentResp, err = serv.ModifyComment(req, userID)
+	commentResp, err = serv.ModifyComment(req, userID, models.RoleAuthor)
 	assert.Nil(t, err)
 	assert.Equal(t, "comment测试，改", commentResp.Comment.Content)
 	// 查comments
-	commentsResp, err = serv.GetComments(int64(postID))
+	commentsResp, err = serv.GetComments(int64(postID), &dtos.PageReq{}, "")
 	assert.Nil(t, err)
 	assert.Equal(t, int(1), len(commentsResp.Comments))
 	assert.Equal(t, "comment测试，改", commentsResp.Comments[0].Content)
@@ -80,7 +81,7 @@ func TestCommentServiceFlow(t *testing.T) {
 	assert.Nil(t, err)
 	assert.Equal(t, "reply测试", replyResp.Reply.Content)
 	// 查询replies
-	repliesResp, err = serv.GetReplies(int64(commentResp.Comment.ID))
+	repliesResp, err = serv.GetReplies(int64(commentResp.Comment.ID), &dtos.PageReq{}, "")
 	assert.Nil(t, err)
 	assert.Equal(t, int(1), len(repliesResp.Replies))
 	assert.Equal(t, "reply测试", repliesResp.Replies[0].Content)
@@ -92,24 +93,164 @@ func TestCommentServiceFlow(t *testing.T) {
 		CommentID: int64(commentResp.Comment.ID),
 	}
 	t.Logf("reply请求参数：%v\n", req)
-	replyResp, err = serv.ModifyReply(req, userID)
+	replyResp, err = serv.ModifyReply(req, userID, models.RoleAuthor)
 	assert.Nil(t, err)
 	assert.Equal(t, "reply测试，改", replyResp.Reply.Content)
 	// 查询replies
-	repliesResp, err = serv.GetReplies(int64(commentResp.Comment.ID))
+	repliesResp, err = serv.GetReplies(int64(commentResp.Comment.ID), &dtos.PageReq{}, "")
 	assert.Nil(t, err)
 	assert.Equal(t, int(1), len(repliesResp.Replies))
 	assert.Equal(t, "reply测试，改", repliesResp.Replies[0].Content)
 
 	// 删除comment
-	err = serv.DeleteComment(int64(commentResp.Comment.ID), userID)
+	err = serv.DeleteComment(int64(commentResp.Comment.ID), userID, models.RoleAuthor)
 	assert.Nil(t, err)
 	// 查replies
-	repliesResp, err = serv.GetReplies(int64(commentResp.Comment.ID))
+	repliesResp, err = serv.GetReplies(int64(commentResp.Comment.ID), &dtos.PageReq{}, "")
 	assert.Equal(t, http.StatusNotFound, err.Code)
 	assert.Nil(t, repliesResp)
 	// 查comments
-	commentsResp, err = serv.GetComments(int64(postID))
+	commentsResp, err = serv.GetComments(int64(postID), &dtos.PageReq{}, "")
 	assert.Nil(t, err)
 	assert.Equal(t, int(0), len(commentsResp.Comments))
 }
+
+func TestLikeServiceFlow(t *testing.T) {
+	db := setupTestDB(t)
+	repo := dao.NewRepository(db)
+	serv := service.NewService(repo)
+	defer teardownTestDB(db)
+
+	userID, postID := preparation(db, serv, t)
+
+	// 不存在的点赞对象
+	likeResp, err := serv.ToggleLike(userID, models.LikeTargetPost, uint(postID+1), true)
+	assert.Equal(t, http.StatusNotFound, err.Code)
+	assert.Nil(t, likeResp)
+
+	// 重复点赞只计一次
+	likeResp, err = serv.ToggleLike(userID, models.LikeTargetPost, uint(postID), true)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, likeResp.Likes)
+	likeResp, err = serv.ToggleLike(userID, models.LikeTargetPost, uint(postID), true)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, likeResp.Likes)
+	assert.True(t, likeResp.Liked)
+
+	// 评论点赞后查询点赞状态
+	commentResp, err := serv.CreateComment(&dtos.CommentReq{
+		ArticleID: int64(postID),
+		Content:   "comment测试",
+	}, userID)
+	assert.Nil(t, err)
+	likeResp, err = serv.ToggleLike(userID, models.LikeTargetComment, commentResp.Comment.ID, true)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, likeResp.Likes)
+
+	commentsResp, err := serv.GetComments(int64(postID), &dtos.PageReq{}, userID)
+	assert.Nil(t, err)
+	assert.True(t, commentsResp.Comments[0].Liked)
+	assert.Equal(t, uint(1), commentsResp.Comments[0].LikeCnt)
+	commentsResp, err = serv.GetComments(int64(postID), &dtos.PageReq{}, "")
+	assert.Nil(t, err)
+	assert.False(t, commentsResp.Comments[0].Liked)
+
+	// 取消点赞，重复取消不会出现负数
+	likeResp, err = serv.ToggleLike(userID, models.LikeTargetComment, commentResp.Comment.ID, false)
+	assert.Nil(t, err)
+	assert.Equal(t, 0, likeResp.Likes)
+	assert.False(t, likeResp.Liked)
+	likeResp, err = serv.ToggleLike(userID, models.LikeTargetComment, commentResp.Comment.ID, false)
+	assert.Nil(t, err)
+	assert.Equal(t, 0, likeResp.Likes)
+
+	// 删除回复、评论和文章时，目标上的点赞记录一并删除
+	likes := func(targetType string, targetID uint) int64 {
+		var cnt int64
+		db.Model(&models.Like{}).Where("target_type = ? AND target_id = ?", targetType, targetID).Count(&cnt)
+		return cnt
+	}
+	reply, err := serv.CreateReply(&dtos.CommentReq{Content: "reply", CommentID: int64(commentResp.Comment.ID)}, userID)
+	assert.Nil(t, err)
+	nested, err := serv.CreateReply(&dtos.CommentReq{Content: "nested", CommentID: int64(commentResp.Comment.ID), ParentID: reply.Reply.ID}, userID)
+	assert.Nil(t, err)
+	other, err := serv.CreateReply(&dtos.CommentReq{Content: "other", CommentID: int64(commentResp.Comment.ID)}, userID)
+	assert.Nil(t, err)
+	for _, id := range []uint{reply.Reply.ID, nested.Reply.ID, other.Reply.ID} {
+		_, err = serv.ToggleLike(userID, models.LikeTargetReply, id, true)
+		assert.Nil(t, err)
+	}
+	_, err = serv.ToggleLike(userID, models.LikeTargetComment, commentResp.Comment.ID, true)
+	assert.Nil(t, err)
+
+	assert.Nil(t, serv.DeleteReply(int64(reply.Reply.ID), userID, models.RoleAuthor))
+	assert.Zero(t, likes(models.LikeTargetReply, reply.Reply.ID))
+	assert.Zero(t, likes(models.LikeTargetReply, nested.Reply.ID))
+	assert.Equal(t, int64(1), likes(models.LikeTargetReply, other.Reply.ID))
+
+	assert.Nil(t, serv.DeleteComment(int64(commentResp.Comment.ID), userID, models.RoleAuthor))
+	assert.Zero(t, likes(models.LikeTargetComment, commentResp.Comment.ID))
+	assert.Zero(t, likes(models.LikeTargetReply, other.Reply.ID))
+
+	assert.Nil(t, serv.DeletePost(uint(postID), userID))
+	assert.Zero(t, likes(models.LikeTargetPost, uint(postID)))
+}
+
+func TestModeration(t *testing.T) {
+	db := setupTestDB(t)
+	repo := dao.NewRepository(db)
+	serv := service.NewService(repo)
+	defer teardownTestDB(db)
+
+	userID, postID := preparation(db, serv, t)
+
+	err := serv.Register("other-user", "other@test.com", "test1234", "", "")
+	assert.Empty(t, err)
+	verifyUser(db, "other-user")
+	loginResp, err := serv.Login("other-user", "test1234", "", "")
+	assert.Empty(t, err)
+	otherID := loginResp.UserInfo.ID
+	assert.Equal(t, models.RoleAuthor, loginResp.UserInfo.Role)
+
+	commentResp, err := serv.CreateComment(&dtos.CommentReq{
+		ArticleID: int64(postID),
+		Content:   "comment测试",
+	}, userID)
+	assert.Nil(t, err)
+
+	// 普通作者不能改别人的评论
+	_, err = serv.ModifyComment(&dtos.CommentReq{
+		CommentID: int64(commentResp.Comment.ID),
+		Content:   "改",
+	}, otherID, models.RoleAuthor)
+	assert.Equal(t, http.StatusForbidden, err.Code)
+
+	// 无效角色和不存在的用户
+	err = serv.SetUserRole(otherID, "root")
+	assert.Equal(t, http.StatusBadRequest, err.Code)
+	err = serv.SetUserRole("not-exist", models.RoleModerator)
+	assert.Equal(t, http.StatusNotFound, err.Code)
+
+	// 提升为版主后重新登录，token中带上新角色
+	err = serv.SetUserRole(otherID, models.RoleModerator)
+	assert.Nil(t, err)
+	loginResp, err = serv.Login("other-user", "test1234", "", "")
+	assert.Empty(t, err)
+	assert.Equal(t, models.RoleModerator, loginResp.UserInfo.Role)
+
+	modifyResp, err := serv.ModifyComment(&dtos.CommentReq{
+		CommentID: int64(commentResp.Comment.ID),
+		Content:   "版主改",
+	}, otherID, models.RoleModerator)
+	assert.Nil(t, err)
+	assert.Equal(t, "版主改", modifyResp.Comment.Content)
+
+	err = serv.DeleteComment(int64(commentResp.Comment.ID), otherID, models.RoleModerator)
+	assert.Nil(t, err)
+
+	// 管理员可以删除任意文章
+	err = serv.AdminDeletePost(uint(postID))
+	assert.Nil(t, err)
+	err = serv.AdminDeletePost(uint(postID))
+	assert.Equal(t, http.StatusNotFound, err.Code)
+}
@@ -4,6 +4,7 @@ import (
 	"log"
 	"os"
 	"testing"
+	"time"
 
 	"github.com/Jack-samu/the-blog-backend-gin.git/internal/dtos"
 	"github.com/Jack-samu/the-blog-backend-gin.git/internal/handler"
@@ -34,6 +35,14 @@ func setupRoutes(h *handler.Handler, r *gin.Engine) {
 		protected.POST("/replies/modify", h.ModifyReply)
 		protected.DELETE("/comments/:id", h.DeleteComment)
 		protected.DELETE("/replies/:id", h.DeleteReply)
+
+		// 点赞部分
+		protected.POST("/articles/:id/like", h.LikePost)
+		protected.DELETE("/articles/:id/like", h.UnlikePost)
+		protected.POST("/comments/:id/like", h.LikeComment)
+		protected.DELETE("/comments/:id/like", h.UnlikeComment)
+		protected.POST("/replies/:id/like", h.LikeReply)
+		protected.DELETE("/replies/:id/like", h.UnlikeReply)
 	}
 }
 
@@ -49,9 +58,19 @@ func setupTestDB(t *testing.T) *gorm.DB {
 		&models.Post{},
 		&models.Draft{},
 		&models.Img{},
+		&models.RefreshToken{},
+		&models.Session{},
+		&models.UserToken{},
+		&models.RecoveryCode{},
+		&models.UserIdentity{},
+		&models.Follow{},
+		&models.Bookmark{},
+		&models.ReadingList{},
+		&models.ReadingListItem{},
 		&models.Comment{},
 		&models.Reply{},
 		&models.Like{},
+		&models.PostRevision{},
 	)
 	if err != nil {
 		t.Fatalf("数据库迁移失败：%s\n", err.Error())
@@ -71,10 +90,16 @@ func teardownTestDB(db *gorm.DB) {
 	}
 }
 
-func preparation(s *service.Service, t *testing.T) (string, int) {
+// 测试用户默认已验证邮箱
+func verifyUser(db *gorm.DB, username string) {
+	db.Model(&models.User{}).Where("username = ?", username).Update("email_verified_at", time.Now())
+}
+
+func preparation(db *gorm.DB, s *service.Service, t *testing.T) (string, int) {
 	err := s.Register("test-user", "test@test.com", "test1234", "guest what", "")
 	assert.Empty(t, err)
-	resp, err := s.Login("test-user", "test1234")
+	verifyUser(db, "test-user")
+	resp, err := s.Login("test-user", "test1234", "", "")
 	assert.Empty(t, err)
 
 	req := &dtos.ArticleReq{
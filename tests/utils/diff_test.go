@@ -0,0 +1,35 @@
+package utils_test
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/Jack-samu/the-blog-backend-gin.git/internal/utils"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDiffLines(t *testing.T) {
+	diff := utils.DiffLines("a\nb\nc\nd", "a\nc\nx\nd")
+	assert.Equal(t, []utils.DiffLine{
+		{Op: utils.DiffEqual, Text: "a"},
+		{Op: utils.DiffDelete, Text: "b"},
+		{Op: utils.DiffEqual, Text: "c"},
+		{Op: utils.DiffInsert, Text: "x"},
+		{Op: utils.DiffEqual, Text: "d"},
+	}, diff)
+
+	assert.Empty(t, utils.DiffLines("", ""))
+	assert.Equal(t, []utils.DiffLine{{Op: utils.DiffInsert, Text: "new"}}, utils.DiffLines("", "new"))
+
+	// 超过规模上限时整段按删除加插入处理
+	var oldLines, newLines []string
+	for i := 0; i < 700; i++ {
+		oldLines = append(oldLines, fmt.Sprintf("old-%d", i))
+		newLines = append(newLines, fmt.Sprintf("new-%d", i))
+	}
+	diff = utils.DiffLines(strings.Join(oldLines, "\n"), strings.Join(newLines, "\n"))
+	assert.Equal(t, 1400, len(diff))
+	assert.Equal(t, utils.DiffLine{Op: utils.DiffDelete, Text: "old-0"}, diff[0])
+	assert.Equal(t, utils.DiffLine{Op: utils.DiffInsert, Text: "new-0"}, diff[700])
+}
@@ -0,0 +1,20 @@
+package utils_test
+
+import (
+	"testing"
+
+	"github.com/Jack-samu/the-blog-backend-gin.git/internal/utils"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHighlight(t *testing.T) {
+	assert.Equal(t, "<mark>Gin</mark>入门", utils.Highlight("Gin入门", []string{"GIN"}, 0))
+	assert.Empty(t, utils.Highlight("Gin入门", []string{"gorm"}, 0))
+
+	// 文本和搜索词按同样的方式转小写，特殊大小写字符的标记位置不偏移
+	assert.Equal(t, "<mark>İstanbul</mark>游记", utils.Highlight("İstanbul游记", []string{"İSTANBUL"}, 0))
+	assert.Equal(t, "İstanbul<mark>游记</mark>", utils.Highlight("İstanbul游记", []string{"游记"}, 0))
+
+	// 截取命中附近的片段，内容做html转义
+	assert.Equal(t, "...&lt;b&gt;<mark>go</mark>&lt;...", utils.Highlight("0123456789<b>go</b>0123456789", []string{"go"}, 3))
+}
@@ -1,6 +1,14 @@
 package utils_test
 
 import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"os"
+	"path/filepath"
+	"strings"
 	"testing"
 	"time"
 
@@ -109,6 +117,115 @@ func TestExpiredToken(t *testing.T) {
 	assert.Nil(t, payload)
 }
 
+func writeKeyFile(t *testing.T, dir, name string, key interface{}) string {
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	assert.NoError(t, err)
+
+	path := filepath.Join(dir, name)
+	err = os.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}), 0600)
+	assert.NoError(t, err)
+	return path
+}
+
+func writePublicKeyFile(t *testing.T, dir, name string, key interface{}) string {
+	der, err := x509.MarshalPKIXPublicKey(key)
+	assert.NoError(t, err)
+
+	path := filepath.Join(dir, name)
+	err = os.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der}), 0600)
+	assert.NoError(t, err)
+	return path
+}
+
+func TestKeyRotation(t *testing.T) {
+	defer utils.ResetKeys()
+
+	dir := t.TempDir()
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	assert.NoError(t, err)
+	_, edKey, err := ed25519.GenerateKey(rand.Reader)
+	assert.NoError(t, err)
+	rsaFile := writeKeyFile(t, dir, "rsa.pem", rsaKey)
+	rsaPubFile := writePublicKeyFile(t, dir, "rsa.pub.pem", &rsaKey.PublicKey)
+	edFile := writeKeyFile(t, dir, "ed.pem", edKey)
+
+	// 默认密钥签发的token在切换前生成
+	legacy, err := utils.GenerateToken("legacy", time.Hour)
+	assert.NoError(t, err)
+
+	// 没有配置任何密钥时拒绝启动
+	t.Setenv("JWT_KEYS_FILE", "")
+	t.Setenv("JWT_SECRET", "")
+	assert.Error(t, utils.InitJWTKeys())
+
+	// 配置错误时保留原有密钥
+	err = utils.LoadKeys(&utils.KeysConfig{Active: "none", Keys: []utils.KeyConfig{
+		{KID: "rsa-1", Alg: utils.AlgRS256, PrivateKeyFile: rsaFile},
+	}})
+	assert.Error(t, err)
+	err = utils.LoadKeys(&utils.KeysConfig{Active: "rsa-1", Keys: []utils.KeyConfig{
+		{KID: "rsa-1", Alg: utils.AlgRS256, PublicKeyFile: rsaPubFile},
+	}})
+	assert.Error(t, err)
+	_, err = utils.ParseToken(legacy)
+	assert.NoError(t, err)
+
+	// 使用RS256签发，头部带kid
+	err = utils.LoadKeys(&utils.KeysConfig{Issuer: "blog-test", Active: "rsa-1", Keys: []utils.KeyConfig{
+		{KID: "rsa-1", Alg: utils.AlgRS256, PrivateKeyFile: rsaFile},
+		{KID: "hs-1", Alg: utils.AlgHS256, Secret: "secret"},
+	}})
+	assert.NoError(t, err)
+	rsaToken, err := utils.GenerateToken("user-1", time.Hour)
+	assert.NoError(t, err)
+	payload, err := utils.ParseToken(rsaToken)
+	assert.NoError(t, err)
+	assert.Equal(t, "blog-test", payload.Issuer)
+	_, err = utils.ParseToken(legacy)
+	assert.Error(t, err)
+
+	// JWKS只公开非对称密钥的公钥
+	jwks := utils.JWKS()
+	assert.Equal(t, 1, len(jwks.Keys))
+	assert.Equal(t, "rsa-1", jwks.Keys[0].Kid)
+	assert.Equal(t, "RSA", jwks.Keys[0].Kty)
+	assert.Equal(t, "AQAB", jwks.Keys[0].E)
+
+	// 轮换到EdDSA，旧密钥只保留公钥用于校验
+	err = utils.LoadKeys(&utils.KeysConfig{Issuer: "blog-test", Active: "ed-1", Keys: []utils.KeyConfig{
+		{KID: "ed-1", Alg: utils.AlgEdDSA, PrivateKeyFile: edFile},
+		{KID: "rsa-1", Alg: utils.AlgRS256, PublicKeyFile: rsaPubFile},
+	}})
+	assert.NoError(t, err)
+	edToken, err := utils.GenerateToken("user-2", time.Hour)
+	assert.NoError(t, err)
+	payload, err = utils.ParseToken(edToken)
+	assert.NoError(t, err)
+	assert.Equal(t, "user-2", payload.ID)
+	payload, err = utils.ParseToken(rsaToken)
+	assert.NoError(t, err)
+	assert.Equal(t, "user-1", payload.ID)
+
+	jwks = utils.JWKS()
+	assert.Equal(t, 2, len(jwks.Keys))
+	assert.Equal(t, "OKP", jwks.Keys[0].Kty)
+	assert.Equal(t, "Ed25519", jwks.Keys[0].Crv)
+
+	// 签名被替换时校验失败
+	parts := strings.Split(edToken, ".")
+	assert.Equal(t, 3, len(parts))
+	_, err = utils.ParseToken(strings.Join([]string{parts[0], parts[1], strings.Split(rsaToken, ".")[2]}, "."))
+	assert.Error(t, err)
+
+	// 旧密钥下线后，用它签发的token失效
+	err = utils.LoadKeys(&utils.KeysConfig{Issuer: "blog-test", Active: "ed-1", Keys: []utils.KeyConfig{
+		{KID: "ed-1", Alg: utils.AlgEdDSA, PrivateKeyFile: edFile},
+	}})
+	assert.NoError(t, err)
+	_, err = utils.ParseToken(rsaToken)
+	assert.Error(t, err)
+}
+
 // 基准测试
 func BenchmarkGenerateToken(b *testing.B) {
 	for i := 0; i < b.N; i++ {
@@ -0,0 +1,44 @@
+package utils_test
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Jack-samu/the-blog-backend-gin.git/internal/utils"
+	"github.com/stretchr/testify/assert"
+)
+
+// RFC 6238附录B的SHA1测试向量，取后6位
+func TestTOTP(t *testing.T) {
+	secret := "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ"
+
+	cases := map[int64]string{
+		59:         "287082",
+		1111111109: "081804",
+		1234567890: "005924",
+		2000000000: "279037",
+	}
+	for ts, want := range cases {
+		code, err := utils.TOTPCode(secret, utils.TOTPStep(time.Unix(ts, 0)))
+		assert.NoError(t, err)
+		assert.Equal(t, want, code)
+	}
+
+	// 允许前后一个周期的偏差
+	now := time.Unix(1234567890, 0)
+	step, ok := utils.ValidateTOTP(secret, "005924", now.Add(30*time.Second))
+	assert.True(t, ok)
+	assert.Equal(t, utils.TOTPStep(now), step)
+	_, ok = utils.ValidateTOTP(secret, "005924", now.Add(90*time.Second))
+	assert.False(t, ok)
+	_, ok = utils.ValidateTOTP(secret, "12345", now)
+	assert.False(t, ok)
+
+	generated, err := utils.GenerateTOTPSecret()
+	assert.NoError(t, err)
+	assert.Equal(t, 32, len(generated))
+	uri := utils.TOTPURI("the-blog", "test-user", generated)
+	assert.True(t, strings.HasPrefix(uri, "otpauth://totp/the-blog:test-user?"))
+	assert.Contains(t, uri, "secret="+generated)
+}